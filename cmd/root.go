@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"syscall"
 
 	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/enricher"
 	"github.com/devopsext/events/input"
 	"github.com/devopsext/events/output"
 	"github.com/devopsext/events/processor"
@@ -49,8 +51,16 @@ var rootOptions = RootOptions{
 
 var textTemplateOptions = render.TextTemplateOptions{
 	TimeFormat: envGet("TEMPLATE_TIME_FORMAT", "2006-01-02T15:04:05.999Z").(string),
+	Strict:     strings.Split(envGet("TEMPLATE_STRICT", "").(string), ","),
 }
 
+var templateDir = envGet("TEMPLATE_DIR", "").(string)
+
+var lintOptions = struct {
+	EventType string
+	Name      string
+}{}
+
 var stdoutOptions = sreProvider.StdoutOptions{
 	Format:          envGet("STDOUT_FORMAT", "text").(string),
 	Level:           envGet("STDOUT_LEVEL", "info").(string),
@@ -71,12 +81,17 @@ var httpInputOptions = input.HttpInputOptions{
 	RancherURL:      envGet("HTTP_IN_RANCHER_URL", "").(string),
 	AlertmanagerURL: envGet("HTTP_IN_ALERTMANAGER_URL", "").(string),
 	GitlabURL:       envGet("HTTP_IN_GITLAB_URL", "").(string),
+	GithubURL:       envGet("HTTP_IN_GITHUB_URL", "").(string),
 	DataDogURL:      envGet("HTTP_IN_DATADOG_URL", "").(string),
 	CustomJsonURL:   envGet("HTTP_IN_CUSTOMJSON_URL", "").(string),
 	AWSURL:          envGet("HTTP_IN_AWS_URL", "").(string),
 	GoogleURL:       envGet("HTTP_IN_GOOGLE_URL", "").(string),
+	NewRelicURL:     envGet("HTTP_IN_NEWRELIC_URL", "").(string),
 	CloudflareURL:   envGet("HTTP_IN_CLOUDFLARE_URL", "").(string),
 	Site24x7URL:     envGet("HTTP_IN_SITE24X7_URL", "").(string),
+	SlackURL:        envGet("HTTP_IN_SLACK_URL", "").(string),
+	TelegramURL:     envGet("HTTP_IN_TELEGRAM_URL", "").(string),
+	CloudEventsURL:  envGet("HTTP_IN_CLOUDEVENTS_URL", "").(string),
 	Listen:          envGet("HTTP_IN_LISTEN", ":80").(string),
 	Tls:             envGet("HTTP_IN_TLS", false).(bool),
 	Cert:            envGet("HTTP_IN_CERT", "").(string),
@@ -91,6 +106,41 @@ var pubsubInputOptions = input.PubSubInputOptions{
 	Subscription: envGet("PUBSUB_IN_SUBSCRIPTION", "").(string),
 }
 
+var telegramInputOptions = input.TelegramInputOptions{
+	URL:      envGet("TELEGRAM_IN_URL", "https://api.telegram.org").(string),
+	IDTokens: envGet("TELEGRAM_IN_ID_TOKENS", "").(string),
+	Timeout:  envGet("TELEGRAM_IN_TIMEOUT", 30).(int),
+}
+
+var telegramProcessorOptions = processor.TelegramProcessorOptions{
+	AllowedChats: envGet("TELEGRAM_IN_ALLOWED_CHATS", "").(string),
+	AllowedUsers: envGet("TELEGRAM_IN_ALLOWED_USERS", "").(string),
+	Commands:     envGet("TELEGRAM_IN_COMMANDS", "ack,silence,status").(string),
+	SecretToken:  envGet("TELEGRAM_IN_SECRET_TOKEN", "").(string),
+}
+
+var alertmanagerProcessorOptions = processor.AlertmanagerProcessorOptions{
+	Alerts: envGet("ALERTMANAGER_IN_ALERTS", true).(bool),
+	Group:  envGet("ALERTMANAGER_IN_GROUP", false).(bool),
+}
+
+var gitlabProcessorOptions = processor.GitlabProcessorOptions{
+	Secret:  envGet("GITLAB_IN_SECRET", "").(string),
+	Secrets: envGet("GITLAB_IN_SECRETS", "").(string),
+}
+
+var cloudEventsProcessorOptions = processor.CloudEventsProcessorOptions{
+	Token: envGet("CLOUDEVENTS_IN_TOKEN", "").(string),
+}
+
+var githubProcessorOptions = processor.GithubProcessorOptions{
+	Secret: envGet("GITHUB_IN_SECRET", "").(string),
+}
+
+var slackProcessorOptions = processor.SlackProcessorOptions{
+	SigningSecret: envGet("SLACK_IN_SIGNING_SECRET", "").(string),
+}
+
 var collectorOutputOptions = output.CollectorOutputOptions{
 	Address: envGet("COLLECTOR_OUT_ADDRESS", "").(string),
 	Message: envGet("COLLECTOR_OUT_MESSAGE", "").(string),
@@ -107,6 +157,7 @@ var kafkaOutputOptions = output.KafkaOutputOptions{
 	NetDialTimeout:     envGet("KAFKA_OUT_NET_DIAL_TIMEOUT", 30).(int),
 	NetReadTimeout:     envGet("KAFKA_OUT_NET_READ_TIMEOUT", 30).(int),
 	NetWriteTimeout:    envGet("KAFKA_OUT_NET_WRITE_TIMEOUT", 30).(int),
+	CloudEventsSource:  envGet("KAFKA_OUT_CLOUDEVENTS_SOURCE", "").(string),
 }
 
 var telegramOutputOptions = output.TelegramOutputOptions{
@@ -121,6 +172,11 @@ var telegramOutputOptions = output.TelegramOutputOptions{
 	BotSelector:     envGet("TELEGRAM_OUT_BOT_SELECTOR", "").(string),
 	AlertExpression: envGet("TELEGRAM_OUT_ALERT_EXPRESSION", "g0.expr").(string),
 	Forward:         envGet("TELEGRAM_OUT_FORWARD", "").(string),
+	ResolveMode:     envGet("TELEGRAM_OUT_RESOLVE_MODE", "").(string),
+	ThreadKey:       envGet("TELEGRAM_OUT_THREAD_KEY", "").(string),
+	ThreadStore:     envGet("TELEGRAM_OUT_THREAD_STORE", "").(string),
+	ThreadTTL:       envGet("TELEGRAM_OUT_THREAD_TTL", 604800).(int),
+	Render:          envGet("TELEGRAM_OUT_RENDER", "grafana").(string),
 }
 
 var slackOutputOptions = output.SlackOutputOptions{
@@ -131,6 +187,13 @@ var slackOutputOptions = output.SlackOutputOptions{
 	Token:           envGet("SLACK_OUT_TOKEN", "").(string),
 	Channel:         envGet("SLACK_OUT_CHANNEL", "").(string),
 	Forward:         envGet("SLACK_OUT_FORWARD", "").(string),
+	Threads:         envGet("SLACK_OUT_THREADS", false).(bool),
+	ThreadKey:       envGet("SLACK_OUT_THREAD_KEY", "").(string),
+	ThreadStore:     envGet("SLACK_OUT_THREAD_STORE", "").(string),
+	ThreadTTL:       envGet("SLACK_OUT_THREAD_TTL", 604800).(int),
+	Update:          envGet("SLACK_OUT_UPDATE", "").(string),
+	Actions:         envGet("SLACK_OUT_ACTIONS", "").(string),
+	Render:          envGet("SLACK_OUT_RENDER", "grafana").(string),
 }
 
 var workchatOutputOptions = output.WorkchatOutputOptions{
@@ -140,6 +203,7 @@ var workchatOutputOptions = output.WorkchatOutputOptions{
 	Timeout:          envGet("WORKCHAT_OUT_TIMEOUT", 30).(int),
 	AlertExpression:  envGet("WORKCHAT_OUT_ALERT_EXPRESSION", "g0.expr").(string),
 	NotificationType: envGet("WORKCHAT_OUT_NOTIFICATION_TYPE", "REGULAR").(string),
+	Render:           envGet("WORKCHAT_OUT_RENDER", "grafana").(string),
 }
 
 var newrelicOutputOptions = output.NewRelicOutputOptions{
@@ -158,17 +222,89 @@ var grafanaOutputOptions = output.GrafanaOutputOptions{
 }
 
 var pubsubOutputOptions = output.PubSubOutputOptions{
-	Credentials:   envGet("PUBSUB_OUT_CREDENTIALS", "").(string),
-	ProjectID:     envGet("PUBSUB_OUT_PROJECT_ID", "").(string),
-	Message:       envGet("PUBSUB_OUT_MESSAGE", "").(string),
-	TopicSelector: envGet("PUBSUB_OUT_TOPIC_SELECTOR", "").(string),
+	Credentials:       envGet("PUBSUB_OUT_CREDENTIALS", "").(string),
+	ProjectID:         envGet("PUBSUB_OUT_PROJECT_ID", "").(string),
+	Message:           envGet("PUBSUB_OUT_MESSAGE", "").(string),
+	TopicSelector:     envGet("PUBSUB_OUT_TOPIC_SELECTOR", "").(string),
+	CloudEventsSource: envGet("PUBSUB_OUT_CLOUDEVENTS_SOURCE", "").(string),
+}
+
+var webhookOutputOptions = output.WebhookOutputOptions{
+	URL:               envGet("WEBHOOK_OUT_URL", "").(string),
+	Message:           envGet("WEBHOOK_OUT_MESSAGE", "").(string),
+	Timeout:           envGet("WEBHOOK_OUT_TIMEOUT", 30).(int),
+	Insecure:          envGet("WEBHOOK_OUT_INSECURE", false).(bool),
+	CloudEventsSource: envGet("WEBHOOK_OUT_CLOUDEVENTS_SOURCE", "").(string),
+	Token:             envGet("WEBHOOK_OUT_TOKEN", "").(string),
 }
 
 var gitlabOutputOptions = output.GitlabOutputOptions{
-	BaseURL:   envGet("GITLAB_OUT_BASE_URL", "").(string),
-	Token:     envGet("GITLAB_OUT_TOKEN", "").(string),
-	Projects:  envGet("GITLAB_OUT_PROJECTS", "").(string),
-	Variables: envGet("GITLAB_OUT_VARIABLES", "").(string),
+	BaseURL:          envGet("GITLAB_OUT_BASE_URL", "").(string),
+	Token:            envGet("GITLAB_OUT_TOKEN", "").(string),
+	Projects:         envGet("GITLAB_OUT_PROJECTS", "").(string),
+	Actions:          envGet("GITLAB_OUT_ACTIONS", "").(string),
+	Variables:        envGet("GITLAB_OUT_VARIABLES", "").(string),
+	PipelineTimeout:  envGet("GITLAB_OUT_PIPELINE_TIMEOUT", 0).(int),
+	PipelineInterval: envGet("GITLAB_OUT_PIPELINE_INTERVAL", 30).(int),
+}
+
+var pagerdutyOutputOptions = output.PagerDutyOutputOptions{
+	URL:                envGet("PAGERDUTY_OUT_URL", "https://events.pagerduty.com/v2/enqueue").(string),
+	Timeout:            envGet("PAGERDUTY_OUT_TIMEOUT", 30).(int),
+	RoutingKey:         envGet("PAGERDUTY_OUT_ROUTING_KEY", "").(string),
+	RoutingKeySelector: envGet("PAGERDUTY_OUT_ROUTING_KEY_SELECTOR", "").(string),
+	Action:             envGet("PAGERDUTY_OUT_ACTION", "").(string),
+	DedupKey:           envGet("PAGERDUTY_OUT_DEDUP_KEY", "").(string),
+	Message:            envGet("PAGERDUTY_OUT_MESSAGE", "").(string),
+	Severity:           envGet("PAGERDUTY_OUT_SEVERITY", "").(string),
+	Source:             envGet("PAGERDUTY_OUT_SOURCE", "").(string),
+	Component:          envGet("PAGERDUTY_OUT_COMPONENT", "").(string),
+	Group:              envGet("PAGERDUTY_OUT_GROUP", "").(string),
+	Class:              envGet("PAGERDUTY_OUT_CLASS", "").(string),
+	Details:            envGet("PAGERDUTY_OUT_DETAILS", "").(string),
+	Forward:            envGet("PAGERDUTY_OUT_FORWARD", "").(string),
+}
+
+var opsgenieOutputOptions = output.OpsgenieOutputOptions{
+	URL:         envGet("OPSGENIE_OUT_URL", "").(string),
+	Region:      envGet("OPSGENIE_OUT_REGION", "us").(string),
+	WebURL:      envGet("OPSGENIE_OUT_WEB_URL", "").(string),
+	Timeout:     envGet("OPSGENIE_OUT_TIMEOUT", 30).(int),
+	ApiKey:      envGet("OPSGENIE_OUT_API_KEY", "").(string),
+	Action:      envGet("OPSGENIE_OUT_ACTION", "").(string),
+	Alias:       envGet("OPSGENIE_OUT_ALIAS", "").(string),
+	Message:     envGet("OPSGENIE_OUT_MESSAGE", "").(string),
+	Description: envGet("OPSGENIE_OUT_DESCRIPTION", "").(string),
+	Priority:    envGet("OPSGENIE_OUT_PRIORITY", "").(string),
+	Responders:  envGet("OPSGENIE_OUT_RESPONDERS", "").(string),
+	Tags:        envGet("OPSGENIE_OUT_TAGS", "").(string),
+	Details:     envGet("OPSGENIE_OUT_DETAILS", "").(string),
+	Entity:      envGet("OPSGENIE_OUT_ENTITY", "").(string),
+	Source:      envGet("OPSGENIE_OUT_SOURCE", "").(string),
+	Retries:     envGet("OPSGENIE_OUT_RETRIES", 3).(int),
+	Forward:     envGet("OPSGENIE_OUT_FORWARD", "").(string),
+}
+
+var alertmanagerOutputOptions = output.AlertmanagerOutputOptions{
+	Silence:  envGet("ALERTMANAGER_OUT_SILENCE", "").(string),
+	Duration: envGet("ALERTMANAGER_OUT_DURATION", "1h").(string),
+}
+
+var emailOutputOptions = output.EmailOutputOptions{
+	Address:           envGet("EMAIL_OUT_ADDRESS", "").(string),
+	Mode:              envGet("EMAIL_OUT_MODE", "starttls").(string),
+	Insecure:          envGet("EMAIL_OUT_INSECURE", false).(bool),
+	Timeout:           envGet("EMAIL_OUT_TIMEOUT", 30).(int),
+	Username:          envGet("EMAIL_OUT_USERNAME", "").(string),
+	Password:          envGet("EMAIL_OUT_PASSWORD", "").(string),
+	From:              envGet("EMAIL_OUT_FROM", "").(string),
+	To:                envGet("EMAIL_OUT_TO", "").(string),
+	RecipientSelector: envGet("EMAIL_OUT_RECIPIENT_SELECTOR", "").(string),
+	Subject:           envGet("EMAIL_OUT_SUBJECT", "").(string),
+	Message:           envGet("EMAIL_OUT_MESSAGE", "").(string),
+	Text:              envGet("EMAIL_OUT_TEXT", "").(string),
+	AlertExpression:   envGet("EMAIL_OUT_ALERT_EXPRESSION", "g0.expr").(string),
+	Window:            envGet("EMAIL_OUT_WINDOW", 0).(int),
 }
 
 var grafanaRenderOptions = render.GrafanaRenderOptions{
@@ -180,6 +316,60 @@ var grafanaRenderOptions = render.GrafanaRenderOptions{
 	Period:      envGet("GRAFANA_RENDER_PERIOD", 60).(int),
 	ImageWidth:  envGet("GRAFANA_RENDER_IMAGE_WIDTH", 1280).(int),
 	ImageHeight: envGet("GRAFANA_RENDER_IMAGE_HEIGHT", 640).(int),
+	PanelType:   envGet("GRAFANA_RENDER_PANEL_TYPE", "timeseries").(string),
+}
+
+var chartRenderOptions = render.ChartRenderOptions{
+	Period:      envGet("CHART_RENDER_PERIOD", 60).(int),
+	Step:        envGet("CHART_RENDER_STEP", 0).(int),
+	ImageWidth:  envGet("CHART_RENDER_IMAGE_WIDTH", 1280).(int),
+	ImageHeight: envGet("CHART_RENDER_IMAGE_HEIGHT", 640).(int),
+}
+
+var datadogChartOptions = render.DataDogChartOptions{
+	URL:      envGet("DATADOG_CHART_URL", "https://api.datadoghq.com").(string),
+	ApiKey:   envGet("DATADOG_CHART_API_KEY", "").(string),
+	AppKey:   envGet("DATADOG_CHART_APP_KEY", "").(string),
+	Timeout:  envGet("DATADOG_CHART_TIMEOUT", 30).(int),
+	Attempts: envGet("DATADOG_CHART_ATTEMPTS", 3).(int),
+}
+
+var googleChartOptions = render.GoogleChartOptions{
+	Credentials: envGet("GOOGLE_CHART_CREDENTIALS", "").(string),
+	Timeout:     envGet("GOOGLE_CHART_TIMEOUT", 30).(int),
+}
+
+var newrelicChartOptions = render.NewRelicChartOptions{
+	Timeout:  envGet("NEWRELIC_CHART_TIMEOUT", 30).(int),
+	Attempts: envGet("NEWRELIC_CHART_ATTEMPTS", 0).(int),
+}
+
+var alertmanagerApiOptions = render.AlertmanagerApiOptions{
+	URL:     envGet("ALERTMANAGER_API_URL", "").(string),
+	Timeout: envGet("ALERTMANAGER_API_TIMEOUT", 30).(int),
+}
+
+var prometheusApiOptions = render.PrometheusApiOptions{
+	URL:      envGet("PROMETHEUS_API_URL", "").(string),
+	Timeout:  envGet("PROMETHEUS_API_TIMEOUT", 30).(int),
+	User:     envGet("PROMETHEUS_API_USER", "").(string),
+	Password: envGet("PROMETHEUS_API_PASSWORD", "").(string),
+}
+
+var enrichersOptions = common.EnrichersOptions{
+	Timeout: envGet("ENRICHERS_TIMEOUT", 10).(int),
+}
+
+var prometheusEnricherOptions = enricher.PrometheusEnricherOptions{
+	Queries: envGet("PROMETHEUS_ENRICHER_QUERIES", "").(string),
+	Step:    envGet("PROMETHEUS_ENRICHER_STEP", "1m").(string),
+}
+
+var httpEnricherOptions = enricher.HttpEnricherOptions{
+	Steps:    envGet("HTTP_ENRICHER_STEPS", "").(string),
+	Timeout:  envGet("HTTP_ENRICHER_TIMEOUT", 5).(int),
+	Insecure: envGet("HTTP_ENRICHER_INSECURE", false).(bool),
+	TTL:      envGet("HTTP_ENRICHER_TTL", 300).(int),
 }
 
 var jaegerOptions = sreProvider.JaegerOptions{
@@ -289,11 +479,12 @@ func envGet(s string, d interface{}) interface{} {
 
 func interceptSyscall() {
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-c
 		logs.Info("Exiting...")
+		common.FlushStores()
 		os.Exit(1)
 	}()
 }
@@ -460,32 +651,63 @@ func Execute() {
 			observability := common.NewObservability(logs, traces, metrics, events)
 			outputs := common.NewOutputs(logs)
 
+			library, err := render.NewTemplateLibrary(templateDir, logs)
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+			textTemplateOptions.Library = library
+
+			textTemplateOptions.Alertmanager = render.NewAlertmanagerApi(alertmanagerApiOptions, observability)
+			textTemplateOptions.Prometheus = render.NewPrometheusApi(prometheusApiOptions, observability)
+
+			enrichers := common.NewEnrichers(enrichersOptions, logs)
+			enrichers.Add(enricher.NewPrometheusEnricher(prometheusEnricherOptions, textTemplateOptions, observability))
+			enrichers.Add(enricher.NewHttpEnricher(httpEnricherOptions, textTemplateOptions, observability))
+			outputs.SetEnrichers(enrichers)
+
 			processors := common.NewProcessors()
 			processors.Add(processor.NewK8sProcessor(&outputs, observability))
-			processors.Add(processor.NewGitlabProcessor(&outputs, observability))
-			processors.Add(processor.NewAlertmanagerProcessor(&outputs, observability))
+			processors.Add(processor.NewGitlabProcessor(gitlabProcessorOptions, &outputs, observability))
+			processors.Add(processor.NewGithubProcessor(githubProcessorOptions, &outputs, observability))
+			processors.Add(processor.NewAlertmanagerProcessor(alertmanagerProcessorOptions, &outputs, observability))
 			processors.Add(processor.NewCustomJsonProcessor(&outputs, observability))
 			processors.Add(processor.NewRancherProcessor(&outputs, observability))
 			processors.Add(processor.NewDataDogProcessor(&outputs, observability))
 			processors.Add(processor.NewSite24x7Processor(&outputs, observability))
 			processors.Add(processor.NewCloudflareProcessor(&outputs, observability))
 			processors.Add(processor.NewGoogleProcessor(&outputs, observability))
+			processors.Add(processor.NewNewRelicProcessor(&outputs, observability))
 			processors.Add(processor.NewAWSProcessor(&outputs, observability))
+			processors.Add(processor.NewSlackProcessor(slackProcessorOptions, &outputs, observability))
+			processors.Add(processor.NewTelegramProcessor(telegramProcessorOptions, &outputs, observability))
+			processors.Add(processor.NewCloudEventsProcessor(cloudEventsProcessorOptions, &outputs, observability))
+
+			charts := render.NewChartProviders(logs)
+			charts.Add(render.NewDataDogChart(datadogChartOptions, chartRenderOptions, observability))
+			charts.Add(render.NewGoogleChart(googleChartOptions, chartRenderOptions, observability))
+			charts.Add(render.NewNewRelicChart(newrelicChartOptions, observability))
 
 			inputs := common.NewInputs()
 			inputs.Add(input.NewHttpInput(httpInputOptions, processors, observability))
 			inputs.Add(input.NewPubSubInput(pubsubInputOptions, processors, observability))
+			inputs.Add(input.NewTelegramInput(telegramInputOptions, processors, observability))
 
 			outputs.Add(output.NewCollectorOutput(&mainWG, collectorOutputOptions, textTemplateOptions, observability))
 			outputs.Add(output.NewKafkaOutput(&mainWG, kafkaOutputOptions, textTemplateOptions, observability))
-			outputs.Add(output.NewTelegramOutput(&mainWG, telegramOutputOptions, textTemplateOptions, grafanaRenderOptions, observability, &outputs))
-			outputs.Add(output.NewSlackOutput(&mainWG, slackOutputOptions, textTemplateOptions, grafanaRenderOptions, observability, &outputs))
-			outputs.Add(output.NewWorkchatOutput(&mainWG, workchatOutputOptions, textTemplateOptions, grafanaRenderOptions, observability))
+			outputs.Add(output.NewTelegramOutput(&mainWG, telegramOutputOptions, textTemplateOptions, grafanaRenderOptions, chartRenderOptions, charts, observability, &outputs))
+			outputs.Add(output.NewSlackOutput(&mainWG, slackOutputOptions, textTemplateOptions, grafanaRenderOptions, chartRenderOptions, charts, observability, &outputs))
+			outputs.Add(output.NewWorkchatOutput(&mainWG, workchatOutputOptions, textTemplateOptions, grafanaRenderOptions, chartRenderOptions, charts, observability))
 			outputs.Add(output.NewNewRelicOutput(&mainWG, newrelicOutputOptions, textTemplateOptions, observability, newrelicEventer))
 			outputs.Add(output.NewDataDogOutput(&mainWG, datadogOutputOptions, textTemplateOptions, observability, datadogEventer))
 			outputs.Add(output.NewGrafanaOutput(&mainWG, grafanaOutputOptions, textTemplateOptions, observability, grafanaEventer))
 			outputs.Add(output.NewPubSubOutput(&mainWG, pubsubOutputOptions, textTemplateOptions, observability))
-			outputs.Add(output.NewGitlabOutput(&mainWG, gitlabOutputOptions, textTemplateOptions, observability))
+			outputs.Add(output.NewGitlabOutput(&mainWG, gitlabOutputOptions, textTemplateOptions, observability, &outputs))
+			outputs.Add(output.NewEmailOutput(&mainWG, emailOutputOptions, textTemplateOptions, grafanaRenderOptions, observability))
+			outputs.Add(output.NewPagerDutyOutput(&mainWG, pagerdutyOutputOptions, textTemplateOptions, observability, &outputs))
+			outputs.Add(output.NewOpsgenieOutput(&mainWG, opsgenieOutputOptions, textTemplateOptions, observability, &outputs))
+			outputs.Add(output.NewAlertmanagerOutput(&mainWG, alertmanagerOutputOptions, textTemplateOptions, observability))
+			outputs.Add(output.NewWebhookOutput(&mainWG, webhookOutputOptions, textTemplateOptions, observability))
 
 			inputs.Start(&mainWG, &outputs)
 			mainWG.Wait()
@@ -500,6 +722,8 @@ func Execute() {
 	flags.StringSliceVar(&rootOptions.Events, "events", rootOptions.Events, "Event providers: grafana, datadog, newrelic")
 
 	flags.StringVar(&textTemplateOptions.TimeFormat, "template-time-format", textTemplateOptions.TimeFormat, "Template time format")
+	flags.StringVar(&templateDir, "template-dir", templateDir, "Template dir with *.tmpl files of shared partials")
+	flags.StringSliceVar(&textTemplateOptions.Strict, "template-strict", textTemplateOptions.Strict, "Template names which fail on missing keys, * for all")
 
 	flags.StringVar(&stdoutOptions.Format, "stdout-format", stdoutOptions.Format, "Stdout format: json, text, template")
 	flags.StringVar(&stdoutOptions.Level, "stdout-level", stdoutOptions.Level, "Stdout level: info, warn, error, debug, panic")
@@ -516,12 +740,17 @@ func Execute() {
 	flags.StringVar(&httpInputOptions.RancherURL, "http-in-rancher-url", httpInputOptions.RancherURL, "Http Rancher url")
 	flags.StringVar(&httpInputOptions.AlertmanagerURL, "http-in-alertmanager-url", httpInputOptions.AlertmanagerURL, "Http Alertmanager url")
 	flags.StringVar(&httpInputOptions.GitlabURL, "http-in-gitlab-url", httpInputOptions.GitlabURL, "Http Gitlab url")
+	flags.StringVar(&httpInputOptions.GithubURL, "http-in-github-url", httpInputOptions.GithubURL, "Http Github url")
 	flags.StringVar(&httpInputOptions.DataDogURL, "http-in-datadog-url", httpInputOptions.DataDogURL, "Http DataDog url")
 	flags.StringVar(&httpInputOptions.Site24x7URL, "http-in-site24x7-url", httpInputOptions.Site24x7URL, "Http Site24x7 url")
 	flags.StringVar(&httpInputOptions.CloudflareURL, "http-in-cloudflare-url", httpInputOptions.CloudflareURL, "Http Cloudflare url")
 	flags.StringVar(&httpInputOptions.GoogleURL, "http-in-google-url", httpInputOptions.GoogleURL, "Http Google url")
+	flags.StringVar(&httpInputOptions.NewRelicURL, "http-in-newrelic-url", httpInputOptions.NewRelicURL, "Http NewRelic url")
 	flags.StringVar(&httpInputOptions.AWSURL, "http-in-aws-url", httpInputOptions.AWSURL, "Http AWS url")
 	flags.StringVar(&httpInputOptions.CustomJsonURL, "http-in-customjson-url", httpInputOptions.CustomJsonURL, "Http CustomJson url")
+	flags.StringVar(&httpInputOptions.SlackURL, "http-in-slack-url", httpInputOptions.SlackURL, "Http Slack interactivity url")
+	flags.StringVar(&httpInputOptions.TelegramURL, "http-in-telegram-url", httpInputOptions.TelegramURL, "Http Telegram webhook url")
+	flags.StringVar(&httpInputOptions.CloudEventsURL, "http-in-cloudevents-url", httpInputOptions.CloudEventsURL, "Http CloudEvents url")
 	flags.StringVar(&httpInputOptions.Listen, "http-in-listen", httpInputOptions.Listen, "Http listen")
 	flags.BoolVar(&httpInputOptions.Tls, "http-in-tls", httpInputOptions.Tls, "Http TLS")
 	flags.StringVar(&httpInputOptions.Cert, "http-in-cert", httpInputOptions.Cert, "Http cert file or content")
@@ -543,6 +772,7 @@ func Execute() {
 	flags.IntVar(&kafkaOutputOptions.NetDialTimeout, "kafka-out-net-dial-timeout", kafkaOutputOptions.NetDialTimeout, "Kafka Net dial timeout")
 	flags.IntVar(&kafkaOutputOptions.NetReadTimeout, "kafka-out-net-read-timeout", kafkaOutputOptions.NetReadTimeout, "Kafka Net read timeout")
 	flags.IntVar(&kafkaOutputOptions.NetWriteTimeout, "kafka-out-net-write-timeout", kafkaOutputOptions.NetWriteTimeout, "Kafka Net write timeout")
+	flags.StringVar(&kafkaOutputOptions.CloudEventsSource, "kafka-out-cloudevents-source", kafkaOutputOptions.CloudEventsSource, "Kafka CloudEvents source, messages are sent as CloudEvents if defined")
 
 	flags.StringVar(&telegramOutputOptions.IDToken, "telegram-out-id-token", telegramOutputOptions.IDToken, "Telegram ID token")
 	flags.StringVar(&telegramOutputOptions.ChatID, "telegram-out-chat-id", telegramOutputOptions.ChatID, "Telegram chat ID")
@@ -553,6 +783,29 @@ func Execute() {
 	flags.StringVar(&telegramOutputOptions.AlertExpression, "telegram-out-alert-expression", telegramOutputOptions.AlertExpression, "Telegram alert expression")
 	flags.BoolVar(&telegramOutputOptions.DisableNotification, "telegram-out-disable-notification", telegramOutputOptions.DisableNotification, "Telegram disable notification")
 	flags.StringVar(&telegramOutputOptions.Forward, "telegram-out-forward", telegramOutputOptions.Forward, "Telegram forward regex pattern")
+	flags.StringVar(&telegramOutputOptions.ResolveMode, "telegram-out-resolve-mode", telegramOutputOptions.ResolveMode, "Telegram mode for events with the same key: reply, edit")
+	flags.StringVar(&telegramOutputOptions.ThreadKey, "telegram-out-thread-key", telegramOutputOptions.ThreadKey, "Telegram thread key template")
+	flags.StringVar(&telegramOutputOptions.ThreadStore, "telegram-out-thread-store", telegramOutputOptions.ThreadStore, "Telegram thread store file")
+	flags.IntVar(&telegramOutputOptions.ThreadTTL, "telegram-out-thread-ttl", telegramOutputOptions.ThreadTTL, "Telegram thread TTL in seconds, threads don't expire if 0")
+	flags.StringVar(&telegramOutputOptions.Render, "telegram-out-render", telegramOutputOptions.Render, "Telegram image render: grafana, chart")
+
+	flags.StringVar(&telegramInputOptions.URL, "telegram-in-url", telegramInputOptions.URL, "Telegram Bot API URL")
+	flags.StringVar(&telegramInputOptions.IDTokens, "telegram-in-id-tokens", telegramInputOptions.IDTokens, "Telegram ID tokens to poll updates, comma separated")
+	flags.IntVar(&telegramInputOptions.Timeout, "telegram-in-timeout", telegramInputOptions.Timeout, "Telegram long polling timeout")
+	flags.StringVar(&telegramProcessorOptions.AllowedChats, "telegram-in-allowed-chats", telegramProcessorOptions.AllowedChats, "Telegram allowed chat IDs, comma separated, * allows all, empty denies all")
+	flags.StringVar(&telegramProcessorOptions.AllowedUsers, "telegram-in-allowed-users", telegramProcessorOptions.AllowedUsers, "Telegram allowed user IDs, comma separated, * allows all, empty denies all")
+	flags.StringVar(&telegramProcessorOptions.Commands, "telegram-in-commands", telegramProcessorOptions.Commands, "Telegram allowed commands, comma separated")
+	flags.StringVar(&telegramProcessorOptions.SecretToken, "telegram-in-secret-token", telegramProcessorOptions.SecretToken, "Telegram webhook secret token, webhook requests are rejected without it")
+
+	flags.BoolVar(&alertmanagerProcessorOptions.Alerts, "alertmanager-in-alerts", alertmanagerProcessorOptions.Alerts, "Alertmanager send event per alert")
+	flags.BoolVar(&alertmanagerProcessorOptions.Group, "alertmanager-in-group", alertmanagerProcessorOptions.Group, "Alertmanager send event per notification group")
+
+	flags.StringVar(&gitlabProcessorOptions.Secret, "gitlab-in-secret", gitlabProcessorOptions.Secret, "Gitlab webhook secret token to verify X-Gitlab-Token, requests are rejected without it or secret of channel")
+	flags.StringVar(&gitlabProcessorOptions.Secrets, "gitlab-in-secrets", gitlabProcessorOptions.Secrets, "Gitlab webhook secret tokens per channel: channel=secret, comma separated")
+	flags.StringVar(&cloudEventsProcessorOptions.Token, "cloudevents-in-token", cloudEventsProcessorOptions.Token, "CloudEvents bearer token to verify Authorization header, requests are not verified without it")
+	flags.StringVar(&githubProcessorOptions.Secret, "github-in-secret", githubProcessorOptions.Secret, "Github webhook secret to verify X-Hub-Signature-256, requests are rejected without it")
+
+	flags.StringVar(&slackProcessorOptions.SigningSecret, "slack-in-signing-secret", slackProcessorOptions.SigningSecret, "Slack signing secret to verify interaction requests, requests are rejected without it")
 
 	flags.StringVar(&slackOutputOptions.Message, "slack-out-message", slackOutputOptions.Message, "Slack message template")
 	flags.StringVar(&slackOutputOptions.ChannelSelector, "slack-out-channel-selector", slackOutputOptions.ChannelSelector, "Slack Channel selector template")
@@ -561,6 +814,13 @@ func Execute() {
 	flags.StringVar(&slackOutputOptions.Token, "slack-out-token", slackOutputOptions.Token, "Slack token")
 	flags.StringVar(&slackOutputOptions.Channel, "slack-out-channel", slackOutputOptions.Channel, "Slack channel")
 	flags.StringVar(&slackOutputOptions.Forward, "slack-out-forward", slackOutputOptions.Forward, "Slack forward regex pattern")
+	flags.BoolVar(&slackOutputOptions.Threads, "slack-out-threads", slackOutputOptions.Threads, "Slack reply in threads for events with the same key")
+	flags.StringVar(&slackOutputOptions.ThreadKey, "slack-out-thread-key", slackOutputOptions.ThreadKey, "Slack thread key template")
+	flags.StringVar(&slackOutputOptions.ThreadStore, "slack-out-thread-store", slackOutputOptions.ThreadStore, "Slack thread store file")
+	flags.IntVar(&slackOutputOptions.ThreadTTL, "slack-out-thread-ttl", slackOutputOptions.ThreadTTL, "Slack thread TTL in seconds, threads don't expire if 0")
+	flags.StringVar(&slackOutputOptions.Update, "slack-out-update", slackOutputOptions.Update, "Slack parent message update template (JSON for chat.update)")
+	flags.StringVar(&slackOutputOptions.Actions, "slack-out-actions", slackOutputOptions.Actions, "Slack actions template (JSON array of Block Kit elements)")
+	flags.StringVar(&slackOutputOptions.Render, "slack-out-render", slackOutputOptions.Render, "Slack image render: grafana, chart")
 
 	flags.StringVar(&workchatOutputOptions.URL, "workchat-out-url", workchatOutputOptions.URL, "Workchat URL")
 	flags.StringVar(&workchatOutputOptions.Message, "workchat-out-message", workchatOutputOptions.Message, "Workchat message template")
@@ -568,16 +828,96 @@ func Execute() {
 	flags.IntVar(&workchatOutputOptions.Timeout, "workchat-out-timeout", workchatOutputOptions.Timeout, "Workchat timeout")
 	flags.StringVar(&workchatOutputOptions.AlertExpression, "workchat-out-alert-expression", workchatOutputOptions.AlertExpression, "Workchat alert expression")
 	flags.StringVar(&workchatOutputOptions.NotificationType, "workchat-out-notification-type", workchatOutputOptions.NotificationType, "Workchat notification type")
+	flags.StringVar(&workchatOutputOptions.Render, "workchat-out-render", workchatOutputOptions.Render, "Workchat image render: grafana, chart")
 
 	flags.StringVar(&pubsubOutputOptions.Credentials, "pubsub-out-credentials", pubsubOutputOptions.Credentials, "PubSub output credentials")
 	flags.StringVar(&pubsubOutputOptions.ProjectID, "pubsub-out-project-id", pubsubOutputOptions.ProjectID, "PubSub output project ID")
 	flags.StringVar(&pubsubOutputOptions.TopicSelector, "pubsub-out-topic-selector", pubsubOutputOptions.TopicSelector, "PubSub output topic selector")
 	flags.StringVar(&pubsubOutputOptions.Message, "pubsub-out-message", pubsubOutputOptions.Message, "PubSub output message")
+	flags.StringVar(&pubsubOutputOptions.CloudEventsSource, "pubsub-out-cloudevents-source", pubsubOutputOptions.CloudEventsSource, "PubSub CloudEvents source, messages are sent as CloudEvents if defined")
 
 	flags.StringVar(&gitlabOutputOptions.BaseURL, "gitlab-out-base-url", gitlabOutputOptions.BaseURL, "Gitlab output base URL")
 	flags.StringVar(&gitlabOutputOptions.Token, "gitlab-out-token", gitlabOutputOptions.Token, "Gitlab output token")
 	flags.StringVar(&gitlabOutputOptions.Projects, "gitlab-out-projects", gitlabOutputOptions.Projects, "Gitlab output projects")
 	flags.StringVar(&gitlabOutputOptions.Variables, "gitlab-out-variables", gitlabOutputOptions.Variables, "Gitlab output variables")
+	flags.StringVar(&gitlabOutputOptions.Actions, "gitlab-out-actions", gitlabOutputOptions.Actions, "Gitlab output actions template")
+	flags.IntVar(&gitlabOutputOptions.PipelineTimeout, "gitlab-out-pipeline-timeout", gitlabOutputOptions.PipelineTimeout, "Gitlab output pipeline tracking timeout in seconds, 0 disables tracking")
+	flags.IntVar(&gitlabOutputOptions.PipelineInterval, "gitlab-out-pipeline-interval", gitlabOutputOptions.PipelineInterval, "Gitlab output pipeline polling interval in seconds")
+
+	flags.StringVar(&pagerdutyOutputOptions.URL, "pagerduty-out-url", pagerdutyOutputOptions.URL, "PagerDuty Events API v2 URL")
+	flags.IntVar(&pagerdutyOutputOptions.Timeout, "pagerduty-out-timeout", pagerdutyOutputOptions.Timeout, "PagerDuty timeout")
+	flags.StringVar(&pagerdutyOutputOptions.RoutingKey, "pagerduty-out-routing-key", pagerdutyOutputOptions.RoutingKey, "PagerDuty default routing key")
+	flags.StringVar(&pagerdutyOutputOptions.RoutingKeySelector, "pagerduty-out-routing-key-selector", pagerdutyOutputOptions.RoutingKeySelector, "PagerDuty routing key selector template")
+	flags.StringVar(&pagerdutyOutputOptions.Action, "pagerduty-out-action", pagerdutyOutputOptions.Action, "PagerDuty action template: trigger, acknowledge, resolve")
+	flags.StringVar(&pagerdutyOutputOptions.DedupKey, "pagerduty-out-dedup-key", pagerdutyOutputOptions.DedupKey, "PagerDuty dedup key template")
+	flags.StringVar(&pagerdutyOutputOptions.Message, "pagerduty-out-message", pagerdutyOutputOptions.Message, "PagerDuty summary template")
+	flags.StringVar(&pagerdutyOutputOptions.Severity, "pagerduty-out-severity", pagerdutyOutputOptions.Severity, "PagerDuty severity template")
+	flags.StringVar(&pagerdutyOutputOptions.Source, "pagerduty-out-source", pagerdutyOutputOptions.Source, "PagerDuty source template")
+	flags.StringVar(&pagerdutyOutputOptions.Component, "pagerduty-out-component", pagerdutyOutputOptions.Component, "PagerDuty component template")
+	flags.StringVar(&pagerdutyOutputOptions.Group, "pagerduty-out-group", pagerdutyOutputOptions.Group, "PagerDuty group template")
+	flags.StringVar(&pagerdutyOutputOptions.Class, "pagerduty-out-class", pagerdutyOutputOptions.Class, "PagerDuty class template")
+	flags.StringVar(&pagerdutyOutputOptions.Details, "pagerduty-out-details", pagerdutyOutputOptions.Details, "PagerDuty custom details template (JSON)")
+	flags.StringVar(&pagerdutyOutputOptions.Forward, "pagerduty-out-forward", pagerdutyOutputOptions.Forward, "PagerDuty forward regex pattern")
+
+	flags.StringVar(&opsgenieOutputOptions.URL, "opsgenie-out-url", opsgenieOutputOptions.URL, "Opsgenie API URL, overrides region")
+	flags.StringVar(&opsgenieOutputOptions.Region, "opsgenie-out-region", opsgenieOutputOptions.Region, "Opsgenie region: us, eu")
+	flags.StringVar(&opsgenieOutputOptions.WebURL, "opsgenie-out-web-url", opsgenieOutputOptions.WebURL, "Opsgenie web URL to build alert links")
+	flags.IntVar(&opsgenieOutputOptions.Timeout, "opsgenie-out-timeout", opsgenieOutputOptions.Timeout, "Opsgenie timeout")
+	flags.StringVar(&opsgenieOutputOptions.ApiKey, "opsgenie-out-api-key", opsgenieOutputOptions.ApiKey, "Opsgenie API key")
+	flags.StringVar(&opsgenieOutputOptions.Action, "opsgenie-out-action", opsgenieOutputOptions.Action, "Opsgenie action template: create, close")
+	flags.StringVar(&opsgenieOutputOptions.Alias, "opsgenie-out-alias", opsgenieOutputOptions.Alias, "Opsgenie alias template")
+	flags.StringVar(&opsgenieOutputOptions.Message, "opsgenie-out-message", opsgenieOutputOptions.Message, "Opsgenie message template")
+	flags.StringVar(&opsgenieOutputOptions.Description, "opsgenie-out-description", opsgenieOutputOptions.Description, "Opsgenie description template")
+	flags.StringVar(&opsgenieOutputOptions.Priority, "opsgenie-out-priority", opsgenieOutputOptions.Priority, "Opsgenie priority template, P1, P3 or P5 by severity of event if empty")
+	flags.StringVar(&opsgenieOutputOptions.Responders, "opsgenie-out-responders", opsgenieOutputOptions.Responders, "Opsgenie responders template")
+	flags.StringVar(&opsgenieOutputOptions.Tags, "opsgenie-out-tags", opsgenieOutputOptions.Tags, "Opsgenie tags template")
+	flags.StringVar(&opsgenieOutputOptions.Details, "opsgenie-out-details", opsgenieOutputOptions.Details, "Opsgenie details template (JSON)")
+	flags.StringVar(&opsgenieOutputOptions.Entity, "opsgenie-out-entity", opsgenieOutputOptions.Entity, "Opsgenie entity template")
+	flags.StringVar(&opsgenieOutputOptions.Source, "opsgenie-out-source", opsgenieOutputOptions.Source, "Opsgenie source template")
+	flags.IntVar(&opsgenieOutputOptions.Retries, "opsgenie-out-retries", opsgenieOutputOptions.Retries, "Opsgenie retries on rate limit")
+	flags.StringVar(&opsgenieOutputOptions.Forward, "opsgenie-out-forward", opsgenieOutputOptions.Forward, "Opsgenie forward regex pattern")
+
+	flags.StringVar(&alertmanagerOutputOptions.Silence, "alertmanager-out-silence", alertmanagerOutputOptions.Silence, "Alertmanager silence template, silence of id is expired if expire is true")
+	flags.StringVar(&alertmanagerOutputOptions.Duration, "alertmanager-out-duration", alertmanagerOutputOptions.Duration, "Alertmanager default silence duration")
+
+	flags.StringVar(&webhookOutputOptions.URL, "webhook-out-url", webhookOutputOptions.URL, "Webhook URLs separated by comma")
+	flags.StringVar(&webhookOutputOptions.Message, "webhook-out-message", webhookOutputOptions.Message, "Webhook message template, data of event is sent if not defined")
+	flags.IntVar(&webhookOutputOptions.Timeout, "webhook-out-timeout", webhookOutputOptions.Timeout, "Webhook timeout")
+	flags.BoolVar(&webhookOutputOptions.Insecure, "webhook-out-insecure", webhookOutputOptions.Insecure, "Webhook insecure skip verify")
+	flags.StringVar(&webhookOutputOptions.CloudEventsSource, "webhook-out-cloudevents-source", webhookOutputOptions.CloudEventsSource, "Webhook CloudEvents source, messages are sent as CloudEvents if defined")
+	flags.StringVar(&webhookOutputOptions.Token, "webhook-out-token", webhookOutputOptions.Token, "Webhook bearer token, it's checked by CloudEvents input of other instance")
+
+	flags.StringVar(&emailOutputOptions.Address, "email-out-address", emailOutputOptions.Address, "Email SMTP address (host:port)")
+	flags.StringVar(&emailOutputOptions.Mode, "email-out-mode", emailOutputOptions.Mode, "Email SMTP mode: starttls, tls, none")
+	flags.BoolVar(&emailOutputOptions.Insecure, "email-out-insecure", emailOutputOptions.Insecure, "Email SMTP insecure TLS")
+	flags.IntVar(&emailOutputOptions.Timeout, "email-out-timeout", emailOutputOptions.Timeout, "Email SMTP timeout")
+	flags.StringVar(&emailOutputOptions.Username, "email-out-username", emailOutputOptions.Username, "Email SMTP username")
+	flags.StringVar(&emailOutputOptions.Password, "email-out-password", emailOutputOptions.Password, "Email SMTP password")
+	flags.StringVar(&emailOutputOptions.From, "email-out-from", emailOutputOptions.From, "Email from address")
+	flags.StringVar(&emailOutputOptions.To, "email-out-to", emailOutputOptions.To, "Email default recipients, comma separated")
+	flags.StringVar(&emailOutputOptions.RecipientSelector, "email-out-recipient-selector", emailOutputOptions.RecipientSelector, "Email recipient selector template")
+	flags.StringVar(&emailOutputOptions.Subject, "email-out-subject", emailOutputOptions.Subject, "Email subject template")
+	flags.StringVar(&emailOutputOptions.Message, "email-out-message", emailOutputOptions.Message, "Email HTML message template")
+	flags.StringVar(&emailOutputOptions.Text, "email-out-text", emailOutputOptions.Text, "Email text message template")
+	flags.StringVar(&emailOutputOptions.AlertExpression, "email-out-alert-expression", emailOutputOptions.AlertExpression, "Email alert expression")
+	flags.IntVar(&emailOutputOptions.Window, "email-out-window", emailOutputOptions.Window, "Email digest window in seconds, 0 disables batching")
+
+	flags.StringVar(&alertmanagerApiOptions.URL, "alertmanager-api-url", alertmanagerApiOptions.URL, "Alertmanager API URL")
+	flags.IntVar(&alertmanagerApiOptions.Timeout, "alertmanager-api-timeout", alertmanagerApiOptions.Timeout, "Alertmanager API timeout")
+
+	flags.StringVar(&prometheusApiOptions.URL, "prometheus-api-url", prometheusApiOptions.URL, "Prometheus compatible API URL")
+	flags.IntVar(&prometheusApiOptions.Timeout, "prometheus-api-timeout", prometheusApiOptions.Timeout, "Prometheus API timeout")
+	flags.StringVar(&prometheusApiOptions.User, "prometheus-api-user", prometheusApiOptions.User, "Prometheus API basic auth user")
+	flags.StringVar(&prometheusApiOptions.Password, "prometheus-api-password", prometheusApiOptions.Password, "Prometheus API basic auth password")
+
+	flags.IntVar(&enrichersOptions.Timeout, "enrichers-timeout", enrichersOptions.Timeout, "Enrichers timeout of each enricher in seconds, 0 waits without limit")
+	flags.StringVar(&prometheusEnricherOptions.Queries, "prometheus-enricher-queries", prometheusEnricherOptions.Queries, "Prometheus enricher queries template")
+	flags.StringVar(&prometheusEnricherOptions.Step, "prometheus-enricher-step", prometheusEnricherOptions.Step, "Prometheus enricher default step of range queries")
+
+	flags.StringVar(&httpEnricherOptions.Steps, "http-enricher-steps", httpEnricherOptions.Steps, "Http enricher steps template")
+	flags.IntVar(&httpEnricherOptions.Timeout, "http-enricher-timeout", httpEnricherOptions.Timeout, "Http enricher timeout")
+	flags.BoolVar(&httpEnricherOptions.Insecure, "http-enricher-insecure", httpEnricherOptions.Insecure, "Http enricher insecure")
+	flags.IntVar(&httpEnricherOptions.TTL, "http-enricher-ttl", httpEnricherOptions.TTL, "Http enricher cache TTL in seconds, 0 disables cache")
 
 	flags.StringVar(&grafanaRenderOptions.URL, "grafana-render-url", grafanaRenderOptions.URL, "Grafana render URL")
 	flags.IntVar(&grafanaRenderOptions.Timeout, "grafana-render-timeout", grafanaRenderOptions.Timeout, "Grafan render timeout")
@@ -587,6 +927,24 @@ func Execute() {
 	flags.IntVar(&grafanaRenderOptions.Period, "grafana-render-period", grafanaRenderOptions.Period, "Grafana render period in minutes")
 	flags.IntVar(&grafanaRenderOptions.ImageWidth, "grafana-render-image-width", grafanaRenderOptions.ImageWidth, "Grafan render image width")
 	flags.IntVar(&grafanaRenderOptions.ImageHeight, "grafana-render-image-height", grafanaRenderOptions.ImageHeight, "Grafan render image height")
+	flags.StringVar(&grafanaRenderOptions.PanelType, "grafana-render-panel-type", grafanaRenderOptions.PanelType, "Grafana render panel type of generated dashboards: timeseries, graph")
+
+	flags.IntVar(&chartRenderOptions.Period, "chart-render-period", chartRenderOptions.Period, "Chart render period in minutes")
+	flags.IntVar(&chartRenderOptions.Step, "chart-render-step", chartRenderOptions.Step, "Chart render step in seconds, 0 means auto")
+	flags.IntVar(&chartRenderOptions.ImageWidth, "chart-render-image-width", chartRenderOptions.ImageWidth, "Chart render image width")
+	flags.IntVar(&chartRenderOptions.ImageHeight, "chart-render-image-height", chartRenderOptions.ImageHeight, "Chart render image height")
+
+	flags.StringVar(&datadogChartOptions.URL, "datadog-chart-url", datadogChartOptions.URL, "DataDog chart API URL, like https://api.datadoghq.com")
+	flags.StringVar(&datadogChartOptions.ApiKey, "datadog-chart-api-key", datadogChartOptions.ApiKey, "DataDog chart API key")
+	flags.StringVar(&datadogChartOptions.AppKey, "datadog-chart-app-key", datadogChartOptions.AppKey, "DataDog chart application key, snapshot is used if keys are not set")
+	flags.IntVar(&datadogChartOptions.Timeout, "datadog-chart-timeout", datadogChartOptions.Timeout, "DataDog chart timeout in seconds")
+	flags.IntVar(&datadogChartOptions.Attempts, "datadog-chart-attempts", datadogChartOptions.Attempts, "DataDog chart snapshot download attempts")
+
+	flags.StringVar(&googleChartOptions.Credentials, "google-chart-credentials", googleChartOptions.Credentials, "Google chart credentials file or json")
+	flags.IntVar(&googleChartOptions.Timeout, "google-chart-timeout", googleChartOptions.Timeout, "Google chart timeout in seconds")
+
+	flags.IntVar(&newrelicChartOptions.Timeout, "newrelic-chart-timeout", newrelicChartOptions.Timeout, "NewRelic chart timeout in seconds")
+	flags.IntVar(&newrelicChartOptions.Attempts, "newrelic-chart-attempts", newrelicChartOptions.Attempts, "NewRelic violation chart download attempts, 0 disables chart")
 
 	flags.StringVar(&jaegerOptions.ServiceName, "jaeger-service-name", jaegerOptions.ServiceName, "Jaeger service name")
 	flags.StringVar(&jaegerOptions.AgentHost, "jaeger-agent-host", jaegerOptions.AgentHost, "Jaeger agent host")
@@ -661,6 +1019,56 @@ func Execute() {
 		},
 	})
 
+	lintCmd := &cobra.Command{
+		Use:   "lint [template files]",
+		Short: "Check field references of templates against data of events",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+
+			library, err := render.NewTemplateLibrary(templateDir, logs)
+			if err != nil {
+				logs.Error(err)
+				os.Exit(1)
+			}
+			textTemplateOptions.Library = library
+			linter := render.NewTemplateLinter(processor.EventDataTypes())
+
+			failed := false
+			for _, file := range args {
+
+				// telegram.message is executed as telegram-message layout
+				name := lintOptions.Name
+				if utils.IsEmpty(name) {
+					name = strings.ReplaceAll(filepath.Base(file), ".", "-")
+				}
+
+				tpl := render.NewTextTemplate(name, file, textTemplateOptions, nil, logs)
+				if tpl == nil {
+					failed = true
+					continue
+				}
+
+				issues, err := linter.Lint(tpl, lintOptions.EventType)
+				if err != nil {
+					logs.Error(err)
+					os.Exit(1)
+				}
+				for _, i := range issues {
+					fmt.Printf("%s: %s\n", file, i)
+				}
+				failed = failed || len(issues) > 0
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	lintFlags := lintCmd.Flags()
+	lintFlags.StringVar(&lintOptions.EventType, "event-type", lintOptions.EventType, "Event type of templates, by default data is checked inside of {{if eq .type \"...\"}} blocks")
+	lintFlags.StringVar(&lintOptions.Name, "name", lintOptions.Name, "Template name, by default file name with dashes")
+	rootCmd.AddCommand(lintCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		logs.Error(err)
 		os.Exit(1)
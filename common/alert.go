@@ -0,0 +1,73 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+type AlertLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// AlertData is normalized view of alert-like events, so one template handles all alert sources, raw payload of
+// processor is still available by .data
+type AlertData struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Status      string            `json:"status"`
+	Severity    string            `json:"severity"`
+	Source      string            `json:"source"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Links       []*AlertLink      `json:"links,omitempty"`
+	StartedAt   *time.Time        `json:"startedAt,omitempty"`
+	EndedAt     *time.Time        `json:"endedAt,omitempty"`
+}
+
+// AddLink adds link if URL is set, links with the same URL are added once
+func (a *AlertData) AddLink(title, URL string) {
+
+	URL = strings.TrimSpace(URL)
+	if URL == "" || URL == "null" {
+		return
+	}
+	for _, l := range a.Links {
+		if l.URL == URL {
+			return
+		}
+	}
+	a.Links = append(a.Links, &AlertLink{Title: title, URL: URL})
+}
+
+// AddLabel adds label if value is set
+func (a *AlertData) AddLabel(name, value string) {
+
+	value = strings.TrimSpace(value)
+	if name == "" || value == "" {
+		return
+	}
+	if a.Labels == nil {
+		a.Labels = make(map[string]string)
+	}
+	a.Labels[name] = value
+}
+
+// SetStartedAt sets start time, zero time is skipped
+func (a *AlertData) SetStartedAt(t time.Time) {
+
+	if t.IsZero() || t.Unix() <= 0 {
+		return
+	}
+	t = t.UTC()
+	a.StartedAt = &t
+}
+
+// SetEndedAt sets end time, zero time is skipped
+func (a *AlertData) SetEndedAt(t time.Time) {
+
+	if t.IsZero() || t.Unix() <= 0 {
+		return
+	}
+	t = t.UTC()
+	a.EndedAt = &t
+}
@@ -0,0 +1,360 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+const (
+	CloudEventsSpecVersion      = "1.0"
+	CloudEventsContentType      = "application/cloudevents+json"
+	CloudEventsBatchContentType = "application/cloudevents-batch+json"
+	cloudEventsTraceParent      = "traceparent"
+	cloudEventsTraceState       = "tracestate"
+	cloudEventsSeverity         = "severity"
+	cloudEventsStatus           = "status"
+	cloudEventsDefaultTextType  = "text/plain"
+	cloudEventsDefaultJsonType  = "application/json"
+)
+
+var cloudEventsContextAttributes = []string{"specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema"}
+
+var cloudEventsHexID = regexp.MustCompile("^[0-9a-f]+$")
+
+// CloudEvent keeps context attributes of CNCF CloudEvents 1.0, extensions are kept as strings
+type CloudEvent struct {
+	ID              string
+	Source          string
+	SpecVersion     string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	Data            []byte
+	Extensions      map[string]string
+}
+
+func (ce *CloudEvent) validate() error {
+
+	if !strings.HasPrefix(ce.SpecVersion, "1.") {
+		return fmt.Errorf("cloudevents specversion %s is not supported", ce.SpecVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return errors.New("cloudevents id, source or type is empty")
+	}
+	return nil
+}
+
+func (ce *CloudEvent) isJson() bool {
+
+	ct := strings.ToLower(ce.DataContentType)
+	return ct == "" || strings.HasSuffix(strings.Split(ct, ";")[0], "json")
+}
+
+// Attributes returns context attributes and extensions of binary content mode by prefix: ce- for http and pubsub,
+// ce_ for kafka. Data content type is set to contentType key as protocols have their own header for it
+func (ce *CloudEvent) Attributes(prefix, contentType string) map[string]string {
+
+	m := map[string]string{
+		prefix + "specversion": ce.SpecVersion,
+		prefix + "id":          ce.ID,
+		prefix + "source":      ce.Source,
+		prefix + "type":        ce.Type,
+	}
+	if ce.Subject != "" {
+		m[prefix+"subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		m[prefix+"time"] = ce.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if ce.DataSchema != "" {
+		m[prefix+"dataschema"] = ce.DataSchema
+	}
+	if ce.DataContentType != "" {
+		m[contentType] = ce.DataContentType
+	}
+	for k, v := range ce.Extensions {
+		m[prefix+k] = v
+	}
+	return m
+}
+
+// TraceHeader returns carrier of trace context extensions, so span can be continued from them
+func (ce *CloudEvent) TraceHeader() http.Header {
+
+	h := http.Header{}
+	if v, ok := ce.Extensions[cloudEventsTraceParent]; ok {
+		h.Set(cloudEventsTraceParent, v)
+	}
+	if v, ok := ce.Extensions[cloudEventsTraceState]; ok {
+		h.Set(cloudEventsTraceState, v)
+	}
+	return h
+}
+
+// Event maps cloud event to event of channel, context attributes are available by .via.CloudEvents
+func (ce *CloudEvent) Event(channel string) *Event {
+
+	var data interface{} = string(ce.Data)
+	if ce.isJson() {
+		var v interface{}
+		if err := json.Unmarshal(ce.Data, &v); err == nil {
+			data = v
+		}
+	}
+
+	attributes := make(map[string]interface{})
+	for k, v := range ce.Attributes("", "datacontenttype") {
+		attributes[k] = v
+	}
+
+	e := &Event{
+		ID:       ce.ID,
+		Channel:  channel,
+		Type:     ce.Type,
+		Key:      ce.Subject,
+		Severity: NormalizeSeverity(ce.Extensions[cloudEventsSeverity]),
+		Status:   ce.Extensions[cloudEventsStatus],
+		Data:     data,
+		Via:      map[string]interface{}{"CloudEvents": attributes},
+	}
+	if ce.Time.IsZero() {
+		e.SetTime(time.Now().UTC())
+	} else {
+		e.SetTime(ce.Time.UTC())
+	}
+	return e
+}
+
+func (ce *CloudEvent) unmarshalMap(m map[string]json.RawMessage) error {
+
+	ce.Extensions = make(map[string]string)
+	for k, raw := range m {
+
+		if k == "data" || k == "data_base64" {
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// extensions can be numbers or booleans, they are kept as strings
+			s = string(raw)
+		}
+
+		switch k {
+		case "specversion":
+			ce.SpecVersion = s
+		case "id":
+			ce.ID = s
+		case "source":
+			ce.Source = s
+		case "type":
+			ce.Type = s
+		case "subject":
+			ce.Subject = s
+		case "datacontenttype":
+			ce.DataContentType = s
+		case "dataschema":
+			ce.DataSchema = s
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return err
+			}
+			ce.Time = t
+		default:
+			ce.Extensions[k] = s
+		}
+	}
+
+	if raw, ok := m["data_base64"]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		ce.Data = b
+	} else if raw, ok := m["data"]; ok {
+		var s string
+		if !ce.isJson() && json.Unmarshal(raw, &s) == nil {
+			ce.Data = []byte(s)
+		} else {
+			ce.Data = raw
+		}
+		if ce.DataContentType == "" {
+			ce.DataContentType = cloudEventsDefaultJsonType
+		}
+	}
+	return ce.validate()
+}
+
+// ParseCloudEvents reads cloud events of http request in structured, batched or binary content mode
+func ParseCloudEvents(header http.Header, body []byte) ([]*CloudEvent, error) {
+
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(header.Get("Content-Type"), ";")[0]))
+
+	switch contentType {
+	case CloudEventsContentType:
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(body, &m); err != nil {
+			return nil, err
+		}
+		ce := &CloudEvent{}
+		if err := ce.unmarshalMap(m); err != nil {
+			return nil, err
+		}
+		return []*CloudEvent{ce}, nil
+	case CloudEventsBatchContentType:
+		var arr []map[string]json.RawMessage
+		if err := json.Unmarshal(body, &arr); err != nil {
+			return nil, err
+		}
+		var events []*CloudEvent
+		for _, m := range arr {
+			ce := &CloudEvent{}
+			if err := ce.unmarshalMap(m); err != nil {
+				return nil, err
+			}
+			events = append(events, ce)
+		}
+		return events, nil
+	}
+
+	ce := &CloudEvent{
+		SpecVersion:     header.Get("Ce-Specversion"),
+		ID:              header.Get("Ce-Id"),
+		Source:          header.Get("Ce-Source"),
+		Type:            header.Get("Ce-Type"),
+		Subject:         header.Get("Ce-Subject"),
+		DataSchema:      header.Get("Ce-Dataschema"),
+		DataContentType: header.Get("Content-Type"),
+		Data:            body,
+		Extensions:      make(map[string]string),
+	}
+
+	if s := header.Get("Ce-Time"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		ce.Time = t
+	}
+
+	known := make(map[string]bool)
+	for _, a := range cloudEventsContextAttributes {
+		known[a] = true
+	}
+	for k := range header {
+		name := strings.ToLower(k)
+		if !strings.HasPrefix(name, "ce-") || known[name[3:]] {
+			continue
+		}
+		ce.Extensions[name[3:]] = header.Get(k)
+	}
+
+	// w3c trace context of transport is used, if trace extensions are not set
+	for _, k := range []string{cloudEventsTraceParent, cloudEventsTraceState} {
+		if _, ok := ce.Extensions[k]; !ok && header.Get(k) != "" {
+			ce.Extensions[k] = header.Get(k)
+		}
+	}
+
+	if err := ce.validate(); err != nil {
+		return nil, err
+	}
+	return []*CloudEvent{ce}, nil
+}
+
+func cloudEventsTraceID(id string, size int) string {
+
+	id = strings.ToLower(strings.TrimPrefix(id, "0x"))
+	if id == "" || len(id) > size {
+		return ""
+	}
+
+	// datadog uses decimal ids, jaeger and opentelemetry hex ones
+	if len(id) != size && strings.Trim(id, "0123456789") == "" {
+		if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+			id = strconv.FormatUint(n, 16)
+		}
+	}
+	if !cloudEventsHexID.MatchString(id) {
+		return ""
+	}
+	return strings.Repeat("0", size-len(id)) + id
+}
+
+// CloudEventsTraceParent returns w3c traceparent of span context, which is used by distributed tracing extension
+func CloudEventsTraceParent(ctx sreCommon.TracerSpanContext) string {
+
+	if ctx == nil {
+		return ""
+	}
+	traceID := cloudEventsTraceID(ctx.GetTraceID(), 32)
+	spanID := cloudEventsTraceID(ctx.GetSpanID(), 16)
+	if traceID == "" || spanID == "" {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", traceID, spanID)
+}
+
+// NewCloudEvent makes cloud event of event with data, id of event is used so retries and other outputs keep the same id,
+// correlation key is subject
+func NewCloudEvent(e *Event, source string, data []byte, spanCtx sreCommon.TracerSpanContext) (*CloudEvent, error) {
+
+	id := e.ID
+	if id == "" {
+		b, err := e.JsonBytes()
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		id = hex.EncodeToString(sum[:16])
+	}
+
+	if e.Channel != "" {
+		source = fmt.Sprintf("%s/%s", strings.TrimRight(source, "/"), e.Channel)
+	}
+
+	contentType := cloudEventsDefaultTextType
+	if json.Valid(data) {
+		contentType = cloudEventsDefaultJsonType
+	}
+
+	ce := &CloudEvent{
+		ID:              id,
+		Source:          source,
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            e.Type,
+		Subject:         e.Key,
+		Time:            e.Time,
+		DataContentType: contentType,
+		Data:            data,
+		Extensions:      make(map[string]string),
+	}
+	if e.Severity != "" {
+		ce.Extensions[cloudEventsSeverity] = e.Severity
+	}
+	if e.Status != "" {
+		ce.Extensions[cloudEventsStatus] = e.Status
+	}
+	if tp := CloudEventsTraceParent(spanCtx); tp != "" {
+		ce.Extensions[cloudEventsTraceParent] = tp
+	}
+	return ce, nil
+}
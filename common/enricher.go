@@ -0,0 +1,6 @@
+package common
+
+type Enricher interface {
+	Enrich(event *Event) error
+	Name() string
+}
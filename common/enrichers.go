@@ -0,0 +1,91 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+type EnrichersOptions struct {
+	Timeout int
+}
+
+type Enrichers struct {
+	list    []Enricher
+	options EnrichersOptions
+	logger  sreCommon.Logger
+}
+
+func (ens *Enrichers) Add(e Enricher) {
+
+	if reflect.ValueOf(e).IsNil() {
+		return
+	}
+	ens.list = append(ens.list, e)
+}
+
+// run calls enricher with copy of event and waits for it not longer than timeout, so slow enricher doesn't hold
+// request of processor. Result of enricher which is timed out is dropped
+func (ens *Enrichers) run(en Enricher, e *Event) (interface{}, bool, error) {
+
+	c := *e
+	c.Enrich = make(map[string]interface{})
+	for k, v := range e.Enrich {
+		c.Enrich[k] = v
+	}
+	c.Via = make(map[string]interface{})
+	for k, v := range e.Via {
+		c.Via[k] = v
+	}
+
+	if ens.options.Timeout <= 0 {
+		err := en.Enrich(&c)
+		value, ok := c.Enrich[en.Name()]
+		return value, ok, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- en.Enrich(&c)
+	}()
+
+	select {
+	case err := <-done:
+		value, ok := c.Enrich[en.Name()]
+		return value, ok, err
+	case <-time.After(time.Duration(ens.options.Timeout) * time.Second):
+		return nil, false, fmt.Errorf("timed out after %ds", ens.options.Timeout)
+	}
+}
+
+// Enrich runs enrichers one by one, errors are logged and event goes further as is
+func (ens *Enrichers) Enrich(e *Event) {
+
+	if e == nil {
+		return
+	}
+
+	for _, en := range ens.list {
+
+		if _, ok := e.Enrich[en.Name()]; ok {
+			continue
+		}
+
+		value, ok, err := ens.run(en, e)
+		if err != nil && ens.logger != nil {
+			ens.logger.Error("%s enricher failed: %v", en.Name(), err)
+		}
+		if ok {
+			e.SetEnrich(en.Name(), value)
+		}
+	}
+}
+
+func NewEnrichers(options EnrichersOptions, logger sreCommon.Logger) *Enrichers {
+	return &Enrichers{
+		options: options,
+		logger:  logger,
+	}
+}
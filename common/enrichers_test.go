@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+type testEnricher struct {
+	name  string
+	delay time.Duration
+}
+
+func (t *testEnricher) Name() string {
+	return t.name
+}
+
+func (t *testEnricher) Enrich(event *Event) error {
+
+	time.Sleep(t.delay)
+	event.SetEnrich(t.name, event.Type)
+	return nil
+}
+
+func TestEnrichersTimeout(t *testing.T) {
+
+	ens := NewEnrichers(EnrichersOptions{Timeout: 1}, nil)
+	ens.Add(&testEnricher{name: "slow", delay: 3 * time.Second})
+	ens.Add(&testEnricher{name: "fast"})
+
+	e := &Event{Type: "TestEvent"}
+
+	start := time.Now()
+	ens.Enrich(e)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("enrichers took %s", d)
+	}
+
+	if _, ok := e.Enrich["slow"]; ok {
+		t.Error("result of timed out enricher is kept")
+	}
+	if e.Enrich["fast"] != "TestEvent" {
+		t.Errorf("result of fast enricher is lost: %v", e.Enrich)
+	}
+}
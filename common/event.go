@@ -1,18 +1,67 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"time"
 
 	sreCommon "github.com/devopsext/sre/common"
 )
 
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	SeverityOk       = "ok"
+
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+	StatusInfo     = "info"
+)
+
+var eventSeverities = map[string]string{
+	"critical":  SeverityCritical,
+	"fatal":     SeverityCritical,
+	"error":     SeverityCritical,
+	"high":      SeverityCritical,
+	"page":      SeverityCritical,
+	"down":      SeverityCritical,
+	"p1":        SeverityCritical,
+	"warning":   SeverityWarning,
+	"warn":      SeverityWarning,
+	"medium":    SeverityWarning,
+	"trouble":   SeverityWarning,
+	"no data":   SeverityWarning,
+	"p2":        SeverityWarning,
+	"p3":        SeverityWarning,
+	"info":      SeverityInfo,
+	"low":       SeverityInfo,
+	"normal":    SeverityInfo,
+	"p4":        SeverityInfo,
+	"p5":        SeverityInfo,
+	"ok":        SeverityOk,
+	"up":        SeverityOk,
+	"resolved":  SeverityOk,
+	"recovered": SeverityOk,
+	"success":   SeverityOk,
+}
+
+// Event has ID, which is the same for the same content, correlation key of alert or pipeline, which is the same for
+// all its events, and normalized severity and status. Alert is set for alert-like events only
 type Event struct {
+	ID          string                 `json:"id,omitempty"`
 	Time        time.Time              `json:"time"`
 	Channel     string                 `json:"channel"`
 	Type        string                 `json:"type"`
+	Key         string                 `json:"key,omitempty"`
+	Severity    string                 `json:"severity,omitempty"`
+	Status      string                 `json:"status,omitempty"`
 	Data        interface{}            `json:"data"`
+	Alert       *AlertData             `json:"alert,omitempty"`
 	Via         map[string]interface{} `json:"via,omitempty"`
+	Enrich      map[string]interface{} `json:"enrich,omitempty"`
 	spanContext sreCommon.TracerSpanContext
 	logger      sreCommon.Logger
 }
@@ -54,6 +103,36 @@ func (e *Event) JsonMap() (map[string]interface{}, error) {
 	return m, nil
 }
 
+// Copy returns event with the same ID, key, alert and enrichment, so copy isn't normalized or enriched again.
+// Via, enrichment and alert are copied, because event is shared by outputs
+func (e *Event) Copy() *Event {
+
+	c := *e
+	c.Via = make(map[string]interface{})
+	for k, v := range e.Via {
+		c.Via[k] = v
+	}
+	if e.Enrich != nil {
+		c.Enrich = make(map[string]interface{})
+		for k, v := range e.Enrich {
+			c.Enrich[k] = v
+		}
+	}
+	if e.Alert != nil {
+		alert := *e.Alert
+		c.Alert = &alert
+	}
+	return &c
+}
+
+// Forward returns copy of event with response of output in via, so other outputs can refer to it
+func (e *Event) Forward(name string, obj interface{}) *Event {
+
+	c := e.Copy()
+	c.Via[name] = obj
+	return c
+}
+
 func (e *Event) SetSpanContext(context sreCommon.TracerSpanContext) {
 	e.spanContext = context
 }
@@ -66,6 +145,69 @@ func (e *Event) SetLogger(logger sreCommon.Logger) {
 	e.logger = logger
 }
 
+// SetEnrich keeps result of enricher by its name, enrichers run before outputs so no lock is needed
+func (e *Event) SetEnrich(name string, value interface{}) {
+
+	if e.Enrich == nil {
+		e.Enrich = make(map[string]interface{})
+	}
+	e.Enrich[name] = value
+}
+
 func (e *Event) SetTime(time time.Time) {
 	e.Time = time
 }
+
+// NormalizeSeverity returns critical, warning, info or ok for vendor severity, priority or state, unknown is empty
+func NormalizeSeverity(s string) string {
+	return eventSeverities[strings.ToLower(strings.TrimSpace(s))]
+}
+
+// SetAlert sets correlation key, status and severity of event, severity is normalized
+func (e *Event) SetAlert(key, status, severity string) {
+
+	e.Key = key
+	e.Status = status
+	e.Severity = NormalizeSeverity(severity)
+}
+
+// normalize sets ID by hash of event and defaults of status and severity, informational events have info for both
+func (e *Event) normalize() error {
+
+	if e.Status == "" {
+		e.Status = StatusInfo
+	}
+
+	if e.Severity == "" {
+		switch e.Status {
+		case StatusResolved:
+			e.Severity = SeverityOk
+		case StatusFiring:
+			e.Severity = SeverityWarning
+		default:
+			e.Severity = SeverityInfo
+		}
+	}
+
+	// alert has the same status and severity as event, unless processor sets its own
+	if e.Alert != nil {
+		if e.Alert.Status == "" {
+			e.Alert.Status = e.Status
+		}
+		if e.Alert.Severity == "" {
+			e.Alert.Severity = e.Severity
+		}
+	}
+
+	if e.ID != "" {
+		return nil
+	}
+
+	b, err := e.JsonBytes()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	e.ID = hex.EncodeToString(sum[:16])
+	return nil
+}
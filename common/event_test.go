@@ -0,0 +1,61 @@
+package common
+
+import (
+	"testing"
+)
+
+type testOutput struct {
+	events []*Event
+}
+
+func (o *testOutput) Name() string {
+	return "Test"
+}
+
+func (o *testOutput) Send(event *Event) {
+	o.events = append(o.events, event)
+}
+
+func TestEventForward(t *testing.T) {
+
+	outputs := NewOutputs(nil)
+	recorder := &testOutput{}
+	outputs.Add(recorder)
+
+	e := &Event{
+		Channel: "datadog",
+		Type:    "DataDogEvent",
+		Data:    map[string]interface{}{"title": "cpu"},
+		Alert:   &AlertData{Title: "cpu"},
+		Via:     map[string]interface{}{"CloudEvents": "events"},
+	}
+	e.SetAlert("42", StatusFiring, "high")
+	e.SetEnrich("http", "value")
+	outputs.Send(e)
+
+	f := e.Forward("Slack", map[string]interface{}{"ts": "1"})
+	outputs.SendForward(f, nil, ".*")
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(recorder.events))
+	}
+
+	if f.ID == "" || f.ID != e.ID {
+		t.Errorf("forwarded event has ID %s, original %s", f.ID, e.ID)
+	}
+	if f.Key != "42" || f.Status != StatusFiring || f.Severity != SeverityCritical {
+		t.Errorf("forwarded event has key %s, status %s, severity %s", f.Key, f.Status, f.Severity)
+	}
+	if f.Alert == nil || f.Alert == e.Alert || f.Alert.Title != "cpu" {
+		t.Errorf("alert of forwarded event isn't copied: %v", f.Alert)
+	}
+	if f.Enrich["http"] != "value" {
+		t.Errorf("enrichment of forwarded event is lost: %v", f.Enrich)
+	}
+	if _, ok := f.Via["CloudEvents"]; !ok || f.Via["Slack"] == nil {
+		t.Errorf("via of forwarded event is wrong: %v", f.Via)
+	}
+	if _, ok := e.Via["Slack"]; ok {
+		t.Errorf("via of original event is changed: %v", e.Via)
+	}
+}
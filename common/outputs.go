@@ -10,8 +10,9 @@ import (
 )
 
 type Outputs struct {
-	list   []Output
-	logger sreCommon.Logger
+	list      []Output
+	enrichers *Enrichers
+	logger    sreCommon.Logger
 }
 
 func (ots *Outputs) Add(o Output) {
@@ -22,6 +23,10 @@ func (ots *Outputs) Add(o Output) {
 	ots.list = append(ots.list, o)
 }
 
+func (ots *Outputs) SetEnrichers(enrichers *Enrichers) {
+	ots.enrichers = enrichers
+}
+
 func (ots *Outputs) send(e *Event, exclude []Output, pattern string) {
 
 	if e == nil {
@@ -38,6 +43,18 @@ func (ots *Outputs) send(e *Event, exclude []Output, pattern string) {
 		return
 	}
 
+	// ID doesn't depend on enrichment, so the same event gets the same ID
+	if err := e.normalize(); err != nil {
+		if ots.logger != nil {
+			ots.logger.Error(err)
+		}
+		return
+	}
+
+	if ots.enrichers != nil {
+		ots.enrichers.Enrich(e)
+	}
+
 	json, err := json.Marshal(e)
 	if err != nil {
 		if ots.logger != nil {
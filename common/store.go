@@ -0,0 +1,251 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type StoreOptions struct {
+	Path string
+	TTL  int
+}
+
+const storeSaveDelay = time.Second
+
+type StoreItem struct {
+	Value   json.RawMessage `json:"value"`
+	Expires time.Time       `json:"expires"`
+}
+
+// expired is false for item without expiration, which is set if TTL is 0
+func (i *StoreItem) expired(now time.Time) bool {
+	return !i.Expires.IsZero() && now.After(i.Expires)
+}
+
+// stores are stores with file, which are flushed on shutdown
+var stores struct {
+	mutex sync.Mutex
+	list  []*Store
+}
+
+type storeLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+// Store is a simple key value store which keeps items in memory and
+// persists them into json file if path is defined, so they survive restarts.
+// File is written in background, changes of a second are written at once
+// and all of them are written on shutdown by FlushStores. Items don't expire if TTL is 0
+type Store struct {
+	options   StoreOptions
+	logger    sreCommon.Logger
+	mutex     sync.Mutex
+	items     map[string]*StoreItem
+	locks     map[string]*storeLock
+	scheduled bool
+	fileMutex sync.Mutex
+}
+
+func (s *Store) load() {
+
+	if utils.IsEmpty(s.options.Path) {
+		return
+	}
+
+	b, err := ioutil.ReadFile(s.options.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Error(err)
+		}
+		return
+	}
+
+	items := make(map[string]*StoreItem)
+	if err := json.Unmarshal(b, &items); err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	now := time.Now()
+	for k, v := range items {
+		if v == nil || v.expired(now) {
+			continue
+		}
+		s.items[k] = v
+	}
+}
+
+// save schedules write of file, it's called under lock
+func (s *Store) save() {
+
+	if utils.IsEmpty(s.options.Path) || s.scheduled {
+		return
+	}
+	s.scheduled = true
+	time.AfterFunc(storeSaveDelay, s.Flush)
+}
+
+// Flush writes items into file
+func (s *Store) Flush() {
+
+	if utils.IsEmpty(s.options.Path) {
+		return
+	}
+
+	s.mutex.Lock()
+	s.scheduled = false
+	b, err := json.Marshal(s.items)
+	s.mutex.Unlock()
+
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+
+	tmp := s.options.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	if err := os.Rename(tmp, s.options.Path); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// Lock locks key until returned function is called, so check and set of the same key by concurrent events
+// are done one by one
+func (s *Store) Lock(key string) func() {
+
+	s.mutex.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &storeLock{}
+		s.locks[key] = l
+	}
+	l.refs++
+	s.mutex.Unlock()
+
+	l.mutex.Lock()
+	return func() {
+		l.mutex.Unlock()
+
+		s.mutex.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, key)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (s *Store) expire() {
+
+	now := time.Now()
+	for k, v := range s.items {
+		if v.expired(now) {
+			delete(s.items, k)
+		}
+	}
+}
+
+func (s *Store) Get(key string, value interface{}) bool {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return false
+	}
+
+	if item.expired(time.Now()) {
+		delete(s.items, key)
+		return false
+	}
+
+	if err := json.Unmarshal(item.Value, value); err != nil {
+		s.logger.Error(err)
+		return false
+	}
+	return true
+}
+
+func (s *Store) Set(key string, value interface{}) {
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		s.logger.Error(err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item := &StoreItem{Value: b}
+	if s.options.TTL > 0 {
+		item.Expires = time.Now().Add(time.Duration(s.options.TTL) * time.Second)
+	}
+
+	s.expire()
+	s.items[key] = item
+	s.save()
+}
+
+func (s *Store) Delete(key string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.items[key]; !ok {
+		return
+	}
+	delete(s.items, key)
+	s.save()
+}
+
+func NewStore(options StoreOptions, logger sreCommon.Logger) *Store {
+
+	if !utils.IsEmpty(options.Path) {
+		if err := os.MkdirAll(filepath.Dir(options.Path), 0755); err != nil {
+			logger.Error(err)
+		}
+	}
+
+	s := &Store{
+		options: options,
+		logger:  logger,
+		items:   make(map[string]*StoreItem),
+		locks:   make(map[string]*storeLock),
+	}
+	s.load()
+
+	if !utils.IsEmpty(options.Path) {
+		stores.mutex.Lock()
+		stores.list = append(stores.list, s)
+		stores.mutex.Unlock()
+	}
+	return s
+}
+
+// FlushStores writes files of all stores, so changes which are not written yet aren't lost on shutdown
+func FlushStores() {
+
+	stores.mutex.Lock()
+	defer stores.mutex.Unlock()
+
+	for _, s := range stores.list {
+		s.Flush()
+	}
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+func TestStoreTTL(t *testing.T) {
+
+	s := NewStore(StoreOptions{TTL: 60}, sre.NewLogs())
+	s.Set("alive", "a")
+	s.Set("expired", "e")
+	s.items["expired"].Expires = time.Now().Add(-time.Second)
+
+	var v string
+	if !s.Get("alive", &v) || v != "a" {
+		t.Errorf("item isn't found, %q", v)
+	}
+	if s.Get("expired", &v) {
+		t.Error("expired item is found")
+	}
+	if _, ok := s.items["expired"]; ok {
+		t.Error("expired item isn't removed")
+	}
+
+	// items don't expire without TTL
+	s = NewStore(StoreOptions{}, sre.NewLogs())
+	s.Set("key", "value")
+	if !s.Get("key", &v) || v != "value" {
+		t.Errorf("item without TTL isn't found, %q", v)
+	}
+	if !s.items["key"].Expires.IsZero() {
+		t.Errorf("item without TTL expires at %s", s.items["key"].Expires)
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+
+	s := NewStore(StoreOptions{TTL: 60}, sre.NewLogs())
+
+	// the same key is checked and set by one goroutine at a time, so it's set only once
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	sets := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock := s.Lock("thread")
+			defer unlock()
+
+			var v int
+			if s.Get("thread", &v) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			s.Set("thread", 1)
+
+			mutex.Lock()
+			sets++
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if sets != 1 {
+		t.Errorf("expected 1 set, got %d", sets)
+	}
+	if len(s.locks) != 0 {
+		t.Errorf("locks of keys are left: %d", len(s.locks))
+	}
+
+	// other key isn't blocked
+	unlock := s.Lock("first")
+	done := make(chan bool)
+	go func() {
+		s.Lock("second")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("lock of other key is blocked")
+	}
+	unlock()
+}
+
+func TestStoreFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "store", "threads.json")
+
+	s := NewStore(StoreOptions{Path: path, TTL: 60}, sre.NewLogs())
+	s.Set("alive", "a")
+	s.Set("expired", "e")
+	s.Set("deleted", "d")
+	s.Delete("deleted")
+	s.items["expired"].Expires = time.Now().Add(-time.Second)
+
+	// changes are written in background, shutdown writes them at once
+	FlushStores()
+
+	r := NewStore(StoreOptions{Path: path, TTL: 60}, sre.NewLogs())
+	var v string
+	if !r.Get("alive", &v) || v != "a" {
+		t.Errorf("item isn't reloaded, %q", v)
+	}
+	if _, ok := r.items["expired"]; ok {
+		t.Error("expired item is reloaded")
+	}
+	if _, ok := r.items["deleted"]; ok {
+		t.Error("deleted item is reloaded")
+	}
+}
@@ -0,0 +1,259 @@
+package enricher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/blues/jsonata-go"
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type HttpEnricherOptions struct {
+	Steps    string
+	Timeout  int
+	Insecure bool
+	TTL      int
+}
+
+type HttpEnricher struct {
+	client   *http.Client
+	steps    *render.TextTemplate
+	cache    *common.Store
+	options  HttpEnricherOptions
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+	hits     sreCommon.Counter
+}
+
+// HttpEnricherStep is rendered by steps template, response is extracted by JSONata query if it's set
+type HttpEnricherStep struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Query   string            `json:"query,omitempty"`
+}
+
+func (h *HttpEnricher) Name() string {
+	return "http"
+}
+
+func (h *HttpEnricher) getSteps(jsonObject interface{}) ([]*HttpEnricherStep, error) {
+
+	var steps []*HttpEnricherStep
+
+	b, err := h.steps.Execute(jsonObject)
+	if err != nil {
+		return steps, err
+	}
+
+	s := strings.TrimSpace(b.String())
+	if utils.IsEmpty(s) {
+		return steps, nil
+	}
+
+	// template can render single step or list of steps
+	if strings.HasPrefix(s, "[") {
+		err = json.Unmarshal([]byte(s), &steps)
+	} else {
+		var st HttpEnricherStep
+		err = json.Unmarshal([]byte(s), &st)
+		steps = append(steps, &st)
+	}
+	return steps, err
+}
+
+// cacheKey depends on everything which makes response different, so steps of different events share responses.
+// Name and query don't change response, so they are not the part of key
+func (h *HttpEnricher) cacheKey(step *HttpEnricherStep) string {
+
+	b, _ := json.Marshal(&HttpEnricherStep{
+		URL:     step.URL,
+		Method:  strings.ToUpper(step.Method),
+		Headers: step.Headers,
+		Body:    step.Body,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *HttpEnricher) request(step *HttpEnricherStep) (interface{}, error) {
+
+	method := strings.ToUpper(step.Method)
+	if utils.IsEmpty(method) {
+		method = "GET"
+	}
+
+	req, err := http.NewRequest(method, step.URL, strings.NewReader(step.Body))
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsEmpty(step.Body) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range step.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s response: %s %s", step.Name, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		// not json responses are kept as strings
+		obj = string(b)
+	}
+	return obj, nil
+}
+
+// extract evaluates query of step on response, compiled query is shared by all steps and events
+func (h *HttpEnricher) extract(step *HttpEnricherStep, obj interface{}) (interface{}, error) {
+
+	if utils.IsEmpty(step.Query) {
+		return obj, nil
+	}
+
+	e, err := render.CompileJsonata(step.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := e.Eval(obj)
+	if err == jsonata.ErrUndefined {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (h *HttpEnricher) run(eventType string, step *HttpEnricherStep) (interface{}, bool) {
+
+	key := h.cacheKey(step)
+
+	var obj interface{}
+	if h.cache != nil && h.cache.Get(key, &obj) {
+		h.hits.Inc(step.Name)
+	} else {
+		h.requests.Inc(eventType, step.Name)
+		var err error
+		obj, err = h.request(step)
+		if err != nil {
+			h.errors.Inc(eventType, step.Name)
+			h.logger.Error("Http enricher step %s failed: %v", step.Name, err)
+			return nil, false
+		}
+		if h.cache != nil {
+			h.cache.Set(key, obj)
+		}
+	}
+
+	value, err := h.extract(step, obj)
+	if err != nil {
+		h.errors.Inc(eventType, step.Name)
+		h.logger.Error("Http enricher step %s query failed: %v", step.Name, err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (h *HttpEnricher) Enrich(event *common.Event) error {
+
+	if event == nil || event.Data == nil {
+		return nil
+	}
+
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		return err
+	}
+
+	steps, err := h.getSteps(jsonObject)
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	results := make(map[string]interface{})
+
+	for _, step := range steps {
+
+		if step == nil || utils.IsEmpty(step.Name) || utils.IsEmpty(step.URL) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(step *HttpEnricherStep) {
+			defer wg.Done()
+
+			// failed steps are skipped, so event is delivered without their data
+			value, ok := h.run(event.Type, step)
+			if !ok {
+				return
+			}
+
+			mutex.Lock()
+			results[step.Name] = value
+			mutex.Unlock()
+		}(step)
+	}
+	wg.Wait()
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	event.SetEnrich(h.Name(), results)
+	return nil
+}
+
+func NewHttpEnricher(options HttpEnricherOptions, templateOptions render.TextTemplateOptions, observability *common.Observability) *HttpEnricher {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.Steps) {
+		logger.Debug("Http enricher steps are not defined. Skipped")
+		return nil
+	}
+
+	var cache *common.Store
+	if options.TTL > 0 {
+		cache = common.NewStore(common.StoreOptions{TTL: options.TTL}, logger)
+	}
+
+	return &HttpEnricher{
+		client:   utils.NewHttpClient(options.Timeout, options.Insecure),
+		steps:    render.NewTextTemplate("http-steps", options.Steps, templateOptions, options, logger),
+		cache:    cache,
+		options:  options,
+		logger:   logger,
+		requests: observability.Metrics().Counter("requests", "Count of all http enricher requests", []string{"event_type", "step"}, "http", "enricher"),
+		errors:   observability.Metrics().Counter("errors", "Count of all http enricher errors", []string{"event_type", "step"}, "http", "enricher"),
+		hits:     observability.Metrics().Counter("hits", "Count of all http enricher cache hits", []string{"step"}, "http", "enricher"),
+	}
+}
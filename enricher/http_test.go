@@ -0,0 +1,170 @@
+package enricher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+func newTestObservability() *common.Observability {
+	return common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+}
+
+// newTestHttpServer counts requests, /slow answers after delay, /fail answers with error
+func newTestHttpServer(requests *int32, delay time.Duration) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		case "/fail":
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"owner":{"team":"sre","name":"%s"}}`, r.URL.Path)
+	}))
+}
+
+func newTestHttpEnricher(t *testing.T, options HttpEnricherOptions) *HttpEnricher {
+
+	h := NewHttpEnricher(options, render.TextTemplateOptions{}, newTestObservability())
+	if h == nil {
+		t.Fatal("http enricher is not created")
+	}
+	return h
+}
+
+func testEnrich(t *testing.T, h *HttpEnricher) map[string]interface{} {
+
+	event := &common.Event{Type: "test", Data: map[string]interface{}{"service": "api"}}
+	if err := h.Enrich(event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Enrich == nil || event.Enrich[h.Name()] == nil {
+		return nil
+	}
+	return event.Enrich[h.Name()].(map[string]interface{})
+}
+
+func TestHttpEnricherCache(t *testing.T) {
+
+	var requests int32
+	server := newTestHttpServer(&requests, 0)
+	defer server.Close()
+
+	// both steps request the same url, so response is shared
+	steps := fmt.Sprintf(`[
+		{"name":"team","url":"%[1]s/owner","query":"owner.team"},
+		{"name":"owner","url":"%[1]s/owner","query":"owner.name"}
+	]`, server.URL)
+
+	h := newTestHttpEnricher(t, HttpEnricherOptions{Steps: steps, Timeout: 5, TTL: 60})
+
+	for i := 0; i < 3; i++ {
+		results := testEnrich(t, h)
+		if results["team"] != "sre" || results["owner"] != "/owner" {
+			t.Fatalf("unexpected results %d: %v", i, results)
+		}
+	}
+
+	// steps run concurrently, so both of them can miss cache of the first event
+	if n := atomic.LoadInt32(&requests); n < 1 || n > 2 {
+		t.Fatalf("expected response to be cached, got %d requests", n)
+	}
+}
+
+func TestHttpEnricherNoCache(t *testing.T) {
+
+	var requests int32
+	server := newTestHttpServer(&requests, 0)
+	defer server.Close()
+
+	steps := fmt.Sprintf(`{"name":"team","url":"%s/owner","query":"owner.team"}`, server.URL)
+	h := newTestHttpEnricher(t, HttpEnricherOptions{Steps: steps, Timeout: 5})
+
+	testEnrich(t, h)
+	testEnrich(t, h)
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 requests without cache, got %d", n)
+	}
+}
+
+func TestHttpEnricherFailedStep(t *testing.T) {
+
+	var requests int32
+	server := newTestHttpServer(&requests, 0)
+	defer server.Close()
+
+	steps := fmt.Sprintf(`[
+		{"name":"team","url":"%[1]s/owner","query":"owner.team"},
+		{"name":"failed","url":"%[1]s/fail"},
+		{"name":"invalid","url":"%[1]s/owner","query":"owner.("}
+	]`, server.URL)
+
+	h := newTestHttpEnricher(t, HttpEnricherOptions{Steps: steps, Timeout: 5, TTL: 60})
+
+	for i := 0; i < 2; i++ {
+		results := testEnrich(t, h)
+		if len(results) != 1 || results["team"] != "sre" {
+			t.Fatalf("expected failed steps to be skipped, got %v", results)
+		}
+	}
+
+	// failed responses are not cached
+	if n := atomic.LoadInt32(&requests); n < 3 {
+		t.Fatalf("expected failed step to be requested again, got %d requests", n)
+	}
+
+	steps = fmt.Sprintf(`{"name":"failed","url":"%s/fail"}`, server.URL)
+	h = newTestHttpEnricher(t, HttpEnricherOptions{Steps: steps, Timeout: 5})
+
+	if results := testEnrich(t, h); results != nil {
+		t.Fatalf("expected no enrich of failed steps, got %v", results)
+	}
+}
+
+func TestHttpEnricherTimeout(t *testing.T) {
+
+	var requests int32
+	server := newTestHttpServer(&requests, 3*time.Second)
+	defer server.Close()
+
+	steps := fmt.Sprintf(`[
+		{"name":"team","url":"%[1]s/owner","query":"owner.team"},
+		{"name":"slow","url":"%[1]s/slow"}
+	]`, server.URL)
+
+	h := newTestHttpEnricher(t, HttpEnricherOptions{Steps: steps, Timeout: 1})
+
+	start := time.Now()
+	results := testEnrich(t, h)
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("expected slow step to time out, enrich took %s", d)
+	}
+	if len(results) != 1 || results["team"] != "sre" {
+		t.Fatalf("expected slow step to be skipped, got %v", results)
+	}
+}
+
+func TestHttpEnricherNoSteps(t *testing.T) {
+
+	if h := NewHttpEnricher(HttpEnricherOptions{}, render.TextTemplateOptions{}, newTestObservability()); h != nil {
+		t.Fatal("expected no enricher without steps")
+	}
+}
@@ -0,0 +1,225 @@
+package enricher
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type PrometheusEnricherOptions struct {
+	Queries string
+	Step    string
+}
+
+type PrometheusEnricher struct {
+	api      *render.PrometheusApi
+	queries  *render.TextTemplate
+	options  PrometheusEnricherOptions
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+// PrometheusQuery is rendered by queries template, range query is executed if Range is set,
+// otherwise instant query is executed. Both end at Time (now by default) minus Offset
+type PrometheusQuery struct {
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	Range  string `json:"range,omitempty"`
+	Step   string `json:"step,omitempty"`
+	Offset string `json:"offset,omitempty"`
+	Time   string `json:"time,omitempty"`
+}
+
+type PrometheusQueryResult struct {
+	Query  string                     `json:"query"`
+	Series []*render.PrometheusSeries `json:"series"`
+	Value  *float64                   `json:"value,omitempty"`
+	Error  string                     `json:"error,omitempty"`
+}
+
+func (p *PrometheusEnricher) Name() string {
+	return "prometheus"
+}
+
+func (p *PrometheusEnricher) getQueries(jsonObject interface{}) ([]*PrometheusQuery, error) {
+
+	var queries []*PrometheusQuery
+
+	b, err := p.queries.Execute(jsonObject)
+	if err != nil {
+		return queries, err
+	}
+
+	s := strings.TrimSpace(b.String())
+	if utils.IsEmpty(s) {
+		return queries, nil
+	}
+
+	// template can render single query or list of queries
+	if strings.HasPrefix(s, "[") {
+		err = json.Unmarshal([]byte(s), &queries)
+	} else {
+		var q PrometheusQuery
+		err = json.Unmarshal([]byte(s), &q)
+		queries = append(queries, &q)
+	}
+	return queries, err
+}
+
+func (p *PrometheusEnricher) getTime(q *PrometheusQuery) (time.Time, error) {
+
+	t := time.Now().UTC()
+	if !utils.IsEmpty(q.Time) {
+		tm, err := time.Parse(time.RFC3339Nano, q.Time)
+		if err != nil {
+			return t, err
+		}
+		t = tm
+	}
+
+	if !utils.IsEmpty(q.Offset) {
+		d, err := time.ParseDuration(q.Offset)
+		if err != nil {
+			return t, err
+		}
+		t = t.Add(-d)
+	}
+	return t, nil
+}
+
+func (p *PrometheusEnricher) query(q *PrometheusQuery) *PrometheusQueryResult {
+
+	r := &PrometheusQueryResult{Query: q.Query}
+
+	t, err := p.getTime(q)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	var series []*render.PrometheusSeries
+	if !utils.IsEmpty(q.Range) {
+
+		d, err := time.ParseDuration(q.Range)
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+
+		step := q.Step
+		if utils.IsEmpty(step) {
+			step = p.options.Step
+		}
+		s, err := time.ParseDuration(step)
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		series, err = p.api.QueryRange(q.Query, t.Add(-d), t, s)
+	} else {
+		series, err = p.api.Query(q.Query, t)
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	r.Series = series
+	// value of the first series is kept for simple templates like {{ .enrich.prometheus.current.value }}
+	if len(series) > 0 {
+		if series[0].Value != nil {
+			r.Value = series[0].Value.Value
+		} else if l := len(series[0].Values); l > 0 {
+			r.Value = series[0].Values[l-1].Value
+		}
+	}
+	return r
+}
+
+func (p *PrometheusEnricher) Enrich(event *common.Event) error {
+
+	if event == nil || event.Data == nil {
+		return nil
+	}
+
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		return err
+	}
+
+	queries, err := p.getQueries(jsonObject)
+	if err != nil {
+		return err
+	}
+
+	if len(queries) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	results := make(map[string]interface{})
+
+	for _, q := range queries {
+
+		if q == nil || utils.IsEmpty(q.Name) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(q *PrometheusQuery) {
+			defer wg.Done()
+
+			p.requests.Inc(event.Type)
+			r := p.query(q)
+			if !utils.IsEmpty(r.Error) {
+				p.errors.Inc(event.Type)
+				p.logger.Debug("Prometheus query %s failed: %s", q.Name, r.Error)
+			}
+
+			mutex.Lock()
+			results[q.Name] = r
+			mutex.Unlock()
+		}(q)
+	}
+	wg.Wait()
+
+	if len(results) == 0 {
+		return errors.New("prometheus queries have no names")
+	}
+
+	event.SetEnrich(p.Name(), results)
+	return nil
+}
+
+func NewPrometheusEnricher(options PrometheusEnricherOptions, templateOptions render.TextTemplateOptions, observability *common.Observability) *PrometheusEnricher {
+
+	logger := observability.Logs()
+	if templateOptions.Prometheus == nil {
+		logger.Debug("Prometheus API is not defined. Skipped")
+		return nil
+	}
+
+	if utils.IsEmpty(options.Queries) {
+		logger.Debug("Prometheus enricher queries are not defined. Skipped")
+		return nil
+	}
+
+	return &PrometheusEnricher{
+		api:      templateOptions.Prometheus,
+		queries:  render.NewTextTemplate("prometheus-queries", options.Queries, templateOptions, options, logger),
+		options:  options,
+		logger:   logger,
+		requests: observability.Metrics().Counter("requests", "Count of all prometheus enricher requests", []string{"event_type"}, "prometheus", "enricher"),
+		errors:   observability.Metrics().Counter("errors", "Count of all prometheus enricher errors", []string{"event_type"}, "prometheus", "enricher"),
+	}
+}
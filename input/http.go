@@ -23,12 +23,17 @@ type HttpInputOptions struct {
 	RancherURL      string
 	AlertmanagerURL string
 	GitlabURL       string
+	GithubURL       string
 	DataDogURL      string
 	Site24x7URL     string
 	CloudflareURL   string
 	GoogleURL       string
+	NewRelicURL     string
 	AWSURL          string
 	CustomJsonURL   string
+	SlackURL        string
+	TelegramURL     string
+	CloudEventsURL  string
 	Listen          string
 	Tls             bool
 	Cert            string
@@ -203,13 +208,18 @@ func (h *HttpInput) getProcessors(processors *common.Processors, outputs *common
 	h.setProcessor(m, h.options.K8sURL, processor.K8sProcessorType())
 	h.setProcessor(m, h.options.AlertmanagerURL, processor.AlertmanagerProcessorType())
 	h.setProcessor(m, h.options.GitlabURL, processor.GitlabProcessorType())
+	h.setProcessor(m, h.options.GithubURL, processor.GithubProcessorType())
 	h.setProcessor(m, h.options.RancherURL, processor.RancherProcessorType())
 	h.setProcessor(m, h.options.DataDogURL, processor.DataDogProcessorType())
 	h.setProcessor(m, h.options.Site24x7URL, processor.Site24x7ProcessorType())
 	h.setProcessor(m, h.options.CloudflareURL, processor.CloudflareProcessorType())
 	h.setProcessor(m, h.options.GoogleURL, processor.GoogleProcessorType())
+	h.setProcessor(m, h.options.NewRelicURL, processor.NewRelicProcessorType())
 	h.setProcessor(m, h.options.AWSURL, processor.AWSProcessorType())
 	h.setProcessor(m, h.options.CustomJsonURL, processor.CustomJsonProcessorType())
+	h.setProcessor(m, h.options.SlackURL, processor.SlackProcessorType())
+	h.setProcessor(m, h.options.TelegramURL, processor.TelegramProcessorType())
+	h.setProcessor(m, h.options.CloudEventsURL, processor.CloudEventsProcessorType())
 	return m
 }
 
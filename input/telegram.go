@@ -0,0 +1,174 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/processor"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type TelegramInputOptions struct {
+	URL      string
+	IDTokens string
+	Timeout  int
+}
+
+type TelegramInput struct {
+	options    TelegramInputOptions
+	client     *http.Client
+	processors *common.Processors
+	tracer     sreCommon.Tracer
+	logger     sreCommon.Logger
+	requests   sreCommon.Counter
+	errors     sreCommon.Counter
+}
+
+type TelegramUpdates struct {
+	OK          bool                        `json:"ok"`
+	Description string                      `json:"description,omitempty"`
+	Result      []*processor.TelegramUpdate `json:"result"`
+}
+
+func (t *TelegramInput) getBotID(IDToken string) string {
+
+	arr := strings.SplitN(IDToken, ":", 2)
+	if len(arr) == 2 {
+		return arr[0]
+	}
+	return ""
+}
+
+// redact hides bot token of error, http client errors contain request URL
+func (t *TelegramInput) redact(IDToken string, err error) error {
+
+	if err == nil {
+		return nil
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), IDToken, t.getBotID(IDToken)+":***"))
+}
+
+func (t *TelegramInput) getUpdates(IDToken string, offset int64) ([]*processor.TelegramUpdate, error) {
+
+	params := url.Values{}
+	params.Add("timeout", strconv.Itoa(t.options.Timeout))
+	params.Add("offset", strconv.FormatInt(offset, 10))
+	params.Add("allowed_updates", `["message"]`)
+
+	URL := fmt.Sprintf("%s/bot%s/getUpdates?%s", strings.TrimRight(t.options.URL, "/"), IDToken, params.Encode())
+
+	resp, err := t.client.Get(URL)
+	if err != nil {
+		return nil, t.redact(IDToken, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, t.redact(IDToken, err)
+	}
+
+	var r TelegramUpdates
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	if !r.OK {
+		return nil, fmt.Errorf("telegram getUpdates failed: %s", r.Description)
+	}
+	return r.Result, nil
+}
+
+func (t *TelegramInput) poll(IDToken string, p *processor.TelegramProcessor) {
+
+	botID := t.getBotID(IDToken)
+	var offset int64
+
+	for {
+		updates, err := t.getUpdates(IDToken, offset)
+		if err != nil {
+			t.errors.Inc(botID)
+			t.logger.Error(err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, u := range updates {
+
+			if u == nil {
+				continue
+			}
+			offset = u.UpdateID + 1
+
+			span := t.tracer.StartSpan()
+			t.requests.Inc(botID)
+
+			if b, err := json.Marshal(u); err == nil {
+				t.logger.SpanDebug(span, string(b))
+			}
+
+			if err := p.HandleUpdate(span, botID, u); err != nil {
+				t.errors.Inc(botID)
+			}
+			span.Finish()
+		}
+	}
+}
+
+func (t *TelegramInput) Start(wg *sync.WaitGroup, outputs *common.Outputs) {
+
+	p, ok := t.processors.Find(common.AsEventType(processor.TelegramProcessorType())).(*processor.TelegramProcessor)
+	if !ok || p == nil {
+		t.logger.Debug("Telegram processor is not found. Skipped")
+		return
+	}
+
+	t.logger.Info("Start telegram input...")
+
+	for _, IDToken := range strings.Split(t.options.IDTokens, ",") {
+
+		IDToken = strings.TrimSpace(IDToken)
+		if utils.IsEmpty(IDToken) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(IDToken string) {
+			defer wg.Done()
+			t.poll(IDToken, p)
+		}(IDToken)
+	}
+
+	t.logger.Info("Telegram input is up. Polling...")
+}
+
+func NewTelegramInput(options TelegramInputOptions, processors *common.Processors, observability *common.Observability) *TelegramInput {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.IDTokens) {
+		logger.Debug("Telegram input ID tokens are not defined. Skipped")
+		return nil
+	}
+
+	meter := observability.Metrics()
+
+	return &TelegramInput{
+		options:    options,
+		client:     utils.NewHttpClient(options.Timeout+10, false),
+		processors: processors,
+		tracer:     observability.Traces(),
+		logger:     logger,
+		requests:   meter.Counter("requests", "Count of all telegram input requests", []string{"bot_id"}, "telegram", "input"),
+		errors:     meter.Counter("errors", "Count of all telegram input errors", []string{"bot_id"}, "telegram", "input"),
+	}
+}
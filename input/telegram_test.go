@@ -0,0 +1,33 @@
+package input
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+func TestTelegramInputRedactsToken(t *testing.T) {
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	URL := server.URL
+	server.Close()
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	IDToken := "123:secret-token"
+	i := NewTelegramInput(TelegramInputOptions{URL: URL, IDTokens: IDToken, Timeout: 1}, nil, observability)
+
+	_, err := i.getUpdates(IDToken, 0)
+	if err == nil {
+		t.Fatal("getUpdates of closed server doesn't fail")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error contains bot token: %v", err)
+	}
+	if !strings.Contains(err.Error(), "bot123:***") {
+		t.Errorf("error doesn't contain bot ID: %v", err)
+	}
+}
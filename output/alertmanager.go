@@ -0,0 +1,230 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type AlertmanagerOutputOptions struct {
+	Silence  string
+	Duration string
+}
+
+type AlertmanagerOutput struct {
+	wg       *sync.WaitGroup
+	api      *render.AlertmanagerApi
+	silence  *render.TextTemplate
+	options  AlertmanagerOutputOptions
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+// AlertmanagerSilenceRequest is rendered by silence template, matchers or fingerprint of active alert should be set,
+// silence of ID is expired if expire is set
+type AlertmanagerSilenceRequest struct {
+	ID          string `json:"id,omitempty"`
+	Expire      bool   `json:"expire,omitempty"`
+	Matchers    string `json:"matchers,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Duration    string `json:"duration,omitempty"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+func (a *AlertmanagerOutput) Name() string {
+	return "Alertmanager"
+}
+
+func (a *AlertmanagerOutput) getRequests(span sreCommon.TracerSpan, jsonObject interface{}) ([]*AlertmanagerSilenceRequest, error) {
+
+	var requests []*AlertmanagerSilenceRequest
+
+	b, err := a.silence.Execute(jsonObject)
+	if err != nil {
+		return requests, err
+	}
+
+	s := strings.TrimSpace(b.String())
+	if utils.IsEmpty(s) {
+		return requests, nil
+	}
+
+	a.logger.SpanDebug(span, "Alertmanager raw silence => %s", s)
+
+	// template can render single silence or list of silences
+	if strings.HasPrefix(s, "[") {
+		err = json.Unmarshal([]byte(s), &requests)
+	} else {
+		var r AlertmanagerSilenceRequest
+		err = json.Unmarshal([]byte(s), &r)
+		requests = append(requests, &r)
+	}
+	return requests, err
+}
+
+// getMatchers returns matchers of request or equal matchers for all labels of alert found by fingerprint
+func (a *AlertmanagerOutput) getMatchers(r *AlertmanagerSilenceRequest) ([]*render.AlertmanagerMatcher, error) {
+
+	if !utils.IsEmpty(r.Matchers) {
+		return render.ParseAlertmanagerMatchers(r.Matchers)
+	}
+
+	if utils.IsEmpty(r.Fingerprint) {
+		return nil, errors.New("alertmanager silence has no matchers and fingerprint")
+	}
+
+	alert, err := a.api.GetAlert(r.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, fmt.Errorf("alertmanager alert %s is not found", r.Fingerprint)
+	}
+
+	var names []string
+	for k := range alert.Labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var matchers []*render.AlertmanagerMatcher
+	for _, k := range names {
+		matchers = append(matchers, &render.AlertmanagerMatcher{Name: k, Value: alert.Labels[k], IsEqual: true})
+	}
+	return matchers, nil
+}
+
+func (a *AlertmanagerOutput) createSilence(span sreCommon.TracerSpan, r *AlertmanagerSilenceRequest) error {
+
+	matchers, err := a.getMatchers(r)
+	if err != nil {
+		return err
+	}
+
+	duration := r.Duration
+	if utils.IsEmpty(duration) {
+		duration = a.options.Duration
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return err
+	}
+
+	createdBy := r.CreatedBy
+	if utils.IsEmpty(createdBy) {
+		createdBy = "events"
+	}
+
+	now := time.Now().UTC()
+	silence := &render.AlertmanagerSilence{
+		ID:        r.ID,
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(d),
+		CreatedBy: createdBy,
+		Comment:   r.Comment,
+	}
+
+	id, err := a.api.CreateSilence(silence)
+	if err != nil {
+		return err
+	}
+	a.logger.SpanDebug(span, "Alertmanager silence %s => %s", id, duration)
+	return nil
+}
+
+func (a *AlertmanagerOutput) Send(event *common.Event) {
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		if a.api == nil || a.silence == nil {
+			a.logger.Debug("No alertmanager api or silence")
+			return
+		}
+
+		if event == nil {
+			a.logger.Debug("Event is empty")
+			return
+		}
+
+		span := a.tracer.StartFollowSpan(event.GetSpanContext())
+		defer span.Finish()
+
+		if event.Data == nil {
+			a.logger.SpanError(span, "Event data is empty")
+			return
+		}
+
+		jsonObject, err := event.JsonObject()
+		if err != nil {
+			a.logger.SpanError(span, err)
+			return
+		}
+
+		requests, err := a.getRequests(span, jsonObject)
+		if err != nil {
+			a.logger.SpanError(span, err)
+			return
+		}
+
+		for _, r := range requests {
+
+			if r == nil {
+				continue
+			}
+
+			a.requests.Inc(event.Type)
+			if r.Expire {
+				err = a.api.ExpireSilence(r.ID)
+			} else {
+				err = a.createSilence(span, r)
+			}
+			if err != nil {
+				a.errors.Inc(event.Type)
+				a.logger.SpanError(span, err)
+			}
+		}
+	}()
+}
+
+func NewAlertmanagerOutput(wg *sync.WaitGroup,
+	options AlertmanagerOutputOptions,
+	templateOptions render.TextTemplateOptions,
+	observability *common.Observability) *AlertmanagerOutput {
+
+	logger := observability.Logs()
+	if templateOptions.Alertmanager == nil {
+		logger.Debug("Alertmanager API is not defined. Skipped")
+		return nil
+	}
+
+	if utils.IsEmpty(options.Silence) {
+		logger.Debug("Alertmanager silence is not defined. Skipped")
+		return nil
+	}
+
+	return &AlertmanagerOutput{
+		wg:       wg,
+		api:      templateOptions.Alertmanager,
+		silence:  render.NewTextTemplate("alertmanager-silence", options.Silence, templateOptions, options, logger),
+		options:  options,
+		tracer:   observability.Traces(),
+		logger:   logger,
+		requests: observability.Metrics().Counter("requests", "Count of all alertmanager requests", []string{"event_type"}, "alertmanager", "output"),
+		errors:   observability.Metrics().Counter("errors", "Count of all alertmanager errors", []string{"event_type"}, "alertmanager", "output"),
+	}
+}
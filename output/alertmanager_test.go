@@ -0,0 +1,125 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+type alertmanagerCall struct {
+	Method  string
+	Path    string
+	Silence *render.AlertmanagerSilence
+}
+
+func TestAlertmanagerOutputSilence(t *testing.T) {
+
+	var mutex sync.Mutex
+	var calls []*alertmanagerCall
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		c := &alertmanagerCall{Method: r.Method, Path: r.URL.Path}
+		if r.Method == "POST" {
+			c.Silence = &render.AlertmanagerSilence{}
+			if err := json.NewDecoder(r.Body).Decode(c.Silence); err != nil {
+				t.Error(err)
+			}
+		}
+		mutex.Lock()
+		calls = append(calls, c)
+		mutex.Unlock()
+
+		switch {
+		case r.Method == "GET":
+			fmt.Fprint(w, `[{"fingerprint":"f1","labels":{"service":"api","alertname":"DiskFull"}}]`)
+		case r.Method == "DELETE" && r.URL.Path == "/api/v2/silence/unknown":
+			http.Error(w, "silence not found", http.StatusNotFound)
+		case r.Method == "DELETE":
+		default:
+			fmt.Fprint(w, `{"silenceID":"s1"}`)
+		}
+	}))
+	defer server.Close()
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	templateOptions := render.TextTemplateOptions{
+		Alertmanager: render.NewAlertmanagerApi(render.AlertmanagerApiOptions{URL: server.URL, Timeout: 5}, observability),
+	}
+
+	wg := &sync.WaitGroup{}
+	a := NewAlertmanagerOutput(wg, AlertmanagerOutputOptions{
+		Silence:  "{{toJSON .data}}",
+		Duration: "1h",
+	}, templateOptions, observability)
+	if a == nil {
+		t.Fatal("alertmanager output is not created")
+	}
+
+	send := func(silence interface{}) []*alertmanagerCall {
+
+		mutex.Lock()
+		calls = nil
+		mutex.Unlock()
+
+		a.Send(&common.Event{Channel: "test", Type: "TestEvent", Data: silence})
+		wg.Wait()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		return calls
+	}
+
+	// silence of alert is matched by all its labels
+	c := send(map[string]interface{}{"fingerprint": "f1", "duration": "2h", "comment": "maintenance"})
+	if len(c) != 2 || c[0].Path != "/api/v2/alerts" || c[1].Path != "/api/v2/silences" {
+		t.Fatalf("unexpected calls %v", c)
+	}
+	s := c[1].Silence
+	if len(s.Matchers) != 2 || s.Matchers[0].String() != `alertname="DiskFull"` || s.Matchers[1].String() != `service="api"` {
+		t.Errorf("unexpected matchers %v", s.Matchers)
+	}
+	if d := s.EndsAt.Sub(s.StartsAt); d != 2*time.Hour || s.CreatedBy != "events" || s.Comment != "maintenance" {
+		t.Errorf("unexpected silence %+v", s)
+	}
+
+	// list of silences with default duration
+	c = send([]interface{}{
+		map[string]interface{}{"matchers": `service="web"`},
+		map[string]interface{}{"matchers": `service="db"`, "createdBy": "bot"},
+	})
+	if len(c) != 2 || c[0].Silence.Matchers[0].Value != "web" || c[1].Silence.CreatedBy != "bot" {
+		t.Fatalf("unexpected calls %v", c)
+	}
+	if d := c[0].Silence.EndsAt.Sub(c[0].Silence.StartsAt); d != time.Hour {
+		t.Errorf("default duration isn't used, %s", d)
+	}
+
+	c = send(map[string]interface{}{"id": "s1", "expire": true})
+	if len(c) != 1 || c[0].Method != "DELETE" || c[0].Path != "/api/v2/silence/s1" {
+		t.Fatalf("silence isn't expired, %v", c)
+	}
+
+	// errors of alertmanager and unknown alerts don't stop other silences
+	c = send([]interface{}{
+		map[string]interface{}{"id": "unknown", "expire": true},
+		map[string]interface{}{"fingerprint": "f2"},
+		map[string]interface{}{"matchers": `service="web"`},
+	})
+	var paths []string
+	for _, call := range c {
+		paths = append(paths, call.Method+" "+call.Path)
+	}
+	if strings.Join(paths, ",") != "DELETE /api/v2/silence/unknown,GET /api/v2/alerts,POST /api/v2/silences" {
+		t.Errorf("unexpected calls %v", paths)
+	}
+}
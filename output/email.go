@@ -0,0 +1,506 @@
+package output
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+	"github.com/prometheus/alertmanager/template"
+)
+
+type EmailOutputOptions struct {
+	Address           string
+	Mode              string
+	Insecure          bool
+	Timeout           int
+	Username          string
+	Password          string
+	From              string
+	To                string
+	RecipientSelector string
+	Subject           string
+	Message           string
+	Text              string
+	AlertExpression   string
+	Window            int
+}
+
+type EmailOutput struct {
+	wg       *sync.WaitGroup
+	message  *render.TextTemplate
+	text     *render.TextTemplate
+	subject  *render.TextTemplate
+	selector *render.TextTemplate
+	grafana  *render.GrafanaRender
+	options  EmailOutputOptions
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+	mutex    sync.Mutex
+	digests  map[string][]*EmailItem
+	dialer   func(network, address string) (net.Conn, error)
+}
+
+type EmailImage struct {
+	ID       string
+	FileName string
+	Content  []byte
+}
+
+type EmailItem struct {
+	Subject string
+	HTML    string
+	Text    string
+	Images  []*EmailImage
+}
+
+func (e *EmailOutput) Name() string {
+	return "Email"
+}
+
+func (e *EmailOutput) host() string {
+
+	host, _, err := net.SplitHostPort(e.options.Address)
+	if err != nil {
+		return e.options.Address
+	}
+	return host
+}
+
+func (e *EmailOutput) dial() (*smtp.Client, error) {
+
+	host := e.host()
+	conn, err := e.dialer("tcp", e.options.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// whole session including data is limited by timeout, not only dial
+	if e.options.Timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(time.Duration(e.options.Timeout) * time.Second)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: e.options.Insecure,
+	}
+
+	if strings.ToLower(e.options.Mode) == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if strings.ToLower(e.options.Mode) == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	if !utils.IsEmpty(e.options.Username) {
+		auth := smtp.PlainAuth("", e.options.Username, e.options.Password, host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (e *EmailOutput) writePart(w *multipart.Writer, contentType string, content []byte) error {
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := qw.Write(content); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func (e *EmailOutput) writeImage(w *multipart.Writer, image *EmailImage) error {
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", "image/png")
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-ID", fmt.Sprintf("<%s>", image.ID))
+	h.Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, escapeQuotes(image.FileName)))
+
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	s := base64.StdEncoding.EncodeToString(image.Content)
+	for len(s) > 76 {
+		if _, err := fmt.Fprintf(pw, "%s\r\n", s[:76]); err != nil {
+			return err
+		}
+		s = s[76:]
+	}
+	_, err = fmt.Fprintf(pw, "%s\r\n", s)
+	return err
+}
+
+func (e *EmailOutput) build(to string, item *EmailItem) ([]byte, error) {
+
+	var body bytes.Buffer
+	related := multipart.NewWriter(&body)
+
+	var alternative bytes.Buffer
+	aw := multipart.NewWriter(&alternative)
+
+	if !utils.IsEmpty(item.Text) {
+		if err := e.writePart(aw, "text/plain; charset=UTF-8", []byte(item.Text)); err != nil {
+			return nil, err
+		}
+	}
+
+	html := item.HTML
+	for _, image := range item.Images {
+		html = fmt.Sprintf("%s\n<br/><img src=\"cid:%s\" alt=\"%s\"/>", html, image.ID, image.FileName)
+	}
+
+	if !utils.IsEmpty(html) {
+		if err := e.writePart(aw, "text/html; charset=UTF-8", []byte(html)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%s", aw.Boundary()))
+	pw, err := related.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pw.Write(alternative.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, image := range item.Images {
+		if err := e.writeImage(related, image); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := related.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.options.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", item.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/related; boundary=%s\r\n\r\n", related.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (e *EmailOutput) sendMail(spanCtx sreCommon.TracerSpanContext, to string, item *EmailItem) error {
+
+	span := e.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	msg, err := e.build(to, item)
+	if err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(e.options.From); err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+
+	for _, rcpt := range strings.Split(to, ",") {
+		rcpt = strings.TrimSpace(rcpt)
+		if utils.IsEmpty(rcpt) {
+			continue
+		}
+		if err := client.Rcpt(rcpt); err != nil {
+			e.logger.SpanError(span, err)
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		e.logger.SpanError(span, err)
+		return err
+	}
+
+	e.logger.SpanDebug(span, "Email sent to %s => %s", to, item.Subject)
+	return client.Quit()
+}
+
+func (e *EmailOutput) digest(items []*EmailItem) *EmailItem {
+
+	if len(items) == 1 {
+		return items[0]
+	}
+
+	var htmls, texts []string
+	var images []*EmailImage
+
+	for _, item := range items {
+		if !utils.IsEmpty(item.HTML) {
+			htmls = append(htmls, item.HTML)
+		}
+		if !utils.IsEmpty(item.Text) {
+			texts = append(texts, item.Text)
+		}
+		images = append(images, item.Images...)
+	}
+
+	return &EmailItem{
+		Subject: fmt.Sprintf("%s (+%d more)", items[0].Subject, len(items)-1),
+		HTML:    strings.Join(htmls, "\n<hr/>\n"),
+		Text:    strings.Join(texts, "\n\n---\n\n"),
+		Images:  images,
+	}
+}
+
+func (e *EmailOutput) flush(to string) {
+
+	e.mutex.Lock()
+	items := e.digests[to]
+	delete(e.digests, to)
+	e.mutex.Unlock()
+
+	if len(items) == 0 {
+		return
+	}
+
+	span := e.tracer.StartSpan()
+	defer span.Finish()
+
+	e.requests.Inc(to)
+	if err := e.sendMail(span.GetContext(), to, e.digest(items)); err != nil {
+		e.errors.Inc(to)
+	}
+}
+
+func (e *EmailOutput) queue(spanCtx sreCommon.TracerSpanContext, to string, item *EmailItem) {
+
+	if e.options.Window <= 0 {
+		e.requests.Inc(to)
+		if err := e.sendMail(spanCtx, to, item); err != nil {
+			e.errors.Inc(to)
+		}
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	items, ok := e.digests[to]
+	e.digests[to] = append(items, item)
+	if ok {
+		return
+	}
+
+	e.wg.Add(1)
+	time.AfterFunc(time.Duration(e.options.Window)*time.Second, func() {
+		defer e.wg.Done()
+		e.flush(to)
+	})
+}
+
+func (e *EmailOutput) getAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, alert template.Alert) (*EmailImage, error) {
+
+	span := e.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	request, _, err := alertImageRequest(alert, e.options.AlertExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	image, fileName, err := e.grafana.Render(span.GetContext(), request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailImage{
+		ID:       fmt.Sprintf("%x@%s", md5.Sum(image), e.Name()),
+		FileName: fmt.Sprintf("%x.png", md5.Sum([]byte(fileName))),
+		Content:  image,
+	}, nil
+}
+
+func (e *EmailOutput) Send(event *common.Event) {
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		if e.message == nil {
+			e.logger.Debug("No message")
+			return
+		}
+
+		if event == nil {
+			e.logger.Debug("Event is empty")
+			return
+		}
+
+		span := e.tracer.StartFollowSpan(event.GetSpanContext())
+		defer span.Finish()
+
+		if event.Data == nil {
+			e.logger.SpanError(span, "Event data is empty")
+			return
+		}
+
+		jsonObject, err := event.JsonObject()
+		if err != nil {
+			e.logger.SpanError(span, err)
+			return
+		}
+
+		recipients := e.options.To
+		if e.selector != nil {
+			b, err := e.selector.Execute(jsonObject)
+			if err != nil {
+				e.logger.SpanDebug(span, err)
+			} else {
+				recipients = strings.TrimSpace(b.String())
+			}
+		}
+
+		if utils.IsEmpty(recipients) {
+			e.logger.SpanDebug(span, "Email recipients are not found. Skipped")
+			return
+		}
+
+		html := execute(e.message, jsonObject, "")
+		text := execute(e.text, jsonObject, "")
+
+		if utils.IsEmpty(html) && utils.IsEmpty(text) {
+			e.logger.SpanDebug(span, "Email message is empty")
+			return
+		}
+
+		subject := execute(e.subject, jsonObject, "")
+		if utils.IsEmpty(subject) {
+			subject = fmt.Sprintf("%s from %s", event.Type, event.Channel)
+		}
+
+		e.logger.SpanDebug(span, "Email message => %s", html)
+
+		item := &EmailItem{
+			Subject: subject,
+			HTML:    html,
+			Text:    text,
+		}
+
+		if alert, ok := event.Data.(template.Alert); ok && event.Type == "AlertmanagerEvent" && e.grafana != nil {
+			image, err := e.getAlertmanagerImage(span.GetContext(), alert)
+			if err != nil {
+				e.logger.SpanError(span, err)
+			} else {
+				item.Images = append(item.Images, image)
+			}
+		}
+
+		for _, to := range strings.Split(recipients, "\n") {
+
+			to = strings.TrimSpace(to)
+			if utils.IsEmpty(to) {
+				continue
+			}
+			e.queue(span.GetContext(), to, item)
+		}
+	}()
+}
+
+func NewEmailOutput(wg *sync.WaitGroup,
+	options EmailOutputOptions,
+	templateOptions render.TextTemplateOptions,
+	grafanaRenderOptions render.GrafanaRenderOptions,
+	observability *common.Observability) *EmailOutput {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.Address) {
+		logger.Debug("Email address is not defined. Skipped")
+		return nil
+	}
+
+	if utils.IsEmpty(options.Message) {
+		logger.Debug("Email message is not defined. Skipped")
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(options.Timeout) * time.Second}
+
+	return &EmailOutput{
+		wg:       wg,
+		message:  render.NewTextTemplate("email-message", options.Message, templateOptions, options, logger),
+		text:     render.NewTextTemplate("email-text", options.Text, templateOptions, options, logger),
+		subject:  render.NewTextTemplate("email-subject", options.Subject, templateOptions, options, logger),
+		selector: render.NewTextTemplate("email-selector", options.RecipientSelector, templateOptions, options, logger),
+		grafana:  render.NewGrafanaRender(grafanaRenderOptions, observability),
+		options:  options,
+		logger:   logger,
+		tracer:   observability.Traces(),
+		digests:  make(map[string][]*EmailItem),
+		dialer:   dialer.Dial,
+		requests: observability.Metrics().Counter("requests", "Count of all email requests", []string{"to"}, "email", "output"),
+		errors:   observability.Metrics().Counter("errors", "Count of all email errors", []string{"to"}, "email", "output"),
+	}
+}
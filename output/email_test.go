@@ -0,0 +1,261 @@
+package output
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+type smtpMessage struct {
+	TLS  bool
+	From string
+	To   []string
+	Data string
+}
+
+// smtpStandIn is in-process SMTP server which supports implicit TLS and STARTTLS
+type smtpStandIn struct {
+	listener net.Listener
+	config   *tls.Config
+	implicit bool
+	mutex    sync.Mutex
+	messages []*smtpMessage
+}
+
+func newSmtpStandIn(t *testing.T, implicit bool) *smtpStandIn {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpStandIn{
+		listener: l,
+		config:   &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		implicit: implicit,
+	}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+
+	defer func() { conn.Close() }()
+
+	secure := false
+	if s.implicit {
+		conn = tls.Server(conn, s.config)
+		secure = true
+	}
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+
+	m := &smtpMessage{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO", "HELO":
+			if secure {
+				reply("250 localhost")
+			} else {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			conn = tls.Server(conn, s.config)
+			r = bufio.NewReader(conn)
+			secure = true
+		case "MAIL":
+			m = &smtpMessage{TLS: secure, From: line[len("MAIL FROM:"):]}
+			reply("250 OK")
+		case "RCPT":
+			m.To = append(m.To, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.Data = data.String()
+			s.mutex.Lock()
+			s.messages = append(s.messages, m)
+			s.mutex.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpStandIn) sent() []*smtpMessage {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.messages
+}
+
+func newTestEmailOutput(t *testing.T, wg *sync.WaitGroup, options EmailOutputOptions) *EmailOutput {
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	e := NewEmailOutput(wg, options, render.TextTemplateOptions{}, render.GrafanaRenderOptions{}, observability)
+	if e == nil {
+		t.Fatal("email output is not created")
+	}
+	return e
+}
+
+func testEmailEvent(name string) *common.Event {
+
+	e := &common.Event{
+		Channel: "test",
+		Type:    "TestEvent",
+		Data:    map[string]interface{}{"name": name},
+	}
+	e.SetTime(time.Now().UTC())
+	return e
+}
+
+func TestEmailOutputTLSModes(t *testing.T) {
+
+	for _, mode := range []string{"tls", "starttls"} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newSmtpStandIn(t, mode == "tls")
+
+			wg := &sync.WaitGroup{}
+			e := newTestEmailOutput(t, wg, EmailOutputOptions{
+				Address:  server.listener.Addr().String(),
+				Mode:     mode,
+				Insecure: true,
+				Timeout:  5,
+				From:     "events@example.com",
+				To:       "sre@example.com",
+				Subject:  "Event {{.data.name}}",
+				Message:  "<b>Alert {{.data.name}}</b>",
+				Text:     "Alert {{.data.name}}",
+			})
+
+			e.Send(testEmailEvent("disk"))
+			wg.Wait()
+
+			messages := server.sent()
+			if len(messages) != 1 {
+				t.Fatalf("expected 1 message, got %d", len(messages))
+			}
+
+			m := messages[0]
+			if !m.TLS {
+				t.Error("message is sent without TLS")
+			}
+			if m.From != "<events@example.com>" || len(m.To) != 1 || m.To[0] != "<sre@example.com>" {
+				t.Errorf("unexpected envelope %s => %v", m.From, m.To)
+			}
+			for _, s := range []string{"Subject: Event disk", "<b>Alert disk</b>", "text/plain", "Alert disk"} {
+				if !strings.Contains(m.Data, s) {
+					t.Errorf("message doesn't contain %q:\n%s", s, m.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailOutputDigest(t *testing.T) {
+
+	server := newSmtpStandIn(t, false)
+
+	wg := &sync.WaitGroup{}
+	e := newTestEmailOutput(t, wg, EmailOutputOptions{
+		Address:  server.listener.Addr().String(),
+		Mode:     "starttls",
+		Insecure: true,
+		Timeout:  5,
+		From:     "events@example.com",
+		To:       "sre@example.com",
+		Subject:  "Event {{.data.name}}",
+		Message:  "Alert {{.data.name}}",
+		Window:   1,
+	})
+
+	for _, name := range []string{"disk", "cpu", "memory"} {
+		e.Send(testEmailEvent(name))
+	}
+	wg.Wait()
+
+	messages := server.sent()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 digest message, got %d", len(messages))
+	}
+
+	m := messages[0]
+	if !strings.Contains(m.Data, "(+2 more)") {
+		t.Errorf("digest subject is not found:\n%s", m.Data)
+	}
+	for _, name := range []string{"disk", "cpu", "memory"} {
+		if !strings.Contains(m.Data, "Alert "+name) {
+			t.Errorf("digest doesn't contain %s:\n%s", name, m.Data)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package output
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/devopsext/events/common"
 	"github.com/devopsext/events/render"
@@ -18,18 +20,63 @@ type GitlabOutputOptions struct {
 	Token     string
 	Variables string
 	Projects  string
+	Actions   string
+	// pipeline tracking is disabled if timeout is zero
+	PipelineTimeout  int
+	PipelineInterval int
+}
+
+// GitlabPipelineResult is data of GitlabPipelineResultEvent, which is sent when triggered pipeline is done
+type GitlabPipelineResult struct {
+	ProjectID  string        `json:"project_id"`
+	Project    string        `json:"project"`
+	PipelineID int           `json:"pipeline_id"`
+	Ref        string        `json:"ref"`
+	SHA        string        `json:"sha"`
+	Status     string        `json:"status"`
+	Duration   int           `json:"duration"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	WebURL     string        `json:"web_url"`
+	Timeout    bool          `json:"timeout"`
+	Event      *common.Event `json:"event"`
+}
+
+// GitlabAction is rendered by actions template, type is one of issue, note, deployment, mr-note
+type GitlabAction struct {
+	Type        string `json:"type"`
+	Project     string `json:"project"`
+	Token       string `json:"token,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Body        string `json:"body,omitempty"`
+	Labels      string `json:"labels,omitempty"`
+	Assignee    string `json:"assignee,omitempty"`
+	Search      string `json:"search,omitempty"`
+	Issue       int    `json:"issue,omitempty"`
+	Close       bool   `json:"close,omitempty"`
+	MR          int    `json:"mr,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+	SHA         string `json:"sha,omitempty"`
+	Tag         bool   `json:"tag,omitempty"`
+	Status      string `json:"status,omitempty"`
 }
 
 type GitlabOutput struct {
-	wg        *sync.WaitGroup
-	client    *gitlab.Client
-	projects  *render.TextTemplate
-	variables *render.TextTemplate
-	options   GitlabOutputOptions
-	tracer    sreCommon.Tracer
-	logger    sreCommon.Logger
-	requests  sreCommon.Counter
-	errors    sreCommon.Counter
+	wg             *sync.WaitGroup
+	client         *gitlab.Client
+	projects       *render.TextTemplate
+	variables      *render.TextTemplate
+	actions        *render.TextTemplate
+	options        GitlabOutputOptions
+	outputs        *common.Outputs
+	tracer         sreCommon.Tracer
+	logger         sreCommon.Logger
+	requests       sreCommon.Counter
+	errors         sreCommon.Counter
+	actionRequests sreCommon.Counter
+	actionErrors   sreCommon.Counter
 }
 
 func (g *GitlabOutput) Name() string {
@@ -84,14 +131,410 @@ func (g *GitlabOutput) getProject(s string) string {
 	return ""
 }
 
+func GitlabPipelineResultEventType() string {
+	return "GitlabPipelineResultEvent"
+}
+
+func (g *GitlabOutput) pipelineDone(status string) bool {
+	return utils.Contains([]string{"success", "failed", "canceled", "skipped"}, status)
+}
+
+// trackPipeline polls pipeline until it's done or timeout is reached, then sends result event with key, channel and
+// via of original event, so it's routed to the same thread. It runs after trigger span is finished, so span follows it
+func (g *GitlabOutput) trackPipeline(spanCtx sreCommon.TracerSpanContext, event *common.Event, id string, pipeline *gitlab.Pipeline) {
+
+	span := g.tracer.StartFollowSpan(spanCtx)
+	defer span.Finish()
+
+	interval := time.Duration(g.options.PipelineInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(g.options.PipelineTimeout) * time.Second)
+
+	for !g.pipelineDone(pipeline.Status) && time.Now().Before(deadline) {
+
+		time.Sleep(interval)
+
+		p, _, err := g.client.Pipelines.GetPipeline(id, pipeline.ID)
+		if err != nil {
+			g.errors.Inc(id, pipeline.Ref)
+			g.logger.SpanError(span, err)
+			continue
+		}
+		pipeline = p
+		g.logger.SpanDebug(span, "Gitlab pipeline %d status => %s", pipeline.ID, pipeline.Status)
+	}
+
+	key := event.Key
+	if utils.IsEmpty(key) {
+		if jsonMap, err := event.JsonMap(); err == nil {
+			key = correlationKey(event, jsonMap)
+		}
+	}
+
+	origin := event.Copy()
+	origin.Key = key
+
+	via := make(map[string]interface{})
+	for k, v := range event.Via {
+		via[k] = v
+	}
+	via[g.Name()] = map[string]interface{}{
+		"project_id":  id,
+		"pipeline_id": pipeline.ID,
+		"web_url":     pipeline.WebURL,
+	}
+
+	e := &common.Event{
+		Channel: event.Channel,
+		Type:    GitlabPipelineResultEventType(),
+		Key:     key,
+		Data: &GitlabPipelineResult{
+			ProjectID:  id,
+			Project:    strings.TrimPrefix(g.getProject(pipeline.WebURL), "/"),
+			PipelineID: pipeline.ID,
+			Ref:        pipeline.Ref,
+			SHA:        pipeline.SHA,
+			Status:     pipeline.Status,
+			Duration:   pipeline.Duration,
+			StartedAt:  pipeline.StartedAt,
+			FinishedAt: pipeline.FinishedAt,
+			WebURL:     pipeline.WebURL,
+			Timeout:    !g.pipelineDone(pipeline.Status),
+			Event:      origin,
+		},
+		Via: via,
+	}
+	e.SetTime(time.Now().UTC())
+	e.SetLogger(g.logger)
+	e.SetSpanContext(span.GetContext())
+
+	g.outputs.SendForward(e, []common.Output{g}, ".*")
+}
+
+func (g *GitlabOutput) triggerPipelines(span sreCommon.TracerSpan, event *common.Event, jsonObject interface{}) {
+
+	projects := ""
+	if g.projects != nil {
+		b, err := g.projects.Execute(jsonObject)
+		if err != nil {
+			g.logger.SpanDebug(span, err)
+		} else {
+			projects = b.String()
+		}
+	}
+
+	if utils.IsEmpty(projects) {
+		g.logger.SpanDebug(span, "Gitlab projects are not found")
+		return
+	}
+
+	variables, err := g.getVariables(jsonObject, span)
+	if err != nil {
+		g.logger.SpanError(span, err)
+	}
+
+	arr := strings.Split(projects, "\n")
+	for _, project := range arr {
+
+		project = strings.TrimSpace(project)
+		if utils.IsEmpty(project) {
+			continue
+		}
+		pair := strings.SplitN(project, "=", 2)
+		token := g.options.Token
+
+		if len(pair) == 2 && !utils.IsEmpty(pair[0]) {
+			token = pair[0]
+			project = pair[1]
+		}
+
+		pair = strings.SplitN(project, "@", 2)
+		if len(pair) < 2 {
+			continue
+		}
+
+		id := pair[0]
+		ref := pair[1]
+		if utils.IsEmpty(ref) {
+			ref = "main"
+		}
+
+		g.requests.Inc(id, ref)
+
+		opt := &gitlab.RunPipelineTriggerOptions{Ref: &ref, Token: &token, Variables: variables}
+		pipeline, response, err := g.client.PipelineTriggers.RunPipelineTrigger(id, opt)
+		if err != nil {
+			g.errors.Inc(id, ref)
+			g.logger.SpanError(span, err)
+			continue
+		}
+
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			g.errors.Inc(id, ref)
+			g.logger.SpanError(span, "Gitlab reposne: %s", response.Status)
+			continue
+		}
+		g.logger.SpanDebug(span, "Gitlab pipeline => %s", pipeline.WebURL)
+
+		if g.options.PipelineTimeout > 0 && g.outputs != nil {
+			g.wg.Add(1)
+			go func(id string, pipeline *gitlab.Pipeline) {
+				defer g.wg.Done()
+				g.trackPipeline(span.GetContext(), event, id, pipeline)
+			}(id, pipeline)
+		}
+	}
+}
+
+func (g *GitlabOutput) getActions(span sreCommon.TracerSpan, jsonObject interface{}) ([]*GitlabAction, error) {
+
+	var actions []*GitlabAction
+	if g.actions == nil {
+		return actions, nil
+	}
+
+	b, err := g.actions.Execute(jsonObject)
+	if err != nil {
+		return actions, err
+	}
+
+	s := strings.TrimSpace(b.String())
+	if utils.IsEmpty(s) {
+		return actions, nil
+	}
+
+	g.logger.SpanDebug(span, "Gitlab raw actions => %s", s)
+
+	if err := json.Unmarshal([]byte(s), &actions); err != nil {
+		return actions, err
+	}
+	return actions, nil
+}
+
+func (g *GitlabOutput) getLabels(s string) *gitlab.Labels {
+
+	var labels gitlab.Labels
+	for _, l := range strings.Split(s, ",") {
+		l = strings.TrimSpace(l)
+		if !utils.IsEmpty(l) {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return &labels
+}
+
+func (g *GitlabOutput) getAssignees(a *GitlabAction, options []gitlab.RequestOptionFunc) (*[]int, error) {
+
+	if utils.IsEmpty(a.Assignee) {
+		return nil, nil
+	}
+
+	users, _, err := g.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String(a.Assignee)}, options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("gitlab user %s is not found", a.Assignee)
+	}
+	return &[]int{users[0].ID}, nil
+}
+
+// findIssue returns the first opened issue with search text in title or description
+func (g *GitlabOutput) findIssue(a *GitlabAction, options []gitlab.RequestOptionFunc) (*gitlab.Issue, error) {
+
+	if a.Issue > 0 {
+		issues, _, err := g.client.Issues.ListProjectIssues(a.Project, &gitlab.ListProjectIssuesOptions{IIDs: &[]int{a.Issue}}, options...)
+		if err != nil || len(issues) == 0 {
+			return nil, err
+		}
+		return issues[0], nil
+	}
+
+	if utils.IsEmpty(a.Search) {
+		return nil, nil
+	}
+
+	opt := &gitlab.ListProjectIssuesOptions{
+		State:  gitlab.String("opened"),
+		Search: gitlab.String(a.Search),
+	}
+	issues, _, err := g.client.Issues.ListProjectIssues(a.Project, opt, options...)
+	if err != nil || len(issues) == 0 {
+		return nil, err
+	}
+	return issues[0], nil
+}
+
+func (g *GitlabOutput) addNote(span sreCommon.TracerSpan, a *GitlabAction, issue *gitlab.Issue, body string, options []gitlab.RequestOptionFunc) error {
+
+	if utils.IsEmpty(body) {
+		return nil
+	}
+
+	note, _, err := g.client.Notes.CreateIssueNote(a.Project, issue.IID, &gitlab.CreateIssueNoteOptions{Body: gitlab.String(body)}, options...)
+	if err != nil {
+		return err
+	}
+	g.logger.SpanDebug(span, "Gitlab note %d => %s", note.ID, issue.WebURL)
+	return nil
+}
+
+// issue creates new issue, or adds note to existing one, and closes it if requested
+func (g *GitlabOutput) issue(span sreCommon.TracerSpan, a *GitlabAction, options []gitlab.RequestOptionFunc) error {
+
+	issue, err := g.findIssue(a, options)
+	if err != nil {
+		return err
+	}
+
+	body := a.Body
+	if utils.IsEmpty(body) {
+		body = a.Description
+	}
+
+	if issue != nil {
+
+		if err := g.addNote(span, a, issue, body, options); err != nil {
+			return err
+		}
+		if !a.Close {
+			return nil
+		}
+
+		_, _, err := g.client.Issues.UpdateIssue(a.Project, issue.IID, &gitlab.UpdateIssueOptions{StateEvent: gitlab.String("close")}, options...)
+		if err != nil {
+			return err
+		}
+		g.logger.SpanDebug(span, "Gitlab issue closed => %s", issue.WebURL)
+		return nil
+	}
+
+	// nothing to close
+	if a.Close {
+		g.logger.SpanDebug(span, "Gitlab issue to close is not found in %s", a.Project)
+		return nil
+	}
+
+	if a.Type == "note" {
+		g.logger.SpanDebug(span, "Gitlab issue to note is not found in %s", a.Project)
+		return nil
+	}
+
+	if utils.IsEmpty(a.Title) {
+		return fmt.Errorf("gitlab issue title is empty")
+	}
+
+	assignees, err := g.getAssignees(a, options)
+	if err != nil {
+		g.logger.SpanError(span, err)
+	}
+
+	opt := &gitlab.CreateIssueOptions{
+		Title:       gitlab.String(a.Title),
+		Description: gitlab.String(a.Description),
+		Labels:      g.getLabels(a.Labels),
+		AssigneeIDs: assignees,
+	}
+	issue, _, err = g.client.Issues.CreateIssue(a.Project, opt, options...)
+	if err != nil {
+		return err
+	}
+	g.logger.SpanDebug(span, "Gitlab issue => %s", issue.WebURL)
+	return nil
+}
+
+func (g *GitlabOutput) deployment(span sreCommon.TracerSpan, a *GitlabAction, options []gitlab.RequestOptionFunc) error {
+
+	status := a.Status
+	if utils.IsEmpty(status) {
+		status = string(gitlab.DeploymentStatusSuccess)
+	}
+	ref := a.Ref
+	if utils.IsEmpty(ref) {
+		ref = "main"
+	}
+
+	opt := &gitlab.CreateProjectDeploymentOptions{
+		Environment: gitlab.String(a.Environment),
+		Ref:         gitlab.String(ref),
+		SHA:         gitlab.String(a.SHA),
+		Tag:         gitlab.Bool(a.Tag),
+		Status:      gitlab.DeploymentStatus(gitlab.DeploymentStatusValue(status)),
+	}
+	deployment, _, err := g.client.Deployments.CreateProjectDeployment(a.Project, opt, options...)
+	if err != nil {
+		return err
+	}
+	g.logger.SpanDebug(span, "Gitlab deployment %d => %s", deployment.ID, a.Environment)
+	return nil
+}
+
+func (g *GitlabOutput) mergeRequestNote(span sreCommon.TracerSpan, a *GitlabAction, options []gitlab.RequestOptionFunc) error {
+
+	if a.MR <= 0 || utils.IsEmpty(a.Body) {
+		return fmt.Errorf("gitlab merge request or body is empty")
+	}
+
+	note, _, err := g.client.Notes.CreateMergeRequestNote(a.Project, a.MR, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(a.Body)}, options...)
+	if err != nil {
+		return err
+	}
+	g.logger.SpanDebug(span, "Gitlab merge request %d note => %d", a.MR, note.ID)
+	return nil
+}
+
+func (g *GitlabOutput) runActions(span sreCommon.TracerSpan, jsonObject interface{}) {
+
+	actions, err := g.getActions(span, jsonObject)
+	if err != nil {
+		g.logger.SpanError(span, err)
+		return
+	}
+
+	for _, a := range actions {
+
+		if a == nil || utils.IsEmpty(a.Project) {
+			continue
+		}
+
+		var options []gitlab.RequestOptionFunc
+		if !utils.IsEmpty(a.Token) {
+			options = append(options, gitlab.WithToken(gitlab.PrivateToken, a.Token))
+		}
+
+		g.actionRequests.Inc(a.Project, a.Type)
+
+		switch a.Type {
+		case "issue", "note":
+			err = g.issue(span, a, options)
+		case "deployment":
+			err = g.deployment(span, a, options)
+		case "mr-note":
+			err = g.mergeRequestNote(span, a, options)
+		default:
+			err = fmt.Errorf("gitlab action %s is not supported", a.Type)
+		}
+
+		if err != nil {
+			g.actionErrors.Inc(a.Project, a.Type)
+			g.logger.SpanError(span, err)
+		}
+	}
+}
+
 func (g *GitlabOutput) Send(event *common.Event) {
 
 	g.wg.Add(1)
 	go func() {
 		defer g.wg.Done()
 
-		if g.client == nil || g.projects == nil {
-			g.logger.Debug("No client or projects")
+		if g.client == nil || (g.projects == nil && g.actions == nil) {
+			g.logger.Debug("No client, projects or actions")
 			return
 		}
 
@@ -100,6 +543,11 @@ func (g *GitlabOutput) Send(event *common.Event) {
 			return
 		}
 
+		// skip own result events
+		if _, ok := event.Via[g.Name()]; ok {
+			return
+		}
+
 		span := g.tracer.StartFollowSpan(event.GetSpanContext())
 		defer span.Finish()
 
@@ -114,76 +562,16 @@ func (g *GitlabOutput) Send(event *common.Event) {
 			return
 		}
 
-		projects := ""
-		if g.projects != nil {
-			b, err := g.projects.Execute(jsonObject)
-			if err != nil {
-				g.logger.SpanDebug(span, err)
-			} else {
-				projects = b.String()
-			}
-		}
-
-		if utils.IsEmpty(projects) {
-			g.logger.SpanDebug(span, "Gitlab projects are not found")
-			return
-		}
-
-		variables, err := g.getVariables(jsonObject, span)
-		if err != nil {
-			g.logger.SpanError(span, err)
-		}
-
-		arr := strings.Split(projects, "\n")
-		for _, project := range arr {
-
-			project = strings.TrimSpace(project)
-			if utils.IsEmpty(project) {
-				continue
-			}
-			pair := strings.SplitN(project, "=", 2)
-			token := g.options.Token
-
-			if len(pair) == 2 && !utils.IsEmpty(pair[0]) {
-				token = pair[0]
-				project = pair[1]
-			}
-
-			pair = strings.SplitN(project, "@", 2)
-			if len(pair) < 2 {
-				continue
-			}
-
-			id := pair[0]
-			ref := pair[1]
-			if utils.IsEmpty(ref) {
-				ref = "main"
-			}
-
-			g.requests.Inc(id, ref)
-
-			opt := &gitlab.RunPipelineTriggerOptions{Ref: &ref, Token: &token, Variables: variables}
-			pipeline, response, err := g.client.PipelineTriggers.RunPipelineTrigger(id, opt)
-			if err != nil {
-				g.errors.Inc(id, ref)
-				g.logger.SpanError(span, err)
-				continue
-			}
-
-			if response.StatusCode < 200 || response.StatusCode >= 300 {
-				g.errors.Inc(id, ref)
-				g.logger.SpanError(span, "Gitlab reposne: %s", response.Status)
-				continue
-			}
-			g.logger.SpanDebug(span, "Gitlab pipeline => %s", pipeline.WebURL)
-		}
+		g.triggerPipelines(span, event, jsonObject)
+		g.runActions(span, jsonObject)
 	}()
 }
 
 func NewGitlabOutput(wg *sync.WaitGroup,
 	options GitlabOutputOptions,
 	templateOptions render.TextTemplateOptions,
-	observability *common.Observability) *GitlabOutput {
+	observability *common.Observability,
+	outputs *common.Outputs) *GitlabOutput {
 
 	logger := observability.Logs()
 	if utils.IsEmpty(options.BaseURL) {
@@ -198,14 +586,18 @@ func NewGitlabOutput(wg *sync.WaitGroup,
 	}
 
 	return &GitlabOutput{
-		wg:        wg,
-		client:    client,
-		projects:  render.NewTextTemplate("gitlab-projects", options.Projects, templateOptions, options, logger),
-		variables: render.NewTextTemplate("gitlab-variables", options.Variables, templateOptions, options, logger),
-		options:   options,
-		logger:    logger,
-		tracer:    observability.Traces(),
-		requests:  observability.Metrics().Counter("requests", "Count of all gitlab requests", []string{"project_id", "ref"}, "gitlab", "output"),
-		errors:    observability.Metrics().Counter("errors", "Count of all gitlab errors", []string{"project_id", "ref"}, "gitlab", "output"),
+		wg:             wg,
+		client:         client,
+		projects:       render.NewTextTemplate("gitlab-projects", options.Projects, templateOptions, options, logger),
+		variables:      render.NewTextTemplate("gitlab-variables", options.Variables, templateOptions, options, logger),
+		actions:        render.NewTextTemplate("gitlab-actions", options.Actions, templateOptions, options, logger),
+		options:        options,
+		outputs:        outputs,
+		logger:         logger,
+		tracer:         observability.Traces(),
+		requests:       observability.Metrics().Counter("requests", "Count of all gitlab requests", []string{"project_id", "ref"}, "gitlab", "output"),
+		errors:         observability.Metrics().Counter("errors", "Count of all gitlab errors", []string{"project_id", "ref"}, "gitlab", "output"),
+		actionRequests: observability.Metrics().Counter("action_requests", "Count of all gitlab action requests", []string{"project_id", "action"}, "gitlab", "output"),
+		actionErrors:   observability.Metrics().Counter("action_errors", "Count of all gitlab action errors", []string{"project_id", "action"}, "gitlab", "output"),
 	}
 }
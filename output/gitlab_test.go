@@ -0,0 +1,96 @@
+package output
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+	"github.com/xanzy/go-gitlab"
+)
+
+// testOutput keeps sent events
+type testOutput struct {
+	mutex  sync.Mutex
+	events []*common.Event
+}
+
+func (o *testOutput) Name() string {
+	return "Test"
+}
+
+func (o *testOutput) Send(event *common.Event) {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *testOutput) sent() []*common.Event {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.events
+}
+
+func TestGitlabOutputTrackPipeline(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/1/pipelines/5" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":5,"ref":"main","status":"success","web_url":"https://gitlab.example.com/sre/remediation/-/pipelines/5"}`)
+	}))
+	defer server.Close()
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	outputs := common.NewOutputs(observability.Logs())
+	recorder := &testOutput{}
+	outputs.Add(recorder)
+
+	wg := &sync.WaitGroup{}
+	g := NewGitlabOutput(wg, GitlabOutputOptions{
+		BaseURL:          server.URL,
+		Token:            "token",
+		PipelineTimeout:  10,
+		PipelineInterval: 1,
+	}, render.TextTemplateOptions{}, observability, &outputs)
+	if g == nil {
+		t.Fatal("gitlab output is not created")
+	}
+
+	origin := &common.Event{
+		Channel: "datadog",
+		Type:    "DataDogEvent",
+		Data:    map[string]interface{}{"alert": map[string]interface{}{"id": "42"}},
+	}
+
+	g.trackPipeline(nil, origin, "1", &gitlab.Pipeline{ID: 5, Ref: "main", Status: "running"})
+
+	events := recorder.sent()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 result event, got %d", len(events))
+	}
+
+	e := events[0]
+	if e.Type != GitlabPipelineResultEventType() || e.Channel != "datadog" || e.Key != "42" {
+		t.Errorf("unexpected result event type %s, channel %s, key %s", e.Type, e.Channel, e.Key)
+	}
+	if threadType(e) != "DataDogEvent" {
+		t.Errorf("result event isn't threaded with original event, type %s", threadType(e))
+	}
+
+	r, ok := e.Data.(*GitlabPipelineResult)
+	if !ok {
+		t.Fatalf("unexpected result data %T", e.Data)
+	}
+	if r.Status != "success" || r.Timeout || r.Project != "sre/remediation" || r.Event.Key != "42" {
+		t.Errorf("unexpected pipeline result %+v", r)
+	}
+}
@@ -25,6 +25,7 @@ type KafkaOutputOptions struct {
 	NetDialTimeout     int
 	NetReadTimeout     int
 	NetWriteTimeout    int
+	CloudEventsSource  string
 }
 
 type KafkaOutput struct {
@@ -76,11 +77,26 @@ func (k *KafkaOutput) Send(event *common.Event) {
 		k.requests.Inc(k.options.Topic)
 		k.logger.SpanDebug(span, "Kafka  message => %s", message)
 
-		(*k.producer).Input() <- &sarama.ProducerMessage{
+		msg := &sarama.ProducerMessage{
 			Topic: k.options.Topic,
 			Value: sarama.ByteEncoder(b.Bytes()),
 		}
 
+		// cloud event is sent in binary content mode, so consumers of message are not changed
+		if !utils.IsEmpty(k.options.CloudEventsSource) {
+
+			ce, err := common.NewCloudEvent(event, k.options.CloudEventsSource, b.Bytes(), span.GetContext())
+			if err != nil {
+				k.logger.SpanError(span, err)
+				return
+			}
+			for key, value := range ce.Attributes("ce_", "content-type") {
+				msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+			}
+		}
+
+		(*k.producer).Input() <- msg
+
 		for err = range (*k.producer).Errors() {
 			k.errors.Inc(k.options.Topic)
 		}
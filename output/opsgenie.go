@@ -0,0 +1,494 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type OpsgenieOutputOptions struct {
+	URL         string
+	Region      string
+	WebURL      string
+	Timeout     int
+	ApiKey      string
+	Action      string
+	Alias       string
+	Message     string
+	Description string
+	Priority    string
+	Responders  string
+	Tags        string
+	Details     string
+	Entity      string
+	Source      string
+	Retries     int
+	Forward     string
+}
+
+type OpsgenieOutput struct {
+	wg          *sync.WaitGroup
+	client      *http.Client
+	message     *render.TextTemplate
+	action      *render.TextTemplate
+	alias       *render.TextTemplate
+	description *render.TextTemplate
+	priority    *render.TextTemplate
+	responders  *render.TextTemplate
+	tags        *render.TextTemplate
+	details     *render.TextTemplate
+	entity      *render.TextTemplate
+	source      *render.TextTemplate
+	options     OpsgenieOutputOptions
+	tracer      sreCommon.Tracer
+	logger      sreCommon.Logger
+	requests    sreCommon.Counter
+	errors      sreCommon.Counter
+	outputs     *common.Outputs
+}
+
+type OpsgenieResponder struct {
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type OpsgenieAlert struct {
+	Message     string               `json:"message"`
+	Alias       string               `json:"alias,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Responders  []*OpsgenieResponder `json:"responders,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Details     map[string]string    `json:"details,omitempty"`
+	Entity      string               `json:"entity,omitempty"`
+	Source      string               `json:"source,omitempty"`
+	Priority    string               `json:"priority,omitempty"`
+}
+
+type OpsgenieClose struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+type OpsgenieResponse struct {
+	Result    string  `json:"result"`
+	Took      float64 `json:"took"`
+	RequestID string  `json:"requestId"`
+}
+
+// OpsgenieRequestStatus is result of asynchronous processing of create or close request
+type OpsgenieRequestStatus struct {
+	Data struct {
+		IsSuccess bool   `json:"isSuccess"`
+		Status    string `json:"status"`
+		AlertID   string `json:"alertId"`
+		Alias     string `json:"alias"`
+	} `json:"data"`
+}
+
+type OpsgenieVia struct {
+	Action    string `json:"action"`
+	Alias     string `json:"alias"`
+	RequestID string `json:"requestId"`
+	AlertID   string `json:"alertId,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+func (o *OpsgenieOutput) Name() string {
+	return "Opsgenie"
+}
+
+func (o *OpsgenieOutput) apiURL() string {
+
+	if !utils.IsEmpty(o.options.URL) {
+		return strings.TrimRight(o.options.URL, "/")
+	}
+
+	if strings.ToLower(o.options.Region) == "eu" {
+		return "https://api.eu.opsgenie.com"
+	}
+	return "https://api.opsgenie.com"
+}
+
+// defaultAction is based on status of event, which is normalized by processor
+func (o *OpsgenieOutput) defaultAction(event *common.Event) string {
+
+	if event.Status == common.StatusResolved {
+		return "close"
+	}
+	return "create"
+}
+
+// defaultPriority is based on severity of event, unknown severity is left to Opsgenie, which sets P3
+func (o *OpsgenieOutput) defaultPriority(event *common.Event) string {
+
+	switch event.Severity {
+	case common.SeverityCritical:
+		return "P1"
+	case common.SeverityWarning:
+		return "P3"
+	case common.SeverityInfo:
+		return "P5"
+	}
+	return ""
+}
+
+// responders are lines like team:SRE, user:john@example.com, escalation:Main, schedule:Primary
+func (o *OpsgenieOutput) parseResponders(s string) []*OpsgenieResponder {
+
+	var r []*OpsgenieResponder
+	for _, line := range strings.Split(s, "\n") {
+
+		line = strings.TrimSpace(line)
+		if utils.IsEmpty(line) {
+			continue
+		}
+
+		t := "team"
+		v := line
+		if arr := strings.SplitN(line, ":", 2); len(arr) == 2 {
+			t = strings.TrimSpace(arr[0])
+			v = strings.TrimSpace(arr[1])
+		}
+
+		responder := &OpsgenieResponder{Type: t}
+		if t == "user" {
+			responder.Username = v
+		} else {
+			responder.Name = v
+		}
+		r = append(r, responder)
+	}
+	return r
+}
+
+func (o *OpsgenieOutput) parseTags(s string) []string {
+
+	var r []string
+	for _, tag := range strings.FieldsFunc(s, func(c rune) bool { return c == ',' || c == '\n' }) {
+		tag = strings.TrimSpace(tag)
+		if !utils.IsEmpty(tag) {
+			r = append(r, tag)
+		}
+	}
+	return r
+}
+
+func (o *OpsgenieOutput) parseDetails(s string) map[string]string {
+
+	if utils.IsEmpty(s) {
+		return nil
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return map[string]string{"details": s}
+	}
+
+	r := make(map[string]string)
+	for k, v := range m {
+		switch v.(type) {
+		case string:
+			r[k] = v.(string)
+		default:
+			b, _ := json.Marshal(v)
+			r[k] = string(b)
+		}
+	}
+	return r
+}
+
+func (o *OpsgenieOutput) retryAfter(resp *http.Response, attempt int) time.Duration {
+
+	if s := resp.Header.Get("Retry-After"); !utils.IsEmpty(s) {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return time.Duration(attempt+1) * time.Second
+}
+
+func (o *OpsgenieOutput) post(spanCtx sreCommon.TracerSpanContext, URL string, obj interface{}) (*OpsgenieResponse, error) {
+
+	span := o.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	body, err := json.Marshal(obj)
+	if err != nil {
+		o.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	o.logger.SpanDebug(span, "Post to Opsgenie (%s) => %s", URL, string(body))
+
+	for attempt := 0; ; attempt++ {
+
+		req, err := http.NewRequest("POST", URL, bytes.NewReader(body))
+		if err != nil {
+			o.logger.SpanError(span, err)
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("GenieKey %s", o.options.ApiKey))
+
+		resp, err := o.client.Do(req)
+		if err != nil {
+			o.logger.SpanError(span, err)
+			return nil, err
+		}
+
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			o.logger.SpanError(span, err)
+			return nil, err
+		}
+
+		o.logger.SpanDebug(span, "Response from Opsgenie => %s", string(b))
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < o.options.Retries {
+			delay := o.retryAfter(resp, attempt)
+			o.logger.SpanWarn(span, "Opsgenie rate limit reached, retry in %s", delay)
+			time.Sleep(delay)
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err := fmt.Errorf("Opsgenie responded %d: %s", resp.StatusCode, string(b))
+			o.logger.SpanError(span, err)
+			return nil, err
+		}
+
+		var r OpsgenieResponse
+		if err := json.Unmarshal(b, &r); err != nil {
+			o.logger.SpanError(span, err)
+			return nil, err
+		}
+		return &r, nil
+	}
+}
+
+// requestStatus waits for processing of request, Opsgenie responds 404 until request is processed
+func (o *OpsgenieOutput) requestStatus(spanCtx sreCommon.TracerSpanContext, requestID string) (*OpsgenieRequestStatus, error) {
+
+	span := o.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	URL := fmt.Sprintf("%s/v2/alerts/requests/%s", o.apiURL(), url.PathEscape(requestID))
+
+	for attempt := 0; ; attempt++ {
+
+		req, err := http.NewRequest("GET", URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("GenieKey %s", o.options.ApiKey))
+
+		resp, err := o.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		retry := resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusTooManyRequests
+		if retry && attempt < o.options.Retries {
+			time.Sleep(o.retryAfter(resp, attempt))
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("Opsgenie request %s status responded %d: %s", requestID, resp.StatusCode, string(b))
+		}
+
+		o.logger.SpanDebug(span, "Opsgenie request %s status => %s", requestID, string(b))
+
+		var r OpsgenieRequestStatus
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, err
+		}
+		return &r, nil
+	}
+}
+
+func (o *OpsgenieOutput) createAlert(spanCtx sreCommon.TracerSpanContext, alert *OpsgenieAlert) (*OpsgenieResponse, error) {
+	return o.post(spanCtx, fmt.Sprintf("%s/v2/alerts", o.apiURL()), alert)
+}
+
+func (o *OpsgenieOutput) closeAlert(spanCtx sreCommon.TracerSpanContext, alias, source string) (*OpsgenieResponse, error) {
+
+	URL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.apiURL(), url.PathEscape(alias))
+	return o.post(spanCtx, URL, &OpsgenieClose{Source: source, Note: "Resolved"})
+}
+
+func (o *OpsgenieOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event *common.Event, obj *OpsgenieVia) {
+
+	if utils.IsEmpty(o.options.Forward) || o.outputs == nil {
+		return
+	}
+
+	if _, ok := event.Via[o.Name()]; ok {
+		return
+	}
+
+	span := o.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	e := event.Forward(o.Name(), obj)
+	e.SetLogger(o.logger)
+	e.SetSpanContext(span.GetContext())
+
+	o.outputs.SendForward(e, []common.Output{o}, o.options.Forward)
+}
+
+func (o *OpsgenieOutput) Send(event *common.Event) {
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+
+		if o.client == nil {
+			o.logger.Debug("No client")
+			return
+		}
+
+		if event == nil {
+			o.logger.Debug("Event is empty")
+			return
+		}
+
+		span := o.tracer.StartFollowSpan(event.GetSpanContext())
+		defer span.Finish()
+
+		if event.Data == nil {
+			o.logger.SpanError(span, "Event data is empty")
+			return
+		}
+
+		jsonMap, err := event.JsonMap()
+		if err != nil {
+			o.logger.SpanError(span, err)
+			return
+		}
+
+		action := strings.ToLower(execute(o.action, jsonMap, o.defaultAction(event)))
+		alias := execute(o.alias, jsonMap, correlationKey(event, jsonMap))
+		source := execute(o.source, jsonMap, event.Channel)
+
+		var r *OpsgenieResponse
+
+		switch action {
+		case "create":
+
+			message := execute(o.message, jsonMap, "")
+			if utils.IsEmpty(message) {
+				o.logger.SpanDebug(span, "Opsgenie message is empty")
+				return
+			}
+
+			// Opsgenie limits message to 130 chars
+			message = truncate(130, message)
+
+			o.requests.Inc(action)
+			r, err = o.createAlert(span.GetContext(), &OpsgenieAlert{
+				Message:     message,
+				Alias:       alias,
+				Description: execute(o.description, jsonMap, ""),
+				Responders:  o.parseResponders(execute(o.responders, jsonMap, "")),
+				Tags:        o.parseTags(execute(o.tags, jsonMap, "")),
+				Details:     o.parseDetails(execute(o.details, jsonMap, "")),
+				Entity:      execute(o.entity, jsonMap, ""),
+				Source:      source,
+				Priority:    strings.ToUpper(execute(o.priority, jsonMap, o.defaultPriority(event))),
+			})
+		case "close":
+
+			if utils.IsEmpty(alias) {
+				o.logger.SpanError(span, "Opsgenie alias is required to close alert")
+				return
+			}
+
+			o.requests.Inc(action)
+			r, err = o.closeAlert(span.GetContext(), alias, source)
+		default:
+			o.logger.SpanError(span, "Opsgenie action %s is not supported", action)
+			return
+		}
+
+		if err != nil {
+			o.errors.Inc(action)
+			return
+		}
+
+		via := &OpsgenieVia{
+			Action:    action,
+			Alias:     alias,
+			RequestID: r.RequestID,
+		}
+
+		// link to alert needs its ID, which is known after request is processed
+		if !utils.IsEmpty(o.options.Forward) && !utils.IsEmpty(r.RequestID) {
+			status, err := o.requestStatus(span.GetContext(), r.RequestID)
+			if err != nil {
+				o.logger.SpanError(span, err)
+			} else if status.Data.IsSuccess {
+				via.AlertID = status.Data.AlertID
+			}
+		}
+		if !utils.IsEmpty(o.options.WebURL) && !utils.IsEmpty(via.AlertID) {
+			via.URL = fmt.Sprintf("%s/alert/detail/%s/details", strings.TrimRight(o.options.WebURL, "/"), url.PathEscape(via.AlertID))
+		}
+		o.sendGlobally(span.GetContext(), event, via)
+	}()
+}
+
+func NewOpsgenieOutput(wg *sync.WaitGroup,
+	options OpsgenieOutputOptions,
+	templateOptions render.TextTemplateOptions,
+	observability *common.Observability,
+	outputs *common.Outputs) *OpsgenieOutput {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.ApiKey) {
+		logger.Debug("Opsgenie API key is not defined. Skipped")
+		return nil
+	}
+
+	return &OpsgenieOutput{
+		wg:          wg,
+		client:      utils.NewHttpClient(options.Timeout, false),
+		message:     render.NewTextTemplate("opsgenie-message", options.Message, templateOptions, options, logger),
+		action:      render.NewTextTemplate("opsgenie-action", options.Action, templateOptions, options, logger),
+		alias:       render.NewTextTemplate("opsgenie-alias", options.Alias, templateOptions, options, logger),
+		description: render.NewTextTemplate("opsgenie-description", options.Description, templateOptions, options, logger),
+		priority:    render.NewTextTemplate("opsgenie-priority", options.Priority, templateOptions, options, logger),
+		responders:  render.NewTextTemplate("opsgenie-responders", options.Responders, templateOptions, options, logger),
+		tags:        render.NewTextTemplate("opsgenie-tags", options.Tags, templateOptions, options, logger),
+		details:     render.NewTextTemplate("opsgenie-details", options.Details, templateOptions, options, logger),
+		entity:      render.NewTextTemplate("opsgenie-entity", options.Entity, templateOptions, options, logger),
+		source:      render.NewTextTemplate("opsgenie-source", options.Source, templateOptions, options, logger),
+		options:     options,
+		tracer:      observability.Traces(),
+		logger:      logger,
+		requests:    observability.Metrics().Counter("requests", "Count of all opsgenie requests", []string{"action"}, "opsgenie", "output"),
+		errors:      observability.Metrics().Counter("errors", "Count of all opsgenie errors", []string{"action"}, "opsgenie", "output"),
+		outputs:     outputs,
+	}
+}
@@ -0,0 +1,149 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+type opsgenieCall struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]interface{}
+}
+
+func TestOpsgenieOutputLifecycle(t *testing.T) {
+
+	var mutex sync.Mutex
+	var calls []*opsgenieCall
+	limited := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		c := &opsgenieCall{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery}
+		if r.Method == "POST" {
+			if err := json.NewDecoder(r.Body).Decode(&c.Body); err != nil {
+				t.Error(err)
+			}
+		}
+		mutex.Lock()
+		calls = append(calls, c)
+		first := !limited
+		limited = true
+		mutex.Unlock()
+
+		switch {
+		case r.Method == "POST" && first:
+			// the first request hits rate limit
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.Method == "GET":
+			fmt.Fprint(w, `{"data":{"isSuccess":true,"status":"Created","alertId":"a1","alias":"disk"}}`)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"result":"Request will be processed","requestId":"r1"}`)
+		}
+	}))
+	defer server.Close()
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	outputs := common.NewOutputs(observability.Logs())
+	recorder := &testOutput{}
+
+	wg := &sync.WaitGroup{}
+	o := NewOpsgenieOutput(wg, OpsgenieOutputOptions{
+		URL:     server.URL,
+		WebURL:  "https://example.app.opsgenie.com",
+		Timeout: 5,
+		ApiKey:  "key",
+		Message: "{{.data.title}}",
+		Retries: 2,
+		Forward: "Test",
+	}, render.TextTemplateOptions{}, observability, &outputs)
+	outputs.Add(o)
+	outputs.Add(recorder)
+
+	send := func(status, severity string) {
+		e := &common.Event{
+			Channel: "custom",
+			Type:    "CustomEvent",
+			Data:    map[string]interface{}{"title": "disk is full"},
+		}
+		e.SetAlert("disk", status, severity)
+		outputs.Send(e)
+		wg.Wait()
+	}
+
+	send(common.StatusFiring, "high")
+	send(common.StatusResolved, "")
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var paths []string
+	for _, c := range calls {
+		paths = append(paths, fmt.Sprintf("%s %s", c.Method, c.Path))
+	}
+	expected := []string{
+		"POST /v2/alerts", "POST /v2/alerts", "GET /v2/alerts/requests/r1",
+		"POST /v2/alerts/disk/close", "GET /v2/alerts/requests/r1",
+	}
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	create := calls[1]
+	if create.Body["alias"] != "disk" || create.Body["priority"] != "P1" || create.Body["message"] != "disk is full" {
+		t.Errorf("unexpected create %v", create.Body)
+	}
+	if calls[3].Query != "identifierType=alias" {
+		t.Errorf("alert isn't closed by alias: %s", calls[3].Query)
+	}
+
+	var via []*OpsgenieVia
+	for _, e := range recorder.sent() {
+		if v, ok := e.Via[o.Name()].(*OpsgenieVia); ok {
+			via = append(via, v)
+		}
+	}
+	if len(via) != 2 {
+		t.Fatalf("expected 2 forwarded events, got %d", len(via))
+	}
+	if via[0].Action != "create" || via[0].AlertID != "a1" || via[0].URL != "https://example.app.opsgenie.com/alert/detail/a1/details" {
+		t.Errorf("unexpected create via %+v", via[0])
+	}
+	if via[1].Action != "close" || via[1].Alias != "disk" {
+		t.Errorf("unexpected close via %+v", via[1])
+	}
+}
+
+func TestOpsgenieOutputPriority(t *testing.T) {
+
+	tests := []struct {
+		severity string
+		priority string
+	}{
+		{severity: common.SeverityCritical, priority: "P1"},
+		{severity: common.SeverityWarning, priority: "P3"},
+		{severity: common.SeverityInfo, priority: "P5"},
+		{severity: common.SeverityOk, priority: ""},
+		{severity: "", priority: ""},
+	}
+
+	o := &OpsgenieOutput{}
+	for _, tt := range tests {
+		// severity is normalized by processor
+		e := &common.Event{Severity: tt.severity}
+		if p := o.defaultPriority(e); p != tt.priority {
+			t.Errorf("%s: expected %q, got %q", tt.severity, tt.priority, p)
+		}
+	}
+}
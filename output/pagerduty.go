@@ -0,0 +1,312 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type PagerDutyOutputOptions struct {
+	URL                string
+	Timeout            int
+	RoutingKey         string
+	RoutingKeySelector string
+	Action             string
+	DedupKey           string
+	Message            string
+	Severity           string
+	Source             string
+	Component          string
+	Group              string
+	Class              string
+	Details            string
+	Forward            string
+}
+
+type PagerDutyOutput struct {
+	wg        *sync.WaitGroup
+	client    *http.Client
+	message   *render.TextTemplate
+	selector  *render.TextTemplate
+	action    *render.TextTemplate
+	dedupKey  *render.TextTemplate
+	severity  *render.TextTemplate
+	source    *render.TextTemplate
+	component *render.TextTemplate
+	group     *render.TextTemplate
+	class     *render.TextTemplate
+	details   *render.TextTemplate
+	options   PagerDutyOutputOptions
+	tracer    sreCommon.Tracer
+	logger    sreCommon.Logger
+	requests  sreCommon.Counter
+	errors    sreCommon.Counter
+	outputs   *common.Outputs
+}
+
+type PagerDutyPayload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Timestamp     string      `json:"timestamp,omitempty"`
+	Component     string      `json:"component,omitempty"`
+	Group         string      `json:"group,omitempty"`
+	Class         string      `json:"class,omitempty"`
+	CustomDetails interface{} `json:"custom_details,omitempty"`
+}
+
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+}
+
+type PagerDutyResponse struct {
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	DedupKey string   `json:"dedup_key"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func (p *PagerDutyOutput) Name() string {
+	return "PagerDuty"
+}
+
+// defaultAction is based on status of event, which is normalized by processor
+func (p *PagerDutyOutput) defaultAction(event *common.Event) string {
+
+	if event.Status == common.StatusResolved {
+		return "resolve"
+	}
+	return "trigger"
+}
+
+func (p *PagerDutyOutput) normalizeSeverity(severity string) string {
+
+	switch strings.ToLower(severity) {
+	case "critical", "fatal", "disaster", "high", "p1":
+		return "critical"
+	case "error", "major", "p2":
+		return "error"
+	case "warning", "warn", "minor", "medium", "p3":
+		return "warning"
+	}
+	return "info"
+}
+
+func (p *PagerDutyOutput) post(spanCtx sreCommon.TracerSpanContext, e *PagerDutyEvent) ([]byte, error) {
+
+	span := p.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		p.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	p.logger.SpanDebug(span, "Post to PagerDuty (%s) => %s", p.options.URL, string(body))
+
+	req, err := http.NewRequest("POST", p.options.URL, bytes.NewReader(body))
+	if err != nil {
+		p.logger.SpanError(span, err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.logger.SpanError(span, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		p.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	p.logger.SpanDebug(span, "Response from PagerDuty => %s", string(b))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("PagerDuty responded %d: %s", resp.StatusCode, string(b))
+		p.logger.SpanError(span, err)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (p *PagerDutyOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event *common.Event, bytes []byte) {
+
+	if utils.IsEmpty(p.options.Forward) || p.outputs == nil {
+		return
+	}
+
+	if _, ok := event.Via[p.Name()]; ok {
+		return
+	}
+
+	span := p.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	var obj interface{}
+	if err := json.Unmarshal(bytes, &obj); err != nil {
+		p.logger.SpanError(span, err)
+		return
+	}
+
+	e := event.Forward(p.Name(), obj)
+	e.SetLogger(p.logger)
+	e.SetSpanContext(span.GetContext())
+
+	p.outputs.SendForward(e, []common.Output{p}, p.options.Forward)
+}
+
+func (p *PagerDutyOutput) Send(event *common.Event) {
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		if p.client == nil {
+			p.logger.Debug("No client")
+			return
+		}
+
+		if event == nil {
+			p.logger.Debug("Event is empty")
+			return
+		}
+
+		span := p.tracer.StartFollowSpan(event.GetSpanContext())
+		defer span.Finish()
+
+		if event.Data == nil {
+			p.logger.SpanError(span, "Event data is empty")
+			return
+		}
+
+		jsonMap, err := event.JsonMap()
+		if err != nil {
+			p.logger.SpanError(span, err)
+			return
+		}
+
+		keys := execute(p.selector, jsonMap, p.options.RoutingKey)
+		if utils.IsEmpty(keys) {
+			p.logger.SpanDebug(span, "PagerDuty routing keys are not found. Skipped")
+			return
+		}
+
+		action := strings.ToLower(execute(p.action, jsonMap, p.defaultAction(event)))
+		if !utils.Contains([]string{"trigger", "acknowledge", "resolve"}, action) {
+			p.logger.SpanError(span, "PagerDuty action %s is not supported", action)
+			return
+		}
+
+		dedupKey := execute(p.dedupKey, jsonMap, correlationKey(event, jsonMap))
+		if action != "trigger" && utils.IsEmpty(dedupKey) {
+			p.logger.SpanError(span, "PagerDuty dedup key is required for %s", action)
+			return
+		}
+
+		var payload *PagerDutyPayload
+		if action == "trigger" {
+
+			summary := execute(p.message, jsonMap, "")
+			if utils.IsEmpty(summary) {
+				p.logger.SpanDebug(span, "PagerDuty message is empty")
+				return
+			}
+
+			var details interface{}
+			if d := execute(p.details, jsonMap, ""); !utils.IsEmpty(d) {
+				if err := json.Unmarshal([]byte(d), &details); err != nil {
+					details = d
+				}
+			}
+
+			// PagerDuty limits summary to 1024 chars
+			summary = truncate(1024, summary)
+
+			payload = &PagerDutyPayload{
+				Summary:       summary,
+				Source:        execute(p.source, jsonMap, event.Channel),
+				Severity:      p.normalizeSeverity(execute(p.severity, jsonMap, event.Severity)),
+				Timestamp:     event.Time.UTC().Format(time.RFC3339),
+				Component:     execute(p.component, jsonMap, ""),
+				Group:         execute(p.group, jsonMap, ""),
+				Class:         execute(p.class, jsonMap, event.Type),
+				CustomDetails: details,
+			}
+		}
+
+		for _, key := range strings.Split(keys, "\n") {
+
+			key = strings.TrimSpace(key)
+			if utils.IsEmpty(key) {
+				continue
+			}
+
+			p.requests.Inc(action)
+
+			bytes, err := p.post(span.GetContext(), &PagerDutyEvent{
+				RoutingKey:  key,
+				EventAction: action,
+				DedupKey:    dedupKey,
+				Payload:     payload,
+			})
+			if err != nil {
+				p.errors.Inc(action)
+				continue
+			}
+			p.sendGlobally(span.GetContext(), event, bytes)
+		}
+	}()
+}
+
+func NewPagerDutyOutput(wg *sync.WaitGroup,
+	options PagerDutyOutputOptions,
+	templateOptions render.TextTemplateOptions,
+	observability *common.Observability,
+	outputs *common.Outputs) *PagerDutyOutput {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.RoutingKey) && utils.IsEmpty(options.RoutingKeySelector) {
+		logger.Debug("PagerDuty routing key is not defined. Skipped")
+		return nil
+	}
+
+	return &PagerDutyOutput{
+		wg:        wg,
+		client:    utils.NewHttpClient(options.Timeout, false),
+		message:   render.NewTextTemplate("pagerduty-message", options.Message, templateOptions, options, logger),
+		selector:  render.NewTextTemplate("pagerduty-selector", options.RoutingKeySelector, templateOptions, options, logger),
+		action:    render.NewTextTemplate("pagerduty-action", options.Action, templateOptions, options, logger),
+		dedupKey:  render.NewTextTemplate("pagerduty-dedup-key", options.DedupKey, templateOptions, options, logger),
+		severity:  render.NewTextTemplate("pagerduty-severity", options.Severity, templateOptions, options, logger),
+		source:    render.NewTextTemplate("pagerduty-source", options.Source, templateOptions, options, logger),
+		component: render.NewTextTemplate("pagerduty-component", options.Component, templateOptions, options, logger),
+		group:     render.NewTextTemplate("pagerduty-group", options.Group, templateOptions, options, logger),
+		class:     render.NewTextTemplate("pagerduty-class", options.Class, templateOptions, options, logger),
+		details:   render.NewTextTemplate("pagerduty-details", options.Details, templateOptions, options, logger),
+		options:   options,
+		tracer:    observability.Traces(),
+		logger:    logger,
+		requests:  observability.Metrics().Counter("requests", "Count of all pagerduty requests", []string{"action"}, "pagerduty", "output"),
+		errors:    observability.Metrics().Counter("errors", "Count of all pagerduty errors", []string{"action"}, "pagerduty", "output"),
+		outputs:   outputs,
+	}
+}
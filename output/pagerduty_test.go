@@ -0,0 +1,128 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+func TestPagerDutyOutputStatus(t *testing.T) {
+
+	var mutex sync.Mutex
+	var sent []*PagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var e PagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		mutex.Lock()
+		sent = append(sent, &e)
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"status":"success","dedup_key":"%s"}`, e.DedupKey)
+	}))
+	defer server.Close()
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	outputs := common.NewOutputs(observability.Logs())
+
+	wg := &sync.WaitGroup{}
+	p := NewPagerDutyOutput(wg, PagerDutyOutputOptions{
+		URL:        server.URL,
+		Timeout:    5,
+		RoutingKey: "routing",
+		Message:    "{{.data.title}}",
+		Forward:    "Test",
+	}, render.TextTemplateOptions{}, observability, &outputs)
+	recorder := &testOutput{}
+	outputs.Add(p)
+	outputs.Add(recorder)
+
+	send := func(title, status, severity string) *common.Event {
+		// vendor fields don't tell status, it's set by processor
+		e := &common.Event{
+			Channel: "custom",
+			Type:    "CustomEvent",
+			Data:    map[string]interface{}{"title": title},
+		}
+		e.SetAlert("disk", status, severity)
+		outputs.Send(e)
+		wg.Wait()
+		return e
+	}
+
+	long := strings.Repeat("диск < 10% & ", 100)
+	firing := send(long, common.StatusFiring, "high")
+	send(long, common.StatusResolved, "")
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("expected 2 PagerDuty events, got %d", len(sent))
+	}
+
+	trigger := sent[0]
+	if trigger.EventAction != "trigger" || trigger.DedupKey != "disk" || trigger.Payload == nil || trigger.Payload.Severity != "critical" {
+		t.Fatalf("unexpected trigger %+v", trigger)
+	}
+	summary := trigger.Payload.Summary
+	if utf8.RuneCountInString(summary) != 1024 || !strings.HasPrefix(long, strings.TrimSuffix(summary, "…")) {
+		t.Errorf("summary isn't cut by runes to 1024: %d %q", utf8.RuneCountInString(summary), summary[len(summary)-20:])
+	}
+	if sent[1].EventAction != "resolve" || sent[1].DedupKey != "disk" {
+		t.Errorf("unexpected resolve %+v", sent[1])
+	}
+
+	var forwarded []*common.Event
+	for _, e := range recorder.sent() {
+		if _, ok := e.Via[p.Name()]; ok {
+			forwarded = append(forwarded, e)
+		}
+	}
+	if len(forwarded) != 2 {
+		t.Fatalf("expected 2 forwarded events, got %d", len(forwarded))
+	}
+	f := forwarded[0]
+	if f.ID != firing.ID || f.Key != "disk" || f.Severity != common.SeverityCritical || f.Status != common.StatusFiring {
+		t.Errorf("forwarded event isn't the same as original: %s/%s, key %s, severity %s, status %s",
+			f.ID, firing.ID, f.Key, f.Severity, f.Status)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		limit int
+		text  string
+		want  string
+	}{
+		{name: "short", limit: 10, text: "disk", want: "disk"},
+		{name: "at limit", limit: 4, text: "disk", want: "disk"},
+		{name: "over limit", limit: 4, text: "disks", want: "dis…"},
+		{name: "less than", limit: 9, text: "value < 10 on host", want: "value < …"},
+		{name: "ampersand", limit: 8, text: "Tom & Jerry", want: "Tom & J…"},
+		{name: "entity like", limit: 6, text: "a &amp; b", want: "a &am…"},
+		{name: "multibyte", limit: 3, text: "диск", want: "ди…"},
+		{name: "zero", limit: 0, text: "disk", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.limit, tt.text); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
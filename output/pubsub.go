@@ -15,10 +15,11 @@ import (
 )
 
 type PubSubOutputOptions struct {
-	Credentials   string
-	ProjectID     string
-	Message       string
-	TopicSelector string
+	Credentials       string
+	ProjectID         string
+	Message           string
+	TopicSelector     string
+	CloudEventsSource string
 }
 
 type PubSubOutput struct {
@@ -97,6 +98,17 @@ func (ps *PubSubOutput) Send(event *common.Event) {
 
 		ps.logger.SpanDebug(span, "PubSub message => %s", message)
 
+		var attributes map[string]string
+		if !utils.IsEmpty(ps.options.CloudEventsSource) {
+
+			ce, err := common.NewCloudEvent(event, ps.options.CloudEventsSource, []byte(message), span.GetContext())
+			if err != nil {
+				ps.logger.SpanError(span, err)
+				return
+			}
+			attributes = ce.Attributes("ce-", "content-type")
+		}
+
 		arr := strings.Split(topics, "\n")
 		for _, topic := range arr {
 			topic = strings.TrimSpace(topic)
@@ -107,7 +119,7 @@ func (ps *PubSubOutput) Send(event *common.Event) {
 			ps.requests.Inc(topic)
 
 			t := ps.client.Topic(topic)
-			serverID, err := t.Publish(ps.ctx, &pubsub.Message{Data: []byte(message)}).Get(ps.ctx)
+			serverID, err := t.Publish(ps.ctx, &pubsub.Message{Data: []byte(message), Attributes: attributes}).Get(ps.ctx)
 			if err != nil {
 				ps.errors.Inc(topic)
 				ps.logger.SpanError(span, err)
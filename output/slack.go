@@ -1,20 +1,19 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	sreCommon "github.com/devopsext/sre/common"
 	vendors "github.com/devopsext/tools/vendors"
 	"github.com/devopsext/utils"
 
-	"github.com/VictoriaMetrics/metricsql"
 	"github.com/devopsext/events/common"
 	"github.com/devopsext/events/render"
 	"github.com/prometheus/alertmanager/template"
@@ -28,20 +27,38 @@ type SlackOutputOptions struct {
 	ChannelSelector string
 	AlertExpression string
 	Forward         string
+	Threads         bool
+	ThreadKey       string
+	ThreadStore     string
+	ThreadTTL       int
+	Update          string
+	Actions         string
+	Render          string
 }
 
 type SlackOutput struct {
-	wg       *sync.WaitGroup
-	slack    *vendors.Slack
-	message  *render.TextTemplate
-	selector *render.TextTemplate
-	grafana  *render.GrafanaRender
-	options  SlackOutputOptions
-	outputs  *common.Outputs
-	tracer   sreCommon.Tracer
-	logger   sreCommon.Logger
-	requests sreCommon.Counter
-	errors   sreCommon.Counter
+	wg        *sync.WaitGroup
+	slack     *vendors.Slack
+	client    *http.Client
+	message   *render.TextTemplate
+	selector  *render.TextTemplate
+	threadKey *render.TextTemplate
+	update    *render.TextTemplate
+	actions   *render.TextTemplate
+	image     render.ImageRender
+	charts    *render.ChartProviders
+	store     *common.Store
+	options   SlackOutputOptions
+	outputs   *common.Outputs
+	tracer    sreCommon.Tracer
+	logger    sreCommon.Logger
+	requests  sreCommon.Counter
+	errors    sreCommon.Counter
+}
+
+type SlackThread struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
 }
 
 func (s *SlackOutput) Name() string {
@@ -58,32 +75,97 @@ func (s *SlackOutput) getChannel(URL string) string {
 	return u.Query().Get("channels")
 }
 
-func waitDDImage(url string, timeout int) bool {
-	if timeout <= 0 {
-		timeout = 3
+// postMessage calls chat.postMessage with the same blocks as vendors.Slack does and actions block with buttons
+func (s *SlackOutput) postMessage(spanCtx sreCommon.TracerSpanContext, m vendors.SlackMessage, actions []interface{}) ([]byte, error) {
+
+	span := s.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	var blocks []interface{}
+
+	if !utils.IsEmpty(m.Title) {
+		blocks = append(blocks,
+			map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": fmt.Sprintf("*%s*", m.Title)}},
+			map[string]interface{}{"type": "divider"},
+		)
+	}
+
+	if !utils.IsEmpty(m.Message) {
+		blocks = append(blocks, map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": m.Message}})
+	}
+
+	if !utils.IsEmpty(m.ImageURL) {
+		blocks = append(blocks, map[string]interface{}{"type": "image", "image_url": m.ImageURL, "alt_text": m.ImageURL})
+	}
+
+	blocks = append(blocks, map[string]interface{}{"type": "actions", "elements": actions})
+
+	text := m.Title
+	if utils.IsEmpty(text) {
+		text = "Actions"
+	}
+
+	body := map[string]interface{}{
+		"channel": m.Channel,
+		"text":    text,
+		"blocks":  blocks,
+	}
+	if !utils.IsEmpty(m.ParentTS) {
+		body["thread_ts"] = m.ParentTS
+	}
+
+	b, err := s.callApi(span.GetContext(), m.Token, "chat.postMessage", body)
+	if err != nil {
+		s.logger.SpanError(span, err)
+		return nil, err
 	}
-	for i := 0; i < timeout; i++ {
-		resp, err := http.Get(url)
-		if err == nil && resp.StatusCode == 200 && resp.ContentLength > 179 {
-			return true
+	return b, nil
+}
+
+func (s *SlackOutput) getActions(jsonMap map[string]interface{}) []interface{} {
+
+	if s.actions == nil {
+		return nil
+	}
+
+	b, err := s.actions.Execute(jsonMap)
+	if err != nil {
+		return nil
+	}
+
+	text := strings.TrimSpace(b.String())
+	if utils.IsEmpty(text) {
+		return nil
+	}
+
+	var actions []interface{}
+	if err := json.Unmarshal([]byte(text), &actions); err != nil {
+		s.logger.Error(err)
+		return nil
+	}
+	return actions
+}
+
+// sendActions posts buttons into thread of message, which can't have blocks like uploaded file
+func (s *SlackOutput) sendActions(spanCtx sreCommon.TracerSpanContext, token, channel, parentTS string, bytes []byte, actions []interface{}) {
+
+	if len(actions) == 0 {
+		return
+	}
+
+	if utils.IsEmpty(parentTS) {
+		if t := s.getThread(bytes); t != nil {
+			parentTS = t.TS
 		}
-		time.Sleep(time.Second)
 	}
-	return false
+
+	s.postMessage(spanCtx, vendors.SlackMessage{Token: token, Channel: channel, ParentTS: parentTS}, actions)
 }
 
 func (s *SlackOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, m vendors.SlackMessage) ([]byte, error) {
 	span := s.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
-	// check if dd return 1x1 image
-	if !utils.IsEmpty(m.ImageURL) && strings.Contains(m.ImageURL, "datadoghq") {
-		if !waitDDImage(m.ImageURL, 3) {
-			s.logger.SpanDebug(span, "Can't get image from datadoghq: %s", m.ImageURL)
-			m.ImageURL = "https://via.placeholder.com/452x185.png?text=No%20chart%20image"
-		}
-	}
-
 	b, err := s.slack.SendCustomMessage(m)
 	if err != nil {
 		s.logger.SpanError(span, err)
@@ -94,13 +176,31 @@ func (s *SlackOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, m vendors
 	return b, nil
 }
 
+// sendMessageWithActions posts message with buttons, message with file is uploaded and buttons are posted into its thread
+func (s *SlackOutput) sendMessageWithActions(spanCtx sreCommon.TracerSpanContext, m vendors.SlackMessage, actions []interface{}) ([]byte, error) {
+
+	if len(actions) == 0 {
+		return s.sendMessage(spanCtx, m)
+	}
+
+	if !utils.IsEmpty(m.FileContent) {
+		bytes, err := s.sendMessage(spanCtx, m)
+		if err != nil {
+			return nil, err
+		}
+		s.sendActions(spanCtx, m.Token, m.Channel, m.ParentTS, bytes, actions)
+		return bytes, nil
+	}
+	return s.postMessage(spanCtx, m, actions)
+}
+
 func (s *SlackOutput) sendErrorMessage(spanCtx sreCommon.TracerSpanContext, m vendors.SlackMessage, err error) error {
 	m.FileContent = err.Error()
 	_, e := s.sendMessage(spanCtx, m)
 	return e
 }
 
-func (s *SlackOutput) sendImage(spanCtx sreCommon.TracerSpanContext, token, channel, message, fileName, title string, image []byte) ([]byte, error) {
+func (s *SlackOutput) sendImage(spanCtx sreCommon.TracerSpanContext, token, channel, parentTS, message, fileName, title string, image []byte) ([]byte, error) {
 
 	span := s.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
@@ -108,6 +208,7 @@ func (s *SlackOutput) sendImage(spanCtx sreCommon.TracerSpanContext, token, chan
 	m := vendors.SlackMessage{
 		Token:       token,
 		Channel:     channel,
+		ParentTS:    parentTS,
 		Message:     message,
 		FileName:    fileName,
 		Title:       title,
@@ -117,65 +218,222 @@ func (s *SlackOutput) sendImage(spanCtx sreCommon.TracerSpanContext, token, chan
 	return s.slack.SendCustomFile(m)
 }
 
-func (s *SlackOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, token, channel, message string, alert template.Alert) ([]byte, error) {
+// sendChartMessage uploads chart of event with message if event type has chart provider
+func (s *SlackOutput) sendChartMessage(spanCtx sreCommon.TracerSpanContext, eventType string, jsonMap map[string]interface{},
+	m vendors.SlackMessage, actions []interface{}) ([]byte, error) {
+
+	span := s.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	chart := s.charts.Chart(span.GetContext(), eventType, jsonMap)
+	if chart == nil {
+		return s.sendMessageWithActions(span.GetContext(), m, actions)
+	}
+
+	title := chart.Caption
+	if utils.IsEmpty(title) {
+		title = m.Title
+	}
+
+	bytes, err := s.sendImage(span.GetContext(), m.Token, m.Channel, m.ParentTS, m.Message, chart.FileName, title, chart.Image)
+	if err != nil {
+		s.logger.SpanError(span, err)
+		return nil, err
+	}
+	s.sendActions(span.GetContext(), m.Token, m.Channel, m.ParentTS, bytes, actions)
+	return bytes, nil
+}
+
+func (s *SlackOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, token, channel, parentTS, message string, alert template.Alert) ([]byte, error) {
 
 	span := s.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
-	u, err := url.Parse(alert.GeneratorURL)
+	request, query, err := alertImageRequest(alert, s.options.AlertExpression)
 	if err != nil {
 		return nil, err
 	}
 
-	values := u.Query()
-	for k, v := range values {
-		alert.Labels[k] = strings.Join(v, " ")
+	if s.image == nil {
+		return s.sendMessage(span.GetContext(), vendors.SlackMessage{Token: token, Channel: channel, ParentTS: parentTS, Message: message, Title: query})
 	}
 
-	query, ok := alert.Labels[s.options.AlertExpression]
+	image, fileName, err := s.image.Render(span.GetContext(), request)
+	if err != nil {
+		s.sendErrorMessage(span.GetContext(),
+			vendors.SlackMessage{Token: token, Channel: channel, ParentTS: parentTS, Message: message, Title: query}, err)
+		return nil, nil
+	}
+	return s.sendImage(span.GetContext(), token, channel, parentTS, message, fileName, query, image)
+}
+
+// getThread finds channel ID and ts of sent message in chat.postMessage or files.upload response
+func (s *SlackOutput) getThread(b []byte) *SlackThread {
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	var r struct {
+		OK      bool   `json:"ok"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+		File    *struct {
+			Shares map[string]map[string][]struct {
+				TS string `json:"ts"`
+			} `json:"shares"`
+		} `json:"file"`
+	}
+
+	if err := json.Unmarshal(b, &r); err != nil || !r.OK {
+		return nil
+	}
+
+	if !utils.IsEmpty(r.TS) {
+		return &SlackThread{Channel: r.Channel, TS: r.TS}
+	}
+
+	if r.File == nil {
+		return nil
+	}
+
+	for _, shares := range r.File.Shares {
+		for channel, list := range shares {
+			if len(list) > 0 && !utils.IsEmpty(list[0].TS) {
+				return &SlackThread{Channel: channel, TS: list[0].TS}
+			}
+		}
+	}
+	return nil
+}
+
+// getViaThread returns thread of slack message which caused event, for instance triggered gitlab pipeline
+func (s *SlackOutput) getViaThread(event *common.Event) *SlackThread {
+
+	if event.Via == nil {
+		return nil
+	}
+
+	obj, ok := event.Via[s.Name()]
 	if !ok {
-		return nil, fmt.Errorf("no alert expression")
+		return nil
 	}
 
-	caption := alert.Labels["alertname"]
-	unit := alert.Labels["unit"]
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return s.getThread(b)
+}
+
+func (s *SlackOutput) getThreadKey(event *common.Event, jsonMap map[string]interface{}) string {
 
-	var minutes *int
+	if s.store == nil {
+		return ""
+	}
 
-	if m, err := strconv.Atoi(alert.Labels["minutes"]); err == nil {
-		minutes = &m
+	if s.threadKey != nil {
+		b, err := s.threadKey.Execute(jsonMap)
+		if err == nil {
+			return strings.TrimSpace(b.String())
+		}
 	}
+	return correlationKey(event, jsonMap)
+}
+
+// callApi posts json body to Slack Web API method and checks ok field of response
+func (s *SlackOutput) callApi(spanCtx sreCommon.TracerSpanContext, token, method string, obj interface{}) ([]byte, error) {
+
+	span := s.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
 
-	expr, err := metricsql.Parse(query)
+	body, err := json.Marshal(obj)
 	if err != nil {
 		return nil, err
 	}
 
-	metric := query
-	operator := ""
-	var value *float64
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://slack.com/api/%s", method), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	binExpr, ok := expr.(*metricsql.BinaryOpExpr)
-	if binExpr != nil && ok {
-		metric = string(binExpr.Left.AppendString(nil))
-		operator = binExpr.Op
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.SpanDebug(span, "Response from Slack => %s", string(b))
+
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
 
-		if v, err := strconv.ParseFloat(string(binExpr.Right.AppendString(nil)), 64); err == nil {
-			value = &v
+	if !r.OK {
+		return nil, fmt.Errorf("slack %s failed: %s", method, r.Error)
+	}
+	return b, nil
+}
+
+// updateMessage calls chat.update for thread parent, update is a json object with text, blocks or attachments
+func (s *SlackOutput) updateMessage(spanCtx sreCommon.TracerSpanContext, token string, thread *SlackThread, update string) error {
+
+	span := s.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(update), &m); err != nil {
+		s.logger.SpanError(span, err)
+		return err
+	}
+	m["channel"] = thread.Channel
+	m["ts"] = thread.TS
+
+	if _, err := s.callApi(span.GetContext(), token, "chat.update", m); err != nil {
+		s.logger.SpanError(span, err)
+		return err
+	}
+	return nil
+}
+
+func (s *SlackOutput) threadSent(spanCtx sreCommon.TracerSpanContext, jsonMap map[string]interface{}, token, key string, thread *SlackThread, bytes []byte) {
+
+	if utils.IsEmpty(key) {
+		return
+	}
+
+	if thread == nil {
+		if t := s.getThread(bytes); t != nil {
+			s.store.Set(key, t)
 		}
+		return
 	}
 
-	if s.grafana == nil {
-		return s.sendMessage(span.GetContext(), vendors.SlackMessage{Token: token, Channel: channel, Message: message, Title: query})
+	if s.update == nil {
+		return
 	}
 
-	image, fileName, err := s.grafana.GenerateDashboard(span.GetContext(), caption, metric, operator, value, minutes, unit)
+	b, err := s.update.Execute(jsonMap)
 	if err != nil {
-		s.sendErrorMessage(span.GetContext(),
-			vendors.SlackMessage{Token: token, Channel: channel, Message: message, Title: query}, err)
-		return nil, nil
+		return
 	}
-	return s.sendImage(span.GetContext(), token, channel, message, fileName, query, image)
+
+	update := strings.TrimSpace(b.String())
+	if utils.IsEmpty(update) {
+		return
+	}
+	s.updateMessage(spanCtx, token, thread, update)
 }
 
 func (s *SlackOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event *common.Event, bytes []byte) {
@@ -197,23 +455,83 @@ func (s *SlackOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event *c
 		return
 	}
 
-	via := event.Via
-	if via == nil {
-		via = make(map[string]interface{})
+	e := event.Forward(s.Name(), obj)
+	e.SetLogger(s.logger)
+	e.SetSpanContext(span.GetContext())
+
+	s.outputs.SendForward(e, []common.Output{s}, s.options.Forward)
+}
+
+// sendChannel sends message into channel, message of the same thread key is sent one by one, so only the first
+// message becomes parent of thread
+func (s *SlackOutput) sendChannel(span sreCommon.TracerSpan, event *common.Event, jsonMap map[string]interface{},
+	token, channel, message, threadKey string, viaThread *SlackThread, actions []interface{}) {
+
+	key := ""
+	parentTS := ""
+	var thread *SlackThread
+	if !utils.IsEmpty(threadKey) {
+		key = fmt.Sprintf("%s/%s/%s", channel, threadType(event), threadKey)
+		unlock := s.store.Lock(key)
+		defer unlock()
+
+		var t SlackThread
+		if s.store.Get(key, &t) {
+			thread = &t
+			parentTS = t.TS
+		}
 	}
-	via[s.Name()] = obj
 
-	e := common.Event{
-		Time:    event.Time,
-		Channel: event.Channel,
-		Type:    event.Type,
-		Data:    event.Data,
-		Via:     via,
+	if utils.IsEmpty(parentTS) && viaThread != nil && viaThread.Channel == channel {
+		parentTS = viaThread.TS
 	}
-	e.SetLogger(s.logger)
-	e.SetSpanContext(span.GetContext())
 
-	s.outputs.SendForward(&e, []common.Output{s}, s.options.Forward)
+	switch event.Type {
+	case "AlertmanagerEvent":
+		m := vendors.SlackMessage{
+			Token:    token,
+			Channel:  channel,
+			ParentTS: parentTS,
+			Message:  message,
+			Title:    "AlertmanagerEvent",
+		}
+		bytes, err := s.sendAlertmanagerImage(span.GetContext(), token, channel, parentTS, message, event.Data.(template.Alert))
+		if err != nil {
+			s.errors.Inc(channel)
+			s.sendErrorMessage(span.GetContext(), m, err)
+		} else {
+			s.threadSent(span.GetContext(), jsonMap, token, key, thread, bytes)
+			s.sendActions(span.GetContext(), token, channel, parentTS, bytes, actions)
+			s.sendGlobally(span.GetContext(), event, bytes)
+		}
+	case "DataDogEvent":
+		var m vendors.SlackMessage
+		if err := json.Unmarshal([]byte(message), &m); err != nil {
+			s.errors.Inc(channel)
+			s.logger.SpanError(span, err)
+			return
+		}
+		m.Token = token
+		m.Channel = channel
+		m.ParentTS = parentTS
+		bytes, err := s.sendChartMessage(span.GetContext(), event.Type, jsonMap, m, actions)
+		if err != nil {
+			s.errors.Inc(channel)
+		} else {
+			s.threadSent(span.GetContext(), jsonMap, token, key, thread, bytes)
+			s.sendGlobally(span.GetContext(), event, bytes)
+		}
+	default:
+		m := prepareSlackMessage(token, channel, "", message)
+		m.ParentTS = parentTS
+		bytes, err := s.sendChartMessage(span.GetContext(), event.Type, jsonMap, m, actions)
+		if err != nil {
+			s.errors.Inc(channel)
+		} else {
+			s.threadSent(span.GetContext(), jsonMap, token, key, thread, bytes)
+			s.sendGlobally(span.GetContext(), event, bytes)
+		}
+	}
 }
 
 func (s *SlackOutput) Send(event *common.Event) {
@@ -279,6 +597,10 @@ func (s *SlackOutput) Send(event *common.Event) {
 
 		s.logger.SpanDebug(span, "Slack message => %s", message)
 
+		threadKey := s.getThreadKey(event, jsonMap)
+		viaThread := s.getViaThread(event)
+		actions := s.getActions(jsonMap)
+
 		for _, ch := range chans {
 
 			ch = strings.TrimSpace(ch)
@@ -297,46 +619,7 @@ func (s *SlackOutput) Send(event *common.Event) {
 
 			s.requests.Inc(channel)
 
-			switch event.Type {
-			case "AlertmanagerEvent":
-				m := vendors.SlackMessage{
-					Token:   token,
-					Channel: channel,
-					Message: message,
-					Title:   "AlertmanagerEvent",
-				}
-				bytes, err := s.sendAlertmanagerImage(span.GetContext(), token, channel, message, event.Data.(template.Alert))
-				if err != nil {
-					s.errors.Inc(channel)
-					s.sendErrorMessage(span.GetContext(), m, err)
-				} else {
-					s.sendGlobally(span.GetContext(), event, bytes)
-				}
-			case "DataDogEvent":
-				var m vendors.SlackMessage
-				err = json.Unmarshal([]byte(message), &m)
-				if err != nil {
-					s.errors.Inc(channel)
-					s.logger.SpanError(span, err)
-					return
-				}
-				m.Token = token
-				m.Channel = channel
-				bytes, err := s.sendMessage(span.GetContext(), m)
-				if err != nil {
-					s.errors.Inc(channel)
-				} else {
-					s.sendGlobally(span.GetContext(), event, bytes)
-				}
-			default:
-				m := prepareSlackMessage(token, channel, "", message)
-				bytes, err := s.sendMessage(span.GetContext(), m)
-				if err != nil {
-					s.errors.Inc(channel)
-				} else {
-					s.sendGlobally(span.GetContext(), event, bytes)
-				}
-			}
+			s.sendChannel(span, event, jsonMap, token, channel, message, threadKey, viaThread, actions)
 		}
 	}()
 }
@@ -372,6 +655,8 @@ func NewSlackOutput(wg *sync.WaitGroup,
 	options SlackOutputOptions,
 	templateOptions render.TextTemplateOptions,
 	grafanaRenderOptions render.GrafanaRenderOptions,
+	chartRenderOptions render.ChartRenderOptions,
+	charts *render.ChartProviders,
 	observability *common.Observability,
 	outputs *common.Outputs) *SlackOutput {
 
@@ -381,19 +666,33 @@ func NewSlackOutput(wg *sync.WaitGroup,
 		return nil
 	}
 
+	var store *common.Store
+	if options.Threads {
+		store = common.NewStore(common.StoreOptions{
+			Path: options.ThreadStore,
+			TTL:  options.ThreadTTL,
+		}, logger)
+	}
+
 	return &SlackOutput{
 		wg: wg,
 		slack: vendors.NewSlack(vendors.SlackOptions{
 			Timeout: options.Timeout,
 		}),
-		message:  render.NewTextTemplate("slack-message", options.Message, templateOptions, options, logger),
-		selector: render.NewTextTemplate("slack-selector", options.ChannelSelector, templateOptions, options, logger),
-		grafana:  render.NewGrafanaRender(grafanaRenderOptions, observability),
-		options:  options,
-		outputs:  outputs,
-		logger:   logger,
-		tracer:   observability.Traces(),
-		requests: observability.Metrics().Counter("requests", "Count of all slack requests", []string{"channel"}, "slack", "output"),
-		errors:   observability.Metrics().Counter("errors", "Count of all slack errors", []string{"channel"}, "slack", "output"),
+		client:    utils.NewHttpClient(options.Timeout, false),
+		message:   render.NewTextTemplate("slack-message", options.Message, templateOptions, options, logger),
+		selector:  render.NewTextTemplate("slack-selector", options.ChannelSelector, templateOptions, options, logger),
+		threadKey: render.NewTextTemplate("slack-thread-key", options.ThreadKey, templateOptions, options, logger),
+		update:    render.NewTextTemplate("slack-update", options.Update, templateOptions, options, logger),
+		actions:   render.NewTextTemplate("slack-actions", options.Actions, templateOptions, options, logger),
+		image:     render.NewImageRender(options.Render, grafanaRenderOptions, chartRenderOptions, templateOptions.Prometheus, observability),
+		charts:    charts,
+		store:     store,
+		options:   options,
+		outputs:   outputs,
+		logger:    logger,
+		tracer:    observability.Traces(),
+		requests:  observability.Metrics().Counter("requests", "Count of all slack requests", []string{"channel"}, "slack", "output"),
+		errors:    observability.Metrics().Counter("errors", "Count of all slack errors", []string{"channel"}, "slack", "output"),
 	}
 }
@@ -1,15 +1,18 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
+	"html"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 
-	"github.com/VictoriaMetrics/metricsql"
 	"github.com/devopsext/events/common"
 	"github.com/devopsext/events/render"
 	sreCommon "github.com/devopsext/sre/common"
@@ -25,22 +28,41 @@ type TelegramOutputOptions struct {
 	BotSelector     string
 	AlertExpression string
 	Forward         string
+	ResolveMode     string
+	ThreadKey       string
+	ThreadStore     string
+	ThreadTTL       int
+	Render          string
 }
 
 type TelegramOutput struct {
-	wg       *sync.WaitGroup
-	telegram *vendors.Telegram
-	message  *render.TextTemplate
-	selector *render.TextTemplate
-	grafana  *render.GrafanaRender
-	options  TelegramOutputOptions
-	outputs  *common.Outputs
-	tracer   sreCommon.Tracer
-	logger   sreCommon.Logger
-	requests sreCommon.Counter
-	errors   sreCommon.Counter
+	wg        *sync.WaitGroup
+	telegram  *vendors.Telegram
+	client    *http.Client
+	message   *render.TextTemplate
+	selector  *render.TextTemplate
+	threadKey *render.TextTemplate
+	image     render.ImageRender
+	charts    *render.ChartProviders
+	store     *common.Store
+	options   TelegramOutputOptions
+	outputs   *common.Outputs
+	tracer    sreCommon.Tracer
+	logger    sreCommon.Logger
+	requests  sreCommon.Counter
+	errors    sreCommon.Counter
 }
 
+// TelegramThread is the first message of key, caption is text which is added to photo message like query of alert,
+// so it's kept when message is edited
+type TelegramThread struct {
+	MessageID int    `json:"message_id"`
+	Photo     bool   `json:"photo"`
+	Caption   string `json:"caption,omitempty"`
+}
+
+const telegramApiURL = "https://api.telegram.org/bot%s/%s"
+
 func (t *TelegramOutput) Name() string {
 	return "Telegram"
 }
@@ -55,11 +77,168 @@ func (t *TelegramOutput) getBotID(IDToken string) string {
 	return ""
 }
 
-func (t *TelegramOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message string) ([]byte, error) {
+// redact hides bot token of error, http client errors contain request URL
+func (t *TelegramOutput) redact(IDToken string, err error) error {
+
+	if err == nil {
+		return nil
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), IDToken, t.getBotID(IDToken)+":***"))
+}
+
+// call sends multipart request to Telegram Bot API method, which is not supported by vendors.Telegram
+func (t *TelegramOutput) call(spanCtx sreCommon.TracerSpanContext, IDToken, method string, fields map[string]string, fileName string, file []byte) ([]byte, error) {
+
+	span := t.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(file) > 0 {
+		fw, err := w.CreateFormFile("photo", fileName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf(telegramApiURL, IDToken, method), &body)
+	if err != nil {
+		err = t.redact(IDToken, err)
+		t.logger.SpanError(span, err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		err = t.redact(IDToken, err)
+		t.logger.SpanError(span, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	t.logger.SpanDebug(span, "Response from Telegram => %s", string(b))
+
+	var r struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	if !r.OK {
+		err := fmt.Errorf("telegram %s failed: %s", method, r.Description)
+		t.logger.SpanError(span, err)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (t *TelegramOutput) fields(chatID string, replyTo int) map[string]string {
+
+	parseMode := t.options.ParseMode
+	if utils.IsEmpty(parseMode) {
+		parseMode = "HTML"
+	}
+
+	return map[string]string{
+		"chat_id":                  chatID,
+		"parse_mode":               parseMode,
+		"disable_web_page_preview": "true",
+		"disable_notification":     strconv.FormatBool(t.options.DisableNotification),
+		"reply_to_message_id":      strconv.Itoa(replyTo),
+	}
+}
+
+func (t *TelegramOutput) editMessage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID string, thread *TelegramThread, message string) ([]byte, error) {
+
+	fields := t.fields(chatID, 0)
+	delete(fields, "reply_to_message_id")
+	fields["message_id"] = strconv.Itoa(thread.MessageID)
+
+	if thread.Photo {
+		delete(fields, "disable_web_page_preview")
+		fields["caption"] = message + thread.Caption
+		return t.call(spanCtx, IDToken, "editMessageCaption", fields, "", nil)
+	}
+
+	fields["text"] = message
+	return t.call(spanCtx, IDToken, "editMessageText", fields, "", nil)
+}
+
+// getThread finds message ID in sendMessage or sendPhoto response
+func (t *TelegramOutput) getThread(b []byte) *TelegramThread {
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	var r struct {
+		OK     bool `json:"ok"`
+		Result *struct {
+			MessageID int           `json:"message_id"`
+			Photo     []interface{} `json:"photo"`
+		} `json:"result"`
+	}
+
+	if err := json.Unmarshal(b, &r); err != nil || !r.OK || r.Result == nil {
+		return nil
+	}
+
+	return &TelegramThread{
+		MessageID: r.Result.MessageID,
+		Photo:     len(r.Result.Photo) > 0,
+	}
+}
+
+func (t *TelegramOutput) getThreadKey(event *common.Event, jsonObject interface{}, jsonMap map[string]interface{}) string {
+
+	if t.store == nil {
+		return ""
+	}
+
+	if t.threadKey != nil {
+		b, err := t.threadKey.Execute(jsonObject)
+		if err == nil {
+			return strings.TrimSpace(b.String())
+		}
+	}
+	return correlationKey(event, jsonMap)
+}
+
+func (t *TelegramOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message string, replyTo int) ([]byte, error) {
 
 	span := t.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
+	if replyTo > 0 {
+		fields := t.fields(chatID, replyTo)
+		fields["text"] = message
+		return t.call(span.GetContext(), IDToken, "sendMessage", fields, "", nil)
+	}
+
 	b, err := t.telegram.SendCustomMessage(vendors.TelegramOptions{
 		IDToken:               IDToken,
 		ChatID:                chatID,
@@ -74,6 +253,7 @@ func (t *TelegramOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, IDToke
 	})
 
 	if err != nil {
+		err = t.redact(IDToken, err)
 		t.logger.SpanError(span, err)
 		return nil, err
 	}
@@ -84,16 +264,23 @@ func (t *TelegramOutput) sendMessage(spanCtx sreCommon.TracerSpanContext, IDToke
 
 func (t *TelegramOutput) sendErrorMessage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message string, err error) error {
 
-	_, e := t.sendMessage(spanCtx, IDToken, chatID, fmt.Sprintf("%s\n%s", message, err.Error()))
+	_, e := t.sendMessage(spanCtx, IDToken, chatID, fmt.Sprintf("%s\n%s", message, err.Error()), 0)
 	return e
 }
 
-func (t *TelegramOutput) sendPhoto(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message, fileName string, photo []byte) ([]byte, error) {
+func (t *TelegramOutput) sendPhoto(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message, fileName string, photo []byte, replyTo int) ([]byte, error) {
 
 	span := t.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
-	return t.telegram.SendCustomPhoto(vendors.TelegramOptions{
+	if replyTo > 0 {
+		fields := t.fields(chatID, replyTo)
+		delete(fields, "disable_web_page_preview")
+		fields["caption"] = message
+		return t.call(span.GetContext(), IDToken, "sendPhoto", fields, fileName, photo)
+	}
+
+	b, err := t.telegram.SendCustomPhoto(vendors.TelegramOptions{
 		IDToken:               IDToken,
 		ChatID:                chatID,
 		Timeout:               t.options.Timeout,
@@ -107,68 +294,59 @@ func (t *TelegramOutput) sendPhoto(spanCtx sreCommon.TracerSpanContext, IDToken,
 			Content: string(photo),
 		},
 	})
+	return b, t.redact(IDToken, err)
 }
 
-func (t *TelegramOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message string, alert template.Alert) ([]byte, error) {
+// sendChartMessage sends chart of event as photo with message if event type has chart provider, caption added to
+// message is returned
+func (t *TelegramOutput) sendChartMessage(spanCtx sreCommon.TracerSpanContext, eventType string, jsonMap map[string]interface{},
+	IDToken, chatID, message string, replyTo int) ([]byte, string, error) {
 
 	span := t.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
-	u, err := url.Parse(alert.GeneratorURL)
-	if err != nil {
-		return nil, err
-	}
-
-	values := u.Query()
-	for k, v := range values {
-		alert.Labels[k] = strings.Join(v, " ")
+	chart := t.charts.Chart(span.GetContext(), eventType, jsonMap)
+	if chart == nil {
+		b, err := t.sendMessage(span.GetContext(), IDToken, chatID, message, replyTo)
+		return b, "", err
 	}
 
-	query, ok := alert.Labels[t.options.AlertExpression]
-	if !ok {
-		err := errors.New("No alert expression")
-		return nil, err
+	caption := ""
+	if !utils.IsEmpty(chart.Caption) {
+		caption = fmt.Sprintf("\n<i>%s</i>", html.EscapeString(chart.Caption))
 	}
+	b, err := t.sendPhoto(span.GetContext(), IDToken, chatID, message+caption, chart.FileName, chart.Image, replyTo)
+	return b, caption, err
+}
 
-	caption := alert.Labels["alertname"]
-	unit := alert.Labels["unit"]
-
-	var minutes *int
+// sendAlertmanagerImage sends image of alert with message, caption with query added to message is returned
+func (t *TelegramOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, IDToken, chatID, message string, alert template.Alert, replyTo int) ([]byte, string, error) {
 
-	if m, err := strconv.Atoi(alert.Labels["minutes"]); err == nil {
-		minutes = &m
-	}
+	span := t.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
 
-	expr, err := metricsql.Parse(query)
+	request, query, err := alertImageRequest(alert, t.options.AlertExpression)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	metric := query
-	operator := ""
-	var value *float64
-
-	binExpr, ok := expr.(*metricsql.BinaryOpExpr)
-	if binExpr != nil && ok {
-		metric = string(binExpr.Left.AppendString(nil))
-		operator = binExpr.Op
-
-		if v, err := strconv.ParseFloat(string(binExpr.Right.AppendString(nil)), 64); err == nil {
-			value = &v
-		}
+	caption := ""
+	if !utils.IsEmpty(query) {
+		caption = fmt.Sprintf("\n<i>%s</i>", query)
 	}
 
-	messageQuery := fmt.Sprintf("%s\n<i>%s</i>", message, query)
-	if t.grafana == nil {
-		return t.sendMessage(span.GetContext(), IDToken, chatID, messageQuery)
+	if t.image == nil {
+		b, err := t.sendMessage(span.GetContext(), IDToken, chatID, message+caption, replyTo)
+		return b, caption, err
 	}
 
-	image, fileName, err := t.grafana.GenerateDashboard(span.GetContext(), caption, metric, operator, value, minutes, unit)
+	image, fileName, err := t.image.Render(span.GetContext(), request)
 	if err != nil {
-		t.sendErrorMessage(span.GetContext(), IDToken, chatID, messageQuery, err)
-		return nil, nil
+		t.sendErrorMessage(span.GetContext(), IDToken, chatID, message+caption, err)
+		return nil, "", nil
 	}
-	return t.sendPhoto(span.GetContext(), IDToken, chatID, messageQuery, fileName, image)
+	b, err := t.sendPhoto(span.GetContext(), IDToken, chatID, message+caption, fileName, image, replyTo)
+	return b, caption, err
 }
 
 func (t *TelegramOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event *common.Event, bytes []byte) {
@@ -190,23 +368,77 @@ func (t *TelegramOutput) sendGlobally(spanCtx sreCommon.TracerSpanContext, event
 		return
 	}
 
-	via := event.Via
-	if via == nil {
-		via = make(map[string]interface{})
+	e := event.Forward(t.Name(), obj)
+	e.SetLogger(t.logger)
+	e.SetSpanContext(span.GetContext())
+
+	t.outputs.SendForward(e, []common.Output{t}, t.options.Forward)
+}
+
+// sendChat sends message into chat, messages of the same thread key are sent one by one, so only the first message
+// is kept as thread. Resolved event edits the first message in edit mode, other events reply to it
+func (t *TelegramOutput) sendChat(span sreCommon.TracerSpan, event *common.Event, jsonMap map[string]interface{},
+	IDToken, chatID, botID, message, threadKey string) {
+
+	key := ""
+	var thread *TelegramThread
+	if !utils.IsEmpty(threadKey) {
+		key = fmt.Sprintf("%s/%s/%s/%s", botID, chatID, threadType(event), threadKey)
+		unlock := t.store.Lock(key)
+		defer unlock()
+
+		var th TelegramThread
+		if t.store.Get(key, &th) {
+			thread = &th
+		}
 	}
-	via[t.Name()] = obj
 
-	e := common.Event{
-		Time:    event.Time,
-		Channel: event.Channel,
-		Type:    event.Type,
-		Data:    event.Data,
-		Via:     via,
+	edit := strings.ToLower(t.options.ResolveMode) == "edit" && event.Status == common.StatusResolved
+	if thread != nil && edit {
+		bytes, err := t.editMessage(span.GetContext(), IDToken, chatID, thread, message)
+		if err != nil {
+			t.errors.Inc(botID, chatID)
+		} else {
+			// thread is resolved, next firing starts new one
+			t.store.Delete(key)
+			t.sendGlobally(span.GetContext(), event, bytes)
+		}
+		return
+	}
+
+	replyTo := 0
+	if thread != nil {
+		replyTo = thread.MessageID
+	}
+
+	var bytes []byte
+	var caption string
+	var err error
+	switch event.Type {
+	case "AlertmanagerEvent":
+		bytes, caption, err = t.sendAlertmanagerImage(span.GetContext(), IDToken, chatID, message, event.Data.(template.Alert), replyTo)
+		if err != nil {
+			t.errors.Inc(botID, chatID)
+			t.sendErrorMessage(span.GetContext(), IDToken, chatID, message, err)
+			return
+		}
+	default:
+		bytes, caption, err = t.sendChartMessage(span.GetContext(), event.Type, jsonMap, IDToken, chatID, message, replyTo)
+		if err != nil {
+			t.errors.Inc(botID, chatID)
+			return
+		}
 	}
-	e.SetLogger(t.logger)
-	e.SetSpanContext(span.GetContext())
 
-	t.outputs.SendForward(&e, []common.Output{t}, t.options.Forward)
+	if event.Status == common.StatusResolved && !utils.IsEmpty(key) {
+		t.store.Delete(key)
+	} else if thread == nil && !utils.IsEmpty(key) {
+		if th := t.getThread(bytes); th != nil {
+			th.Caption = caption
+			t.store.Set(key, th)
+		}
+	}
+	t.sendGlobally(span.GetContext(), event, bytes)
 }
 
 func (t *TelegramOutput) Send(event *common.Event) {
@@ -239,6 +471,8 @@ func (t *TelegramOutput) Send(event *common.Event) {
 			return
 		}
 
+		jsonMap, _ := jsonObject.(map[string]interface{})
+
 		IDTokenChatIDs := ""
 		if !utils.IsEmpty(t.options.IDToken) && !utils.IsEmpty(t.options.ChatID) {
 			IDTokenChatIDs = fmt.Sprintf("%s=%s", t.options.IDToken, t.options.ChatID)
@@ -272,6 +506,8 @@ func (t *TelegramOutput) Send(event *common.Event) {
 
 		t.logger.SpanDebug(span, "Telegram message => %s", message)
 
+		threadKey := t.getThreadKey(event, jsonObject, jsonMap)
+
 		list := strings.Split(IDTokenChatIDs, "\n")
 		for _, IDTokenChatID := range list {
 
@@ -289,23 +525,7 @@ func (t *TelegramOutput) Send(event *common.Event) {
 
 			t.requests.Inc(botID, chatID)
 
-			switch event.Type {
-			case "AlertmanagerEvent":
-				bytes, err := t.sendAlertmanagerImage(span.GetContext(), IDToken, chatID, message, event.Data.(template.Alert))
-				if err != nil {
-					t.errors.Inc(botID, chatID)
-					t.sendErrorMessage(span.GetContext(), IDToken, chatID, message, err)
-				} else {
-					t.sendGlobally(span.GetContext(), event, bytes)
-				}
-			default:
-				bytes, err := t.sendMessage(span.GetContext(), IDToken, chatID, message)
-				if err != nil {
-					t.errors.Inc(botID, chatID)
-				} else {
-					t.sendGlobally(span.GetContext(), event, bytes)
-				}
-			}
+			t.sendChat(span, event, jsonMap, IDToken, chatID, botID, message, threadKey)
 		}
 	}()
 }
@@ -314,6 +534,8 @@ func NewTelegramOutput(wg *sync.WaitGroup,
 	options TelegramOutputOptions,
 	templateOptions render.TextTemplateOptions,
 	grafanaRenderOptions render.GrafanaRenderOptions,
+	chartRenderOptions render.ChartRenderOptions,
+	charts *render.ChartProviders,
 	observability *common.Observability,
 	outputs *common.Outputs) *TelegramOutput {
 
@@ -323,17 +545,29 @@ func NewTelegramOutput(wg *sync.WaitGroup,
 		return nil
 	}
 
+	var store *common.Store
+	if !utils.IsEmpty(options.ResolveMode) {
+		store = common.NewStore(common.StoreOptions{
+			Path: options.ThreadStore,
+			TTL:  options.ThreadTTL,
+		}, logger)
+	}
+
 	return &TelegramOutput{
-		wg:       wg,
-		telegram: vendors.NewTelegram(options.TelegramOptions),
-		message:  render.NewTextTemplate("telegram-message", options.Message, templateOptions, options, logger),
-		selector: render.NewTextTemplate("telegram-selector", options.BotSelector, templateOptions, options, logger),
-		grafana:  render.NewGrafanaRender(grafanaRenderOptions, observability),
-		options:  options,
-		outputs:  outputs,
-		logger:   logger,
-		tracer:   observability.Traces(),
-		requests: observability.Metrics().Counter("requests", "Count of all telegram requests", []string{"bot_id", "chat_id"}, "telegram", "output"),
-		errors:   observability.Metrics().Counter("errors", "Count of all telegram errors", []string{"bot_id", "chat_id"}, "telegram", "output"),
+		wg:        wg,
+		telegram:  vendors.NewTelegram(options.TelegramOptions),
+		client:    utils.NewHttpClient(options.Timeout, options.Insecure),
+		message:   render.NewTextTemplate("telegram-message", options.Message, templateOptions, options, logger),
+		selector:  render.NewTextTemplate("telegram-selector", options.BotSelector, templateOptions, options, logger),
+		threadKey: render.NewTextTemplate("telegram-thread-key", options.ThreadKey, templateOptions, options, logger),
+		image:     render.NewImageRender(options.Render, grafanaRenderOptions, chartRenderOptions, templateOptions.Prometheus, observability),
+		charts:    charts,
+		store:     store,
+		options:   options,
+		outputs:   outputs,
+		logger:    logger,
+		tracer:    observability.Traces(),
+		requests:  observability.Metrics().Counter("requests", "Count of all telegram requests", []string{"bot_id", "chat_id"}, "telegram", "output"),
+		errors:    observability.Metrics().Counter("errors", "Count of all telegram errors", []string{"bot_id", "chat_id"}, "telegram", "output"),
 	}
 }
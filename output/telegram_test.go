@@ -0,0 +1,217 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sre "github.com/devopsext/sre/common"
+)
+
+type telegramCall struct {
+	Method string
+	Fields map[string]string
+}
+
+// telegramStandIn is Bot API stand-in, requests to api.telegram.org are redirected to it
+type telegramStandIn struct {
+	server *httptest.Server
+	mutex  sync.Mutex
+	calls  []*telegramCall
+}
+
+func (s *telegramStandIn) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	u, _ := url.Parse(s.server.URL)
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTelegramStandIn(t *testing.T) *telegramStandIn {
+
+	s := &telegramStandIn{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+		}
+		c := &telegramCall{Method: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], Fields: make(map[string]string)}
+		for k, v := range r.MultipartForm.Value {
+			c.Fields[k] = v[0]
+		}
+		s.mutex.Lock()
+		s.calls = append(s.calls, c)
+		s.mutex.Unlock()
+
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":20}}`)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *telegramStandIn) sent() []*telegramCall {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls
+}
+
+func TestTelegramOutputEditOnResolve(t *testing.T) {
+
+	api := newTelegramStandIn(t)
+
+	wg := &sync.WaitGroup{}
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	options := TelegramOutputOptions{
+		Message:     "{{.data.name}} is {{.data.status}}",
+		ResolveMode: "edit",
+		ThreadKey:   "{{.data.name}}",
+		ThreadTTL:   60,
+	}
+	options.IDToken = "1:token"
+	options.ChatID = "-100"
+	options.Timeout = 5
+
+	outputs := common.NewOutputs(observability.Logs())
+	tg := NewTelegramOutput(wg, options, render.TextTemplateOptions{}, render.GrafanaRenderOptions{},
+		render.ChartRenderOptions{}, nil, observability, &outputs)
+	if tg == nil {
+		t.Fatal("telegram output is not created")
+	}
+	tg.client.Transport = api
+
+	tg.store.Set("1/-100/TestEvent/disk", &TelegramThread{MessageID: 10, Photo: true, Caption: "\n<i>up == 0</i>"})
+
+	send := func(status string) {
+		e := &common.Event{
+			Channel: "test",
+			Type:    "TestEvent",
+			Status:  status,
+			Data:    map[string]interface{}{"name": "disk", "status": status},
+		}
+		tg.Send(e)
+		wg.Wait()
+	}
+
+	send("firing")
+	send(common.StatusResolved)
+
+	calls := api.sent()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+
+	reply := calls[0]
+	if reply.Method != "sendMessage" || reply.Fields["reply_to_message_id"] != "10" || reply.Fields["text"] != "disk is firing" {
+		t.Errorf("firing event doesn't reply to thread: %s %v", reply.Method, reply.Fields)
+	}
+
+	edit := calls[1]
+	if edit.Method != "editMessageCaption" || edit.Fields["message_id"] != "10" {
+		t.Errorf("resolved event doesn't edit thread: %s %v", edit.Method, edit.Fields)
+	}
+	if edit.Fields["caption"] != "disk is resolved\n<i>up == 0</i>" {
+		t.Errorf("caption of thread is lost: %q", edit.Fields["caption"])
+	}
+
+	var th TelegramThread
+	if tg.store.Get("1/-100/TestEvent/disk", &th) {
+		t.Errorf("resolved thread is kept: %+v", th)
+	}
+}
+
+type telegramFailure struct{}
+
+func (telegramFailure) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTelegramOutputRedact(t *testing.T) {
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	options := TelegramOutputOptions{Message: "{{.data}}"}
+	options.IDToken = "1:secret"
+	options.ChatID = "-100"
+
+	outputs := common.NewOutputs(observability.Logs())
+	tg := NewTelegramOutput(&sync.WaitGroup{}, options, render.TextTemplateOptions{}, render.GrafanaRenderOptions{},
+		render.ChartRenderOptions{}, nil, observability, &outputs)
+	tg.client.Transport = telegramFailure{}
+
+	// error of http client contains URL with bot token
+	_, err := tg.call(nil, options.IDToken, "sendMessage", tg.fields(options.ChatID, 10), "", nil)
+	if err == nil {
+		t.Fatal("failed call doesn't return error")
+	}
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "bot1:***/sendMessage") {
+		t.Errorf("bot token isn't redacted: %v", err)
+	}
+}
+
+func TestTelegramOutputForward(t *testing.T) {
+
+	api := newTelegramStandIn(t)
+
+	wg := &sync.WaitGroup{}
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	options := TelegramOutputOptions{
+		Message:     "{{.data.reason}}",
+		Forward:     "Test",
+		ResolveMode: "reply",
+		ThreadKey:   "pod",
+		ThreadTTL:   60,
+	}
+	options.IDToken = "1:token"
+	options.ChatID = "-100"
+	options.Timeout = 5
+
+	outputs := common.NewOutputs(observability.Logs())
+	recorder := &testOutput{}
+	outputs.Add(recorder)
+
+	tg := NewTelegramOutput(wg, options, render.TextTemplateOptions{}, render.GrafanaRenderOptions{},
+		render.ChartRenderOptions{}, nil, observability, &outputs)
+	tg.client.Transport = api
+	tg.store.Set("1/-100/K8sEvent/pod", &TelegramThread{MessageID: 10})
+
+	// cloud event of other instance keeps its attributes in via
+	via := map[string]interface{}{"CloudEvents": map[string]interface{}{"source": "events"}}
+	e := &common.Event{
+		ID:      "1",
+		Channel: "cloudevents",
+		Type:    "K8sEvent",
+		Data:    map[string]interface{}{"reason": "Killing"},
+		Via:     via,
+	}
+	e.SetAlert("pod", common.StatusFiring, "warning")
+	tg.Send(e)
+	wg.Wait()
+
+	calls := api.sent()
+	if len(calls) != 1 || calls[0].Fields["text"] != "Killing" {
+		t.Fatalf("event isn't sent as message: %v", calls)
+	}
+
+	events := recorder.sent()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 forwarded event, got %d", len(events))
+	}
+	f := events[0]
+	if _, ok := f.Via[tg.Name()]; !ok {
+		t.Errorf("via of forwarded event doesn't have telegram: %v", f.Via)
+	}
+	if _, ok := f.Via["CloudEvents"]; !ok || f.ID != "1" || f.Key != "pod" || f.Status != common.StatusFiring {
+		t.Errorf("forwarded event isn't copy of original: %+v", f)
+	}
+	if len(via) != 1 {
+		t.Errorf("via of original event is changed: %v", via)
+	}
+}
@@ -0,0 +1,164 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// execute runs template on object, result is trimmed, def is returned if template isn't set, fails or result is empty
+func execute(t *render.TextTemplate, object interface{}, def string) string {
+
+	if t == nil {
+		return def
+	}
+
+	b, err := t.Execute(object)
+	if err != nil {
+		return def
+	}
+
+	s := strings.TrimSpace(b.String())
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// truncate cuts plain text to limit of characters including ellipsis, text isn't markup, so it's cut by runes only
+func truncate(limit int, s string) string {
+
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+	return string(r[:limit-1]) + "…"
+}
+
+// jsonMapPath walks json map by dot separated path like data.alert.id
+func jsonMapPath(m map[string]interface{}, path string) string {
+
+	var v interface{} = m
+	for _, k := range strings.Split(path, ".") {
+		mm, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = mm[k]
+	}
+
+	if v == nil {
+		return ""
+	}
+
+	if f, ok := v.(float64); ok {
+		return fmt.Sprintf("%.0f", f)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// correlationKey returns key of event set by processor, vendor specific identity of alert or pipeline is used for events
+// which come without key
+func correlationKey(event *common.Event, jsonMap map[string]interface{}) string {
+
+	if event.Key != "" {
+		return event.Key
+	}
+
+	switch event.Type {
+	case "AlertmanagerEvent":
+		return jsonMapPath(jsonMap, "data.fingerprint")
+	case "AlertmanagerGroupEvent":
+		return jsonMapPath(jsonMap, "data.groupKey")
+	case "DataDogEvent":
+		return jsonMapPath(jsonMap, "data.alert.id")
+	case "GoogleEvent":
+		return jsonMapPath(jsonMap, "data.incident.incident_id")
+	case "GitlabEvent":
+		switch jsonMapPath(jsonMap, "data.object_kind") {
+		case "pipeline":
+			return jsonMapPath(jsonMap, "data.object_attributes.id")
+		case "build", "job":
+			return jsonMapPath(jsonMap, "data.pipeline_id")
+		}
+	}
+	return ""
+}
+
+// threadType returns type of event which thread belongs to, result of triggered pipeline is replied to thread of
+// event which triggered it
+func threadType(event *common.Event) string {
+
+	if r, ok := event.Data.(*GitlabPipelineResult); ok && r.Event != nil {
+		return r.Event.Type
+	}
+	return event.Type
+}
+
+// alertImageRequest builds image request from alert, expression label is split into metric, operator and threshold.
+// Expression can be omitted if alert refers to existing Grafana panel
+func alertImageRequest(alert template.Alert, expression string) (*render.ImageRequest, string, error) {
+
+	u, err := url.Parse(alert.GeneratorURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	labels := make(map[string]string)
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	for k, v := range u.Query() {
+		labels[k] = strings.Join(v, " ")
+	}
+
+	request := &render.ImageRequest{
+		Title:        labels["alertname"],
+		Unit:         labels["unit"],
+		Labels:       labels,
+		Annotations:  alert.Annotations,
+		GeneratorURL: alert.GeneratorURL,
+		StartsAt:     alert.StartsAt,
+		EndsAt:       alert.EndsAt,
+	}
+
+	if m, err := strconv.Atoi(labels["minutes"]); err == nil {
+		request.Minutes = &m
+	}
+
+	query, ok := labels[expression]
+	if !ok {
+		if request.HasPanel() {
+			return request, "", nil
+		}
+		return nil, "", errors.New("no alert expression")
+	}
+
+	expr, err := metricsql.Parse(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	request.Metric = query
+
+	binExpr, ok := expr.(*metricsql.BinaryOpExpr)
+	if binExpr != nil && ok {
+		request.Metric = string(binExpr.Left.AppendString(nil))
+		request.Operator = binExpr.Op
+
+		if v, err := strconv.ParseFloat(string(binExpr.Right.AppendString(nil)), 64); err == nil {
+			request.Value = &v
+		}
+	}
+	return request, query, nil
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+func TestAlertImageRequest(t *testing.T) {
+
+	tests := []struct {
+		expr     string
+		metric   string
+		operator string
+		value    *float64
+	}{
+		{`rate(http_requests_total{code="500"}[5m]) > 0.5`, `rate(http_requests_total{code="500"}[5m])`, ">", floatPtr(0.5)},
+		{`up{job="api"} == 0`, `up{job="api"}`, "==", floatPtr(0)},
+		{`node_filesystem_free_bytes <= 1e+09`, `node_filesystem_free_bytes`, "<=", floatPtr(1e9)},
+		{`errors > on(job) limits`, `errors`, ">", nil},
+		{`sum(rate(requests[1m]))`, `sum(rate(requests[1m]))`, "", nil},
+	}
+
+	for _, tt := range tests {
+
+		alert := template.Alert{
+			Labels:       template.KV{"alertname": "Test", "expr": tt.expr, "unit": "percent"},
+			GeneratorURL: "http://prometheus/graph?g0.expr=up&minutes=30",
+		}
+
+		request, query, err := alertImageRequest(alert, "expr")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if query != tt.expr || request.Metric != tt.metric || request.Operator != tt.operator {
+			t.Fatalf("%s: unexpected metric %q and operator %q", tt.expr, request.Metric, request.Operator)
+		}
+		if (request.Value == nil) != (tt.value == nil) || (tt.value != nil && *request.Value != *tt.value) {
+			t.Fatalf("%s: unexpected threshold %v", tt.expr, request.Value)
+		}
+		if request.Title != "Test" || request.Unit != "percent" || request.Minutes == nil || *request.Minutes != 30 {
+			t.Fatalf("%s: unexpected request %+v", tt.expr, request)
+		}
+	}
+
+	alert := template.Alert{Labels: template.KV{"alertname": "Test", "expr": "rate(x[5m] > 1"}}
+	if _, _, err := alertImageRequest(alert, "expr"); err == nil {
+		t.Fatal("expected invalid expression to fail")
+	}
+
+	alert = template.Alert{Labels: template.KV{"alertname": "Test"}}
+	if _, _, err := alertImageRequest(alert, "expr"); err == nil {
+		t.Fatal("expected alert without expression and panel to fail")
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/devopsext/events/common"
+	"github.com/devopsext/events/render"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type WebhookOutputOptions struct {
+	URL               string
+	Message           string
+	Timeout           int
+	Insecure          bool
+	CloudEventsSource string
+	Token             string
+}
+
+// WebhookOutput posts rendered message or data of event to URLs, they are sent as CloudEvents in binary content mode
+// if source is defined
+type WebhookOutput struct {
+	wg       *sync.WaitGroup
+	client   *http.Client
+	message  *render.TextTemplate
+	options  WebhookOutputOptions
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+func (w *WebhookOutput) Name() string {
+	return "Webhook"
+}
+
+func (w *WebhookOutput) getData(event *common.Event) ([]byte, error) {
+
+	if w.message == nil {
+		return json.Marshal(event.Data)
+	}
+
+	jsonObject, err := event.JsonObject()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := w.message.Execute(jsonObject)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSpace(b.String())), nil
+}
+
+func (w *WebhookOutput) post(span sreCommon.TracerSpan, URL string, header http.Header, data []byte) error {
+
+	req, err := http.NewRequest("POST", URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded %d: %s", URL, resp.StatusCode, b)
+	}
+	w.logger.SpanDebug(span, "Webhook %s response => %s", URL, b)
+	return nil
+}
+
+func (w *WebhookOutput) Send(event *common.Event) {
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		if event == nil {
+			w.logger.Debug("Event is empty")
+			return
+		}
+
+		span := w.tracer.StartFollowSpan(event.GetSpanContext())
+		defer span.Finish()
+
+		if event.Data == nil {
+			w.logger.SpanError(span, "Event data is empty")
+			return
+		}
+
+		data, err := w.getData(event)
+		if err != nil {
+			w.logger.SpanError(span, err)
+			return
+		}
+
+		if len(data) == 0 {
+			w.logger.SpanDebug(span, "Webhook message is empty")
+			return
+		}
+
+		w.logger.SpanDebug(span, "Webhook message => %s", data)
+
+		header := http.Header{}
+		header.Set("Content-Type", "text/plain")
+		if json.Valid(data) {
+			header.Set("Content-Type", "application/json")
+		}
+		span.SetCarrier(header)
+
+		if !utils.IsEmpty(w.options.Token) {
+			header.Set("Authorization", fmt.Sprintf("Bearer %s", w.options.Token))
+		}
+
+		if !utils.IsEmpty(w.options.CloudEventsSource) {
+
+			ce, err := common.NewCloudEvent(event, w.options.CloudEventsSource, data, span.GetContext())
+			if err != nil {
+				w.logger.SpanError(span, err)
+				return
+			}
+			for k, v := range ce.Attributes("ce-", "Content-Type") {
+				header.Set(k, v)
+			}
+			if tp, ok := ce.Extensions["traceparent"]; ok {
+				header.Set("traceparent", tp)
+			}
+		}
+
+		for _, URL := range strings.Split(w.options.URL, ",") {
+
+			URL = strings.TrimSpace(URL)
+			if utils.IsEmpty(URL) {
+				continue
+			}
+
+			w.requests.Inc(URL)
+			if err := w.post(span, URL, header.Clone(), data); err != nil {
+				w.errors.Inc(URL)
+				w.logger.SpanError(span, err)
+			}
+		}
+	}()
+}
+
+func NewWebhookOutput(wg *sync.WaitGroup,
+	options WebhookOutputOptions,
+	templateOptions render.TextTemplateOptions,
+	observability *common.Observability) *WebhookOutput {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.URL) {
+		logger.Debug("Webhook output URL is not defined. Skipped")
+		return nil
+	}
+
+	var message *render.TextTemplate
+	if !utils.IsEmpty(options.Message) {
+		message = render.NewTextTemplate("webhook-message", options.Message, templateOptions, options, logger)
+	}
+
+	return &WebhookOutput{
+		wg:       wg,
+		client:   utils.NewHttpClient(options.Timeout, options.Insecure),
+		message:  message,
+		options:  options,
+		logger:   logger,
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all webhook requests", []string{"url"}, "webhook", "output"),
+		errors:   observability.Metrics().Counter("errors", "Count of all webhook errors", []string{"url"}, "webhook", "output"),
+	}
+}
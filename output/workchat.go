@@ -3,7 +3,6 @@ package output
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,13 +10,11 @@ import (
 	"net/http"
 	"net/textproto"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 
 	sreCommon "github.com/devopsext/sre/common"
 
-	"github.com/VictoriaMetrics/metricsql"
 	"github.com/devopsext/events/common"
 	"github.com/devopsext/events/render"
 	"github.com/devopsext/utils"
@@ -31,6 +28,7 @@ type WorkchatOutputOptions struct {
 	Timeout          int
 	AlertExpression  string
 	NotificationType string
+	Render           string
 }
 
 type WorkchatOutput struct {
@@ -38,7 +36,8 @@ type WorkchatOutput struct {
 	client   *http.Client
 	message  *render.TextTemplate
 	selector *render.TextTemplate
-	grafana  *render.GrafanaRender
+	image    render.ImageRender
+	charts   *render.ChartProviders
 	options  WorkchatOutputOptions
 	tracer   sreCommon.Tracer
 	logger   sreCommon.Logger
@@ -205,62 +204,43 @@ func (w *WorkchatOutput) sendPhoto(spanCtx sreCommon.TracerSpanContext, URL, mes
 	return w.sendMessage(span.GetContext(), URL, message)
 }
 
-func (w *WorkchatOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, URL, message string, alert template.Alert) error {
+// sendChartMessage sends chart of event as photo with message if event type has chart provider
+func (w *WorkchatOutput) sendChartMessage(spanCtx sreCommon.TracerSpanContext, eventType string, jsonMap map[string]interface{}, URL, message string) error {
 
 	span := w.tracer.StartChildSpan(spanCtx)
 	defer span.Finish()
 
-	u, err := url.Parse(alert.GeneratorURL)
-	if err != nil {
-		return err
-	}
-
-	values := u.Query()
-	for k, v := range values {
-		alert.Labels[k] = strings.Join(v, " ")
+	chart := w.charts.Chart(span.GetContext(), eventType, jsonMap)
+	if chart == nil {
+		return w.sendMessage(span.GetContext(), URL, message)
 	}
 
-	query, ok := alert.Labels[w.options.AlertExpression]
-	if !ok {
-		err := errors.New("No alert expression")
-		return err
+	if !utils.IsEmpty(chart.Caption) {
+		message = fmt.Sprintf("%s\n_%s_", message, chart.Caption)
 	}
+	return w.sendPhoto(span.GetContext(), URL, message, chart.FileName, chart.Image)
+}
 
-	caption := alert.Labels["alertname"]
-	unit := alert.Labels["unit"]
-
-	var minutes *int
+func (w *WorkchatOutput) sendAlertmanagerImage(spanCtx sreCommon.TracerSpanContext, URL, message string, alert template.Alert) error {
 
-	if m, err := strconv.Atoi(alert.Labels["minutes"]); err == nil {
-		minutes = &m
-	}
+	span := w.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
 
-	expr, err := metricsql.Parse(query)
+	request, query, err := alertImageRequest(alert, w.options.AlertExpression)
 	if err != nil {
 		return err
 	}
 
-	metric := query
-	operator := ""
-	var value *float64
-
-	binExpr, ok := expr.(*metricsql.BinaryOpExpr)
-	if binExpr != nil && ok {
-		metric = string(binExpr.Left.AppendString(nil))
-		operator = binExpr.Op
-
-		if v, err := strconv.ParseFloat(string(binExpr.Right.AppendString(nil)), 64); err == nil {
-			value = &v
-		}
+	messageQuery := message
+	if !utils.IsEmpty(query) {
+		messageQuery = fmt.Sprintf("%s\n_%s_", message, query)
 	}
 
-	messageQuery := fmt.Sprintf("%s\n_%s_", message, query)
-
-	if w.grafana == nil {
+	if w.image == nil {
 		return w.sendMessage(span.GetContext(), URL, messageQuery)
 	}
 
-	photo, fileName, err := w.grafana.GenerateDashboard(span.GetContext(), caption, metric, operator, value, minutes, unit)
+	photo, fileName, err := w.image.Render(span.GetContext(), request)
 	if err != nil {
 		w.sendErrorMessage(span.GetContext(), URL, messageQuery, err)
 		return nil
@@ -293,7 +273,7 @@ func (w *WorkchatOutput) Send(event *common.Event) {
 			return
 		}
 
-		jsonObject, err := event.JsonObject()
+		jsonMap, err := event.JsonMap()
 		if err != nil {
 			w.logger.SpanError(span, err)
 			return
@@ -302,7 +282,7 @@ func (w *WorkchatOutput) Send(event *common.Event) {
 		URLs := w.options.URL
 		if w.selector != nil {
 
-			b, err := w.selector.Execute(jsonObject)
+			b, err := w.selector.Execute(jsonMap)
 			if err != nil {
 				w.logger.SpanDebug(span, err)
 			} else {
@@ -315,7 +295,7 @@ func (w *WorkchatOutput) Send(event *common.Event) {
 			return
 		}
 
-		b, err := w.message.Execute(jsonObject)
+		b, err := w.message.Execute(jsonMap)
 		if err != nil {
 			w.logger.SpanError(span, err)
 			return
@@ -348,7 +328,7 @@ func (w *WorkchatOutput) Send(event *common.Event) {
 					w.sendErrorMessage(span.GetContext(), URL, message, err)
 				}
 			default:
-				err := w.sendMessage(span.GetContext(), URL, message)
+				err := w.sendChartMessage(span.GetContext(), event.Type, jsonMap, URL, message)
 				if err != nil {
 					w.errors.Inc(thread)
 				}
@@ -361,6 +341,8 @@ func NewWorkchatOutput(wg *sync.WaitGroup,
 	options WorkchatOutputOptions,
 	templateOptions render.TextTemplateOptions,
 	grafanaRenderOptions render.GrafanaRenderOptions,
+	chartRenderOptions render.ChartRenderOptions,
+	charts *render.ChartProviders,
 	observability *common.Observability) *WorkchatOutput {
 
 	logger := observability.Logs()
@@ -374,7 +356,8 @@ func NewWorkchatOutput(wg *sync.WaitGroup,
 		client:   utils.NewHttpInsecureClient(options.Timeout),
 		message:  render.NewTextTemplate("workchat-message", options.Message, templateOptions, options, logger),
 		selector: render.NewTextTemplate("workchat-selector", options.URLSelector, templateOptions, options, logger),
-		grafana:  render.NewGrafanaRender(grafanaRenderOptions, observability),
+		image:    render.NewImageRender(options.Render, grafanaRenderOptions, chartRenderOptions, templateOptions.Prometheus, observability),
+		charts:   charts,
 		options:  options,
 		tracer:   observability.Traces(),
 		logger:   logger,
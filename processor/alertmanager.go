@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
@@ -14,7 +15,13 @@ import (
 	"github.com/prometheus/alertmanager/template"
 )
 
+type AlertmanagerProcessorOptions struct {
+	Alerts bool
+	Group  bool
+}
+
 type AlertmanagerProcessor struct {
+	options  AlertmanagerProcessorOptions
 	outputs  *common.Outputs
 	tracer   sreCommon.Tracer
 	logger   sreCommon.Logger
@@ -22,6 +29,14 @@ type AlertmanagerProcessor struct {
 	errors   sreCommon.Counter
 }
 
+// AlertmanagerWebhookMessage is webhook payload with fields which template.Data doesn't have
+type AlertmanagerWebhookMessage struct {
+	template.Data
+	Version         string `json:"version"`
+	GroupKey        string `json:"groupKey"`
+	TruncatedAlerts int    `json:"truncatedAlerts"`
+}
+
 type AlertmanagerResponse struct {
 	Message string
 }
@@ -34,11 +49,133 @@ func (p *AlertmanagerProcessor) EventType() string {
 	return common.AsEventType(AlertmanagerProcessorType())
 }
 
+// GroupEventType is type of event with whole notification group including group and common labels
+func (p *AlertmanagerProcessor) GroupEventType() string {
+	return common.AsEventType(AlertmanagerProcessorType() + "Group")
+}
+
 func (p *AlertmanagerProcessor) prepareStatus(status string) string {
 	return strings.Title(strings.ToLower(status))
 }
 
-func (p *AlertmanagerProcessor) send(span sreCommon.TracerSpan, channel string, data *template.Data) {
+// groupSeverity returns the highest severity of firing alerts
+func (p *AlertmanagerProcessor) groupSeverity(data *AlertmanagerWebhookMessage) string {
+
+	ranks := map[string]int{common.SeverityOk: 1, common.SeverityInfo: 2, common.SeverityWarning: 3, common.SeverityCritical: 4}
+
+	severity := ""
+	for _, alert := range data.Alerts.Firing() {
+		s := common.NormalizeSeverity(alert.Labels["severity"])
+		if ranks[s] > ranks[severity] {
+			severity = s
+		}
+	}
+	return severity
+}
+
+func (p *AlertmanagerProcessor) alertTitle(annotations, labels template.KV) string {
+
+	for _, s := range []string{annotations["summary"], annotations["title"], labels["alertname"]} {
+		if !utils.IsEmpty(s) {
+			return s
+		}
+	}
+	return ""
+}
+
+func (p *AlertmanagerProcessor) alertDescription(annotations template.KV) string {
+
+	if !utils.IsEmpty(annotations["description"]) {
+		return annotations["description"]
+	}
+	return annotations["message"]
+}
+
+// alertData maps alert with its labels, generator and runbook links, end time is set for resolved alert only
+// as firing alert has estimated one
+func (p *AlertmanagerProcessor) alertData(alert template.Alert, externalURL string) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       p.alertTitle(alert.Annotations, alert.Labels),
+		Description: p.alertDescription(alert.Annotations),
+		Source:      AlertmanagerProcessorType(),
+	}
+	for k, v := range alert.Labels {
+		a.AddLabel(k, v)
+	}
+	a.AddLink("Source", alert.GeneratorURL)
+	a.AddLink("Runbook", alert.Annotations["runbook_url"])
+	a.AddLink("Alertmanager", externalURL)
+	a.SetStartedAt(alert.StartsAt)
+	if alert.Status == common.StatusResolved {
+		a.SetEndedAt(alert.EndsAt)
+	}
+	return a
+}
+
+// groupData maps group by common labels and annotations, group is ended when the last alert is resolved
+func (p *AlertmanagerProcessor) groupData(data *AlertmanagerWebhookMessage, startedAt time.Time) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       p.alertTitle(data.CommonAnnotations, data.CommonLabels),
+		Description: p.alertDescription(data.CommonAnnotations),
+		Source:      AlertmanagerProcessorType(),
+	}
+	if utils.IsEmpty(a.Title) {
+		a.Title = data.GroupLabels["alertname"]
+	}
+	for k, v := range data.CommonLabels {
+		a.AddLabel(k, v)
+	}
+	a.AddLink("Runbook", data.CommonAnnotations["runbook_url"])
+	a.AddLink("Alertmanager", data.ExternalURL)
+	a.SetStartedAt(startedAt)
+
+	if data.Status == common.StatusResolved {
+		var endedAt time.Time
+		for _, alert := range data.Alerts {
+			if alert.EndsAt.After(endedAt) {
+				endedAt = alert.EndsAt
+			}
+		}
+		a.SetEndedAt(endedAt)
+	}
+	return a
+}
+
+func (p *AlertmanagerProcessor) sendGroup(span sreCommon.TracerSpan, channel string, data *AlertmanagerWebhookMessage) {
+
+	t := time.Now().UTC()
+	for _, alert := range data.Alerts {
+		if !alert.StartsAt.IsZero() && alert.StartsAt.Before(t) {
+			t = alert.StartsAt.UTC()
+		}
+	}
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.GroupEventType(),
+		Data:    data,
+		Alert:   p.groupData(data, t),
+	}
+	e.SetAlert(data.GroupKey, data.Status, p.groupSeverity(data))
+	e.SetTime(t)
+	if span != nil {
+		e.SetSpanContext(span.GetContext())
+		e.SetLogger(p.logger)
+	}
+	p.outputs.Send(e)
+}
+
+func (p *AlertmanagerProcessor) send(span sreCommon.TracerSpan, channel string, data *AlertmanagerWebhookMessage) {
+
+	if p.options.Group {
+		p.sendGroup(span, channel, data)
+	}
+
+	if !p.options.Alerts {
+		return
+	}
 
 	for _, alert := range data.Alerts {
 
@@ -46,7 +183,9 @@ func (p *AlertmanagerProcessor) send(span sreCommon.TracerSpan, channel string,
 			Channel: channel,
 			Type:    p.EventType(),
 			Data:    alert,
+			Alert:   p.alertData(alert, data.ExternalURL),
 		}
+		e.SetAlert(alert.Fingerprint, alert.Status, alert.Labels["severity"])
 		e.SetTime(alert.StartsAt.UTC())
 		if span != nil {
 			e.SetSpanContext(span.GetContext())
@@ -94,7 +233,7 @@ func (p *AlertmanagerProcessor) HandleHttpRequest(w http.ResponseWriter, r *http
 
 	var response *AlertmanagerResponse
 	errorString := ""
-	data := template.Data{}
+	data := AlertmanagerWebhookMessage{}
 	if err := json.Unmarshal(body, &data); err != nil {
 		p.logger.SpanError(span, "Can't decode body: %v", err)
 		response = &AlertmanagerResponse{
@@ -133,9 +272,10 @@ func (p *AlertmanagerProcessor) HandleHttpRequest(w http.ResponseWriter, r *http
 	return nil
 }
 
-func NewAlertmanagerProcessor(outputs *common.Outputs, observability *common.Observability) *AlertmanagerProcessor {
+func NewAlertmanagerProcessor(options AlertmanagerProcessorOptions, outputs *common.Outputs, observability *common.Observability) *AlertmanagerProcessor {
 
 	return &AlertmanagerProcessor{
+		options:  options,
 		outputs:  outputs,
 		tracer:   observability.Traces(),
 		logger:   observability.Logs(),
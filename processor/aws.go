@@ -43,13 +43,59 @@ func (p *AWSProcessor) EventType() string {
 	return common.AsEventType(AWSProcessorType())
 }
 
-func (p *AWSProcessor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+// detailString returns string of detail by path like state.value
+func (p *AWSProcessor) detailString(request AWSRequest, path string) string {
+
+	var v interface{} = request.Detail
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[name]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// setAlert maps event by its detail type, CloudWatch alarm is correlated by its name, OK state resolves it
+func (p *AWSProcessor) setAlert(e *common.Event, request AWSRequest) {
+
+	a := &common.AlertData{
+		Title:       request.DetailType,
+		Description: p.detailString(request, "eventName"),
+		Source:      AWSProcessorType(),
+	}
+	a.AddLabel("account", request.Account)
+	a.AddLabel("region", request.Region)
+	a.AddLabel("source", request.Source)
+	a.SetStartedAt(request.Time)
+	e.Alert = a
+
+	if request.DetailType != "CloudWatch Alarm State Change" {
+		return
+	}
+
+	a.Title = p.detailString(request, "alarmName")
+	a.Description = p.detailString(request, "state.reason")
+
+	status := common.StatusFiring
+	if strings.ToUpper(p.detailString(request, "state.value")) == "OK" {
+		status = common.StatusResolved
+		a.StartedAt = nil
+		a.SetEndedAt(request.Time)
+	}
+	e.SetAlert(p.detailString(request, "alarmName"), status, "")
+}
+
+func (p *AWSProcessor) send(span sreCommon.TracerSpan, channel string, request AWSRequest, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
-		Data:    o,
+		Data:    request,
 	}
+	p.setAlert(e, request)
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
 	} else {
@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+	"github.com/prometheus/alertmanager/template"
+)
+
+type CloudEventsProcessorOptions struct {
+	Token string
+}
+
+// CloudEventsProcessor accepts CNCF CloudEvents in structured, batched or binary content mode, type of cloud event
+// becomes type of event, so events sent by other instance keep their type
+type CloudEventsProcessor struct {
+	options  CloudEventsProcessorOptions
+	outputs  *common.Outputs
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type CloudEventsResponse struct {
+	Message string
+}
+
+func CloudEventsProcessorType() string {
+	return "CloudEvents"
+}
+
+func (p *CloudEventsProcessor) EventType() string {
+	return common.AsEventType(CloudEventsProcessorType())
+}
+
+// verify checks bearer token if it's defined, Knative and Argo Events brokers don't send it
+func (p *CloudEventsProcessor) verify(r *http.Request) error {
+
+	if utils.IsEmpty(p.options.Token) {
+		return nil
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.options.Token)) != 1 {
+		return errors.New("cloudevents token is invalid")
+	}
+	return nil
+}
+
+func (p *CloudEventsProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+	p.requests.Inc(e.Channel)
+	p.outputs.Send(e)
+	return nil
+}
+
+// alert decodes data of alert event, outputs render alert by its data, so alert of other instance is decoded back
+func (p *CloudEventsProcessor) alert(ce *common.CloudEvent, e *common.Event) error {
+
+	if e.Type != "AlertmanagerEvent" {
+		return nil
+	}
+
+	var alert template.Alert
+	if err := json.Unmarshal(ce.Data, &alert); err != nil || len(alert.Labels) == 0 {
+		return fmt.Errorf("cloudevents data of %s %s is not alert", ce.Type, ce.ID)
+	}
+	e.Data = alert
+	return nil
+}
+
+func (p *CloudEventsProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	span := p.tracer.StartChildSpan(r.Header)
+	defer span.Finish()
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	p.requests.Inc(channel)
+
+	if err := p.verify(r); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return err
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+
+	if len(body) == 0 {
+		p.errors.Inc(channel)
+		err := errors.New("empty body")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.SpanDebug(span, "Body => %s", body)
+
+	events, err := common.ParseCloudEvents(r.Header, body)
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	for _, ce := range events {
+
+		// continue trace of cloud event, if it has trace context extension
+		s := span
+		if len(ce.TraceHeader()) > 0 {
+			s = p.tracer.StartChildSpan(ce.TraceHeader())
+		}
+
+		e := ce.Event(channel)
+		if err := p.alert(ce, e); err != nil {
+			p.errors.Inc(channel)
+			p.logger.SpanError(s, err)
+			if s != span {
+				s.Finish()
+			}
+			continue
+		}
+		e.SetSpanContext(s.GetContext())
+		e.SetLogger(p.logger)
+		p.outputs.Send(e)
+
+		if s != span {
+			s.Finish()
+		}
+	}
+
+	response := &CloudEventsResponse{
+		Message: "OK",
+	}
+
+	resp, err := json.Marshal(response)
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	if _, err := w.Write(resp); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewCloudEventsProcessor(options CloudEventsProcessorOptions, outputs *common.Outputs, observability *common.Observability) *CloudEventsProcessor {
+
+	if utils.IsEmpty(options.Token) {
+		observability.Logs().Warn("CloudEvents token is not defined, requests are not verified")
+	}
+
+	return &CloudEventsProcessor{
+		options:  options,
+		outputs:  outputs,
+		logger:   observability.Logs(),
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all cloudevents processor requests", []string{"channel"}, "cloudevents", "processor"),
+		errors:   observability.Metrics().Counter("errors", "Count of all cloudevents processor errors", []string{"channel"}, "cloudevents", "processor"),
+	}
+}
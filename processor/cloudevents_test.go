@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	"github.com/prometheus/alertmanager/template"
+)
+
+type testOutput struct {
+	mutex  sync.Mutex
+	events []*common.Event
+}
+
+func (o *testOutput) Name() string {
+	return "Test"
+}
+
+func (o *testOutput) Send(event *common.Event) {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *testOutput) sent() []*common.Event {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.events
+}
+
+func cloudEventsRequest(body, authorization string) *http.Request {
+
+	r := httptest.NewRequest("POST", "/cloudevents", strings.NewReader(body))
+	r.Header.Set("Content-Type", common.CloudEventsContentType)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+func TestCloudEventsProcessorToken(t *testing.T) {
+
+	body := `{"specversion":"1.0","id":"1","source":"events","type":"K8sEvent","datacontenttype":"application/json","data":{"reason":"Killing"}}`
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		code   int
+	}{
+		// brokers like Knative and Argo Events don't send token, so it's optional
+		{name: "no token", token: "", header: "", code: http.StatusOK},
+		{name: "no token bearer", token: "", header: "Bearer other", code: http.StatusOK},
+		{name: "no header", token: "token", header: "", code: http.StatusUnauthorized},
+		{name: "empty bearer", token: "token", header: "Bearer ", code: http.StatusUnauthorized},
+		{name: "wrong token", token: "token", header: "Bearer other", code: http.StatusUnauthorized},
+		{name: "valid", token: "token", header: "Bearer token", code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			observability := newTestObservability()
+			outputs := common.NewOutputs(observability.Logs())
+			recorder := &testOutput{}
+			outputs.Add(recorder)
+			p := NewCloudEventsProcessor(CloudEventsProcessorOptions{Token: tt.token}, &outputs, observability)
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, cloudEventsRequest(body, tt.header))
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+
+			sent := 0
+			if tt.code == http.StatusOK {
+				sent = 1
+			}
+			events := recorder.sent()
+			if len(events) != sent {
+				t.Fatalf("expected %d events, got %d", sent, len(events))
+			}
+			if sent == 1 && events[0].Type != "K8sEvent" {
+				t.Errorf("type of cloud event is lost, %s", events[0].Type)
+			}
+		})
+	}
+}
+
+func TestCloudEventsProcessorAlert(t *testing.T) {
+
+	observability := newTestObservability()
+	outputs := common.NewOutputs(observability.Logs())
+	recorder := &testOutput{}
+	outputs.Add(recorder)
+	p := NewCloudEventsProcessor(CloudEventsProcessorOptions{}, &outputs, observability)
+
+	// rendered message of other instance has type of alert, but it isn't alert
+	body := `[
+		{"specversion":"1.0","id":"1","source":"events","type":"AlertmanagerEvent","data":{"status":"firing","labels":{"alertname":"DiskFull"}}},
+		{"specversion":"1.0","id":"2","source":"events","type":"AlertmanagerEvent","data":{"status":"firing"}},
+		{"specversion":"1.0","id":"3","source":"events","type":"AlertmanagerEvent","datacontenttype":"text/plain","data":"disk is full"}
+	]`
+	r := cloudEventsRequest(body, "")
+	r.Header.Set("Content-Type", common.CloudEventsBatchContentType)
+
+	w := httptest.NewRecorder()
+	p.HandleHttpRequest(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	events := recorder.sent()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 alert event, got %d", len(events))
+	}
+	alert, ok := events[0].Data.(template.Alert)
+	if !ok {
+		t.Fatalf("data of alert event is %T", events[0].Data)
+	}
+	if alert.Status != "firing" || alert.Labels["alertname"] != "DiskFull" {
+		t.Errorf("alert isn't decoded: %+v", alert)
+	}
+}
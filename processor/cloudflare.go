@@ -11,6 +11,7 @@ import (
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
 )
 
 type CloudflareProcessor struct {
@@ -21,8 +22,16 @@ type CloudflareProcessor struct {
 	errors   sreCommon.Counter
 }
 
+// CloudflareRequest is payload of Cloudflare notification webhook, test notification has text only
 type CloudflareRequest struct {
-	Text string `json:"text"`
+	Name       string                 `json:"name,omitempty"`
+	Text       string                 `json:"text"`
+	AlertType  string                 `json:"alert_type,omitempty"`
+	AccountID  string                 `json:"account_id,omitempty"`
+	PolicyID   string                 `json:"policy_id,omitempty"`
+	PolicyName string                 `json:"policy_name,omitempty"`
+	Ts         int64                  `json:"ts,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
 }
 
 type CloudflareResponse struct {
@@ -37,12 +46,33 @@ func (p *CloudflareProcessor) EventType() string {
 	return common.AsEventType(CloudflareProcessorType())
 }
 
-func (p *CloudflareProcessor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+// alertData maps notification, name of notification is title or the first line of text if it's not set
+func (p *CloudflareProcessor) alertData(request CloudflareRequest) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       request.Name,
+		Description: request.Text,
+		Source:      CloudflareProcessorType(),
+	}
+	if utils.IsEmpty(a.Title) {
+		a.Title = strings.TrimSpace(strings.SplitN(request.Text, "\n", 2)[0])
+	}
+	a.AddLabel("alert_type", request.AlertType)
+	a.AddLabel("account_id", request.AccountID)
+	a.AddLabel("policy", request.PolicyName)
+	if request.Ts > 0 {
+		a.SetStartedAt(time.Unix(request.Ts, 0))
+	}
+	return a
+}
+
+func (p *CloudflareProcessor) send(span sreCommon.TracerSpan, channel string, request CloudflareRequest, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
-		Data:    o,
+		Data:    request,
+		Alert:   p.alertData(request),
 	}
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
@@ -100,7 +130,12 @@ func (p *CloudflareProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	p.send(span, channel, Cloudflare, nil)
+	if Cloudflare.Ts > 0 {
+		t := time.Unix(Cloudflare.Ts, 0)
+		p.send(span, channel, Cloudflare, &t)
+	} else {
+		p.send(span, channel, Cloudflare, nil)
+	}
 
 	response := &CloudflareResponse{
 		Message: "OK",
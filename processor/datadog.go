@@ -11,6 +11,7 @@ import (
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
 )
 
 type DataDogProcessor struct {
@@ -87,13 +88,75 @@ func (p *DataDogProcessor) EventType() string {
 	return common.AsEventType(DataDogProcessorType())
 }
 
-func (p *DataDogProcessor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+// setAlert maps transition and priority of monitor alert, events without alert are informational
+func (p *DataDogProcessor) setAlert(e *common.Event, request DataDogRequest) {
+
+	if request.Alert == nil || request.Alert.ID == "" {
+		return
+	}
+
+	severity := request.Alert.Priority
+	if common.NormalizeSeverity(severity) == "" {
+		severity = request.Priority
+	}
+
+	status := common.StatusFiring
+	switch strings.ToLower(request.Alert.Transition) {
+	case "recovered":
+		status = common.StatusResolved
+		severity = common.SeverityOk
+	case "warn", "no data":
+		severity = common.SeverityWarning
+	default:
+		if common.NormalizeSeverity(severity) == "" {
+			severity = common.SeverityCritical
+		}
+	}
+	e.SetAlert(request.Alert.ID, status, severity)
+	e.Alert = p.alertData(request, status)
+}
+
+// alertData maps monitor alert, tags like key:value are labels
+func (p *DataDogProcessor) alertData(request DataDogRequest, status string) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       request.Alert.Title,
+		Description: strings.TrimSpace(request.TextOnlyMsg),
+		Source:      DataDogProcessorType(),
+	}
+	if utils.IsEmpty(a.Title) && request.Event != nil {
+		a.Title = request.Event.Title
+	}
+
+	for _, tag := range strings.Split(request.Tags, ",") {
+		kv := strings.SplitN(strings.TrimSpace(tag), ":", 2)
+		if len(kv) == 2 {
+			a.AddLabel(kv[0], kv[1])
+		}
+	}
+	a.AddLabel("metric", request.Alert.Metric)
+	a.AddLabel("scope", request.Alert.Scope)
+
+	a.AddLink("Event", request.Link)
+	a.AddLink("Snapshot", request.Snapshot)
+
+	// date of recovered alert is time of recovery
+	if status == common.StatusResolved {
+		a.SetEndedAt(time.UnixMilli(request.LastUpdated))
+	} else {
+		a.SetStartedAt(time.UnixMilli(request.Date))
+	}
+	return a
+}
+
+func (p *DataDogProcessor) send(span sreCommon.TracerSpan, channel string, request DataDogRequest, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
-		Data:    o,
+		Data:    request,
 	}
+	p.setAlert(e, request)
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
 	} else {
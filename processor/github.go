@@ -0,0 +1,219 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+	"github.com/go-playground/webhooks/v6/github"
+)
+
+type GithubProcessorOptions struct {
+	Secret string
+}
+
+type GithubProcessor struct {
+	options  GithubProcessorOptions
+	outputs  *common.Outputs
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+	hook     *github.Webhook
+}
+
+type GithubResponse struct {
+	Message string
+}
+
+func GithubProcessorType() string {
+	return "Github"
+}
+
+func (p *GithubProcessor) EventType() string {
+	return common.AsEventType(GithubProcessorType())
+}
+
+func (p *GithubProcessor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    o,
+	}
+	if t != nil && (*t).UnixNano() > 0 {
+		e.SetTime((*t).UTC())
+	} else {
+		e.SetTime(time.Now().UTC())
+	}
+	if span != nil {
+		e.SetSpanContext(span.GetContext())
+		e.SetLogger(p.logger)
+	}
+	p.outputs.Send(e)
+}
+
+// verify checks signature as described in https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries,
+// requests are rejected if secret is not defined
+func (p *GithubProcessor) verify(r *http.Request, body []byte) error {
+
+	if utils.IsEmpty(p.options.Secret) {
+		return errors.New("github secret is not defined")
+	}
+
+	signature := r.Header.Get("X-Hub-Signature-256")
+	if utils.IsEmpty(signature) {
+		return errors.New("no github signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.options.Secret))
+	mac.Write(body)
+	expected := fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("github signature is invalid")
+	}
+	return nil
+}
+
+func (p *GithubProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+	p.requests.Inc(e.Channel)
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *GithubProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	span := p.tracer.StartChildSpan(r.Header)
+	defer span.Finish()
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	p.requests.Inc(channel)
+
+	var body []byte
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+
+	if len(body) == 0 {
+		p.errors.Inc(channel)
+		err := errors.New("empty body")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.SpanDebug(span, "Body => %s", body)
+
+	if err := p.verify(r, body); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return err
+	}
+
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	events := []github.Event{github.PushEvent, github.PullRequestEvent, github.WorkflowRunEvent, github.WorkflowJobEvent,
+		github.ReleaseEvent, github.DeploymentStatusEvent, github.CheckSuiteEvent, github.PingEvent}
+	payload, err := p.hook.Parse(r, events...)
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	switch pl := payload.(type) {
+	case github.PushPayload:
+		event := payload.(github.PushPayload)
+		var t *time.Time
+		if ts, err := time.Parse(time.RFC3339, event.HeadCommit.Timestamp); err == nil {
+			t = &ts
+		}
+		p.send(span, channel, event, t)
+	case github.PullRequestPayload:
+		event := payload.(github.PullRequestPayload)
+		p.send(span, channel, event, &event.PullRequest.UpdatedAt)
+	case github.WorkflowRunPayload:
+		event := payload.(github.WorkflowRunPayload)
+		p.send(span, channel, event, &event.WorkflowRun.UpdatedAt)
+	case github.WorkflowJobPayload:
+		event := payload.(github.WorkflowJobPayload)
+		p.send(span, channel, event, &event.WorkflowJob.StartedAt)
+	case github.ReleasePayload:
+		event := payload.(github.ReleasePayload)
+		p.send(span, channel, event, &event.Release.PublishedAt)
+	case github.DeploymentStatusPayload:
+		event := payload.(github.DeploymentStatusPayload)
+		p.send(span, channel, event, &event.DeploymentStatus.CreatedAt)
+	case github.CheckSuitePayload:
+		event := payload.(github.CheckSuitePayload)
+		p.send(span, channel, event, &event.CheckSuite.UpdatedAt)
+	case github.PingPayload:
+		p.logger.SpanDebug(span, "Github ping => %d", payload.(github.PingPayload).HookID)
+	default:
+		p.logger.SpanDebug(span, "Not supported %s", pl)
+	}
+
+	response := &GithubResponse{
+		Message: "OK",
+	}
+
+	resp, err := json.Marshal(response)
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	if _, err := w.Write(resp); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewGithubProcessor(options GithubProcessorOptions, outputs *common.Outputs, observability *common.Observability) *GithubProcessor {
+
+	logger := observability.Logs()
+	// signature is verified with sha256 by processor itself, library supports only sha1
+	hook, err := github.New()
+	if err != nil {
+		logger.Debug("Github processor is disabled.")
+		return nil
+	}
+
+	return &GithubProcessor{
+		options:  options,
+		outputs:  outputs,
+		logger:   logger,
+		tracer:   observability.Traces(),
+		hook:     hook,
+		requests: observability.Metrics().Counter("requests", "Count of all github processor requests", []string{"channel"}, "github", "processor"),
+		errors:   observability.Metrics().Counter("errors", "Count of all github processor errors", []string{"channel"}, "github", "processor"),
+	}
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devopsext/events/common"
+)
+
+func TestGithubProcessorSignature(t *testing.T) {
+
+	body := `{"zen":"Keep it logically awesome.","hook_id":1}`
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		code      int
+	}{
+		{name: "no secret", secret: "", signature: "", code: http.StatusUnauthorized},
+		{name: "no secret signed", secret: "", signature: sign(""), code: http.StatusUnauthorized},
+		{name: "unsigned", secret: "secret", signature: "", code: http.StatusUnauthorized},
+		{name: "wrong secret", secret: "secret", signature: sign("other"), code: http.StatusUnauthorized},
+		{name: "signed", secret: "secret", signature: sign("secret"), code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			observability := newTestObservability()
+			outputs := common.NewOutputs(observability.Logs())
+			p := NewGithubProcessor(GithubProcessorOptions{Secret: tt.secret}, &outputs, observability)
+
+			r := httptest.NewRequest("POST", "/github", strings.NewReader(body))
+			r.Header.Set("X-GitHub-Event", "ping")
+			if tt.signature != "" {
+				r.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, r)
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -2,21 +2,30 @@ package processor
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
 	"github.com/go-playground/webhooks/v6/gitlab"
 )
 
+type GitlabProcessorOptions struct {
+	Secret  string
+	Secrets string
+}
+
 type GitlabProcessor struct {
+	options  GitlabProcessorOptions
 	outputs  *common.Outputs
 	tracer   sreCommon.Tracer
 	logger   sreCommon.Logger
@@ -29,6 +38,165 @@ type GitlabResponse struct {
 	Message string
 }
 
+// hooks which are not supported by webhooks library
+const (
+	GitlabReleaseEvents     gitlab.Event = "Release Hook"
+	GitlabDeploymentEvents  gitlab.Event = "Deployment Hook"
+	GitlabFeatureFlagEvents gitlab.Event = "Feature Flag Hook"
+	GitlabMemberEvents      gitlab.Event = "Member Hook"
+	GitlabEmojiEvents       gitlab.Event = "Emoji Hook"
+)
+
+// GitlabTime parses time in the same formats as gitlab hooks send
+type GitlabTime struct {
+	time.Time
+}
+
+func (t *GitlabTime) UnmarshalJSON(b []byte) (err error) {
+
+	layouts := []string{
+		"2006-01-02 15:04:05 MST",
+		"2006-01-02 15:04:05 Z07:00",
+		"2006-01-02 15:04:05 Z0700",
+		time.RFC3339,
+	}
+	s := strings.Trim(string(b), "\"")
+	if s == "null" || s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	for _, l := range layouts {
+		t.Time, err = time.Parse(l, s)
+		if err == nil {
+			break
+		}
+	}
+	return
+}
+
+type GitlabAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type GitlabCommit struct {
+	ID        string       `json:"id"`
+	Message   string       `json:"message"`
+	Title     string       `json:"title"`
+	Timestamp GitlabTime   `json:"timestamp"`
+	URL       string       `json:"url"`
+	Author    GitlabAuthor `json:"author"`
+}
+
+type GitlabReleaseLink struct {
+	ID       int64  `json:"id"`
+	External bool   `json:"external"`
+	LinkType string `json:"link_type"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+}
+
+type GitlabReleaseSource struct {
+	Format string `json:"format"`
+	URL    string `json:"url"`
+}
+
+type GitlabReleaseAssets struct {
+	Count   int64                  `json:"count"`
+	Links   []*GitlabReleaseLink   `json:"links"`
+	Sources []*GitlabReleaseSource `json:"sources"`
+}
+
+type GitlabReleaseEventPayload struct {
+	ObjectKind  string              `json:"object_kind"`
+	ID          int64               `json:"id"`
+	Action      string              `json:"action"`
+	Name        string              `json:"name"`
+	Tag         string              `json:"tag"`
+	Description string              `json:"description"`
+	URL         string              `json:"url"`
+	CreatedAt   GitlabTime          `json:"created_at"`
+	ReleasedAt  GitlabTime          `json:"released_at"`
+	Project     gitlab.Project      `json:"project"`
+	Commit      GitlabCommit        `json:"commit"`
+	Assets      GitlabReleaseAssets `json:"assets"`
+}
+
+type GitlabDeploymentEventPayload struct {
+	ObjectKind             string         `json:"object_kind"`
+	Status                 string         `json:"status"`
+	StatusChangedAt        GitlabTime     `json:"status_changed_at"`
+	DeploymentID           int64          `json:"deployment_id"`
+	DeployableID           int64          `json:"deployable_id"`
+	DeployableURL          string         `json:"deployable_url"`
+	Environment            string         `json:"environment"`
+	EnvironmentTier        string         `json:"environment_tier"`
+	EnvironmentSlug        string         `json:"environment_slug"`
+	EnvironmentExternalURL string         `json:"environment_external_url"`
+	Project                gitlab.Project `json:"project"`
+	ShortSHA               string         `json:"short_sha"`
+	User                   gitlab.User    `json:"user"`
+	UserURL                string         `json:"user_url"`
+	CommitURL              string         `json:"commit_url"`
+	CommitTitle            string         `json:"commit_title"`
+}
+
+type GitlabFeatureFlagAttributes struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Active      bool   `json:"active"`
+}
+
+type GitlabFeatureFlagEventPayload struct {
+	ObjectKind       string                      `json:"object_kind"`
+	Project          gitlab.Project              `json:"project"`
+	User             gitlab.User                 `json:"user"`
+	UserURL          string                      `json:"user_url"`
+	ObjectAttributes GitlabFeatureFlagAttributes `json:"object_attributes"`
+}
+
+type GitlabMemberEventPayload struct {
+	EventName    string     `json:"event_name"`
+	CreatedAt    GitlabTime `json:"created_at"`
+	UpdatedAt    GitlabTime `json:"updated_at"`
+	ExpiresAt    GitlabTime `json:"expires_at"`
+	GroupName    string     `json:"group_name"`
+	GroupPath    string     `json:"group_path"`
+	GroupID      int64      `json:"group_id"`
+	GroupAccess  string     `json:"group_access"`
+	GroupPlan    string     `json:"group_plan"`
+	UserUsername string     `json:"user_username"`
+	UserName     string     `json:"user_name"`
+	UserEmail    string     `json:"user_email"`
+	UserID       int64      `json:"user_id"`
+}
+
+type GitlabEmojiAttributes struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Name          string     `json:"name"`
+	AwardableType string     `json:"awardable_type"`
+	AwardableID   int64      `json:"awardable_id"`
+	CreatedAt     GitlabTime `json:"created_at"`
+	UpdatedAt     GitlabTime `json:"updated_at"`
+}
+
+type GitlabEmojiEventPayload struct {
+	ObjectKind       string                 `json:"object_kind"`
+	EventType        string                 `json:"event_type"`
+	User             gitlab.User            `json:"user"`
+	ProjectID        int64                  `json:"project_id"`
+	Project          gitlab.Project         `json:"project"`
+	ObjectAttributes GitlabEmojiAttributes  `json:"object_attributes"`
+	Note             map[string]interface{} `json:"note,omitempty"`
+	Issue            map[string]interface{} `json:"issue,omitempty"`
+	MergeRequest     map[string]interface{} `json:"merge_request,omitempty"`
+	Snippet          map[string]interface{} `json:"snippet,omitempty"`
+	Commit           map[string]interface{} `json:"commit,omitempty"`
+	Repository       gitlab.Repository      `json:"repository"`
+}
+
 func GitlabProcessorType() string {
 	return "Gitlab"
 }
@@ -44,6 +212,15 @@ func (p *GitlabProcessor) send(span sreCommon.TracerSpan, channel string, o inte
 		Type:    p.EventType(),
 		Data:    o,
 	}
+
+	// pipeline and its jobs are correlated by pipeline id
+	switch pl := o.(type) {
+	case gitlab.PipelineEventPayload:
+		e.Key = strconv.FormatInt(pl.ObjectAttributes.ID, 10)
+	case gitlab.JobEventPayload:
+		e.Key = strconv.FormatInt(pl.PipelineID, 10)
+	}
+
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
 	} else {
@@ -56,6 +233,70 @@ func (p *GitlabProcessor) send(span sreCommon.TracerSpan, channel string, o inte
 	p.outputs.Send(e)
 }
 
+func (p *GitlabProcessor) getSecret(channel string) string {
+
+	for _, item := range strings.Split(p.options.Secrets, ",") {
+
+		pair := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		if strings.Trim(pair[0], "/") == channel {
+			return pair[1]
+		}
+	}
+	return p.options.Secret
+}
+
+// verify compares X-Gitlab-Token with secret of channel or global secret, requests are rejected if secret is not defined
+func (p *GitlabProcessor) verify(r *http.Request, channel string) error {
+
+	secret := p.getSecret(channel)
+	if utils.IsEmpty(secret) {
+		return fmt.Errorf("gitlab secret of channel %s is not defined", channel)
+	}
+
+	token := r.Header.Get("X-Gitlab-Token")
+	if utils.IsEmpty(token) {
+		return errors.New("no gitlab token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return errors.New("gitlab token is invalid")
+	}
+	return nil
+}
+
+func (p *GitlabProcessor) parse(r *http.Request, body []byte) (interface{}, error) {
+
+	var payload interface{}
+
+	switch gitlab.Event(r.Header.Get("X-Gitlab-Event")) {
+	case GitlabReleaseEvents:
+		payload = &GitlabReleaseEventPayload{}
+	case GitlabDeploymentEvents:
+		payload = &GitlabDeploymentEventPayload{}
+	case GitlabFeatureFlagEvents:
+		payload = &GitlabFeatureFlagEventPayload{}
+	case GitlabMemberEvents:
+		payload = &GitlabMemberEventPayload{}
+	case GitlabEmojiEvents:
+		payload = &GitlabEmojiEventPayload{}
+	default:
+		events := []gitlab.Event{gitlab.PushEvents, gitlab.TagEvents, gitlab.IssuesEvents, gitlab.ConfidentialIssuesEvents, gitlab.CommentEvents,
+			gitlab.MergeRequestEvents, gitlab.WikiPageEvents, gitlab.PipelineEvents, gitlab.BuildEvents, gitlab.JobEvents, gitlab.SystemHookEvents}
+		return p.hook.Parse(r, events...)
+	}
+
+	if r.Method != http.MethodPost {
+		return nil, gitlab.ErrInvalidHTTPMethod
+	}
+
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
 func (p *GitlabProcessor) HandleEvent(e *common.Event) error {
 
 	if e == nil {
@@ -92,12 +333,17 @@ func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 
 	p.logger.SpanDebug(span, "Body => %s", body)
 
+	if err := p.verify(r, channel); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return err
+	}
+
 	r.Body.Close()
 	r.Body = io.NopCloser(bytes.NewBuffer(body))
 
-	events := []gitlab.Event{gitlab.PushEvents, gitlab.TagEvents, gitlab.IssuesEvents, gitlab.ConfidentialIssuesEvents, gitlab.CommentEvents,
-		gitlab.MergeRequestEvents, gitlab.WikiPageEvents, gitlab.PipelineEvents, gitlab.BuildEvents, gitlab.JobEvents, gitlab.SystemHookEvents}
-	payload, err := p.hook.Parse(r, events...)
+	payload, err := p.parse(r, body)
 	if err != nil {
 		p.errors.Inc(channel)
 		p.logger.SpanError(span, err)
@@ -136,6 +382,20 @@ func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 		p.send(span, channel, event, &event.BuildStartedAt.Time)
 	case gitlab.SystemHookPayload:
 		p.send(span, channel, payload.(gitlab.SystemHookPayload), nil)
+	case *GitlabReleaseEventPayload:
+		event := payload.(*GitlabReleaseEventPayload)
+		p.send(span, channel, event, &event.CreatedAt.Time)
+	case *GitlabDeploymentEventPayload:
+		event := payload.(*GitlabDeploymentEventPayload)
+		p.send(span, channel, event, &event.StatusChangedAt.Time)
+	case *GitlabFeatureFlagEventPayload:
+		p.send(span, channel, payload.(*GitlabFeatureFlagEventPayload), nil)
+	case *GitlabMemberEventPayload:
+		event := payload.(*GitlabMemberEventPayload)
+		p.send(span, channel, event, &event.UpdatedAt.Time)
+	case *GitlabEmojiEventPayload:
+		event := payload.(*GitlabEmojiEventPayload)
+		p.send(span, channel, event, &event.ObjectAttributes.CreatedAt.Time)
 	default:
 		p.logger.SpanDebug(span, "Not supported %s", pl)
 	}
@@ -161,9 +421,10 @@ func (p *GitlabProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Reque
 	return nil
 }
 
-func NewGitlabProcessor(outputs *common.Outputs, observability *common.Observability) *GitlabProcessor {
+func NewGitlabProcessor(options GitlabProcessorOptions, outputs *common.Outputs, observability *common.Observability) *GitlabProcessor {
 
 	logger := observability.Logs()
+	// token is verified by processor itself to support secret per channel
 	hook, err := gitlab.New()
 	if err != nil {
 		logger.Debug("Gitlab processor is disabled.")
@@ -171,6 +432,7 @@ func NewGitlabProcessor(outputs *common.Outputs, observability *common.Observabi
 	}
 
 	return &GitlabProcessor{
+		options:  options,
 		outputs:  outputs,
 		logger:   logger,
 		tracer:   observability.Traces(),
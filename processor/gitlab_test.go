@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+)
+
+func gitlabRequest(t *testing.T, channel, hook, file, token string) *http.Request {
+
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/"+channel, strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Gitlab-Event", hook)
+	if token != "" {
+		r.Header.Set("X-Gitlab-Token", token)
+	}
+	return r
+}
+
+func TestGitlabProcessorToken(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		secret  string
+		secrets string
+		channel string
+		token   string
+		code    int
+	}{
+		{name: "no secret", channel: "gitlab", code: http.StatusUnauthorized},
+		{name: "no secret with token", channel: "gitlab", token: "secret", code: http.StatusUnauthorized},
+		{name: "no token", secret: "secret", channel: "gitlab", code: http.StatusUnauthorized},
+		{name: "wrong token", secret: "secret", channel: "gitlab", token: "other", code: http.StatusUnauthorized},
+		{name: "global secret", secret: "secret", channel: "gitlab", token: "secret", code: http.StatusOK},
+		{name: "channel secret", secrets: "/gitlab/team=team", channel: "gitlab/team", token: "team", code: http.StatusOK},
+		{name: "global secret of channel", secret: "secret", secrets: "gitlab/team=team", channel: "gitlab/team", token: "secret", code: http.StatusUnauthorized},
+		{name: "channel without secret", secrets: "gitlab/team=team", channel: "gitlab/other", token: "team", code: http.StatusUnauthorized},
+		{name: "other channel", secret: "secret", secrets: "gitlab/team=team", channel: "gitlab/other", token: "secret", code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			observability := newTestObservability()
+			outputs := common.NewOutputs(observability.Logs())
+			recorder := &testOutput{}
+			outputs.Add(recorder)
+			p := NewGitlabProcessor(GitlabProcessorOptions{Secret: tt.secret, Secrets: tt.secrets}, &outputs, observability)
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, gitlabRequest(t, tt.channel, "Release Hook", "../test/gitlab-release.json", tt.token))
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+
+			sent := 0
+			if tt.code == http.StatusOK {
+				sent = 1
+			}
+			if events := recorder.sent(); len(events) != sent {
+				t.Errorf("expected %d events, got %d", sent, len(events))
+			}
+		})
+	}
+}
+
+func TestGitlabProcessorHooks(t *testing.T) {
+
+	tests := []struct {
+		hook  string
+		file  string
+		check func(data interface{}) bool
+		time  string
+	}{
+		{
+			hook: "Release Hook", file: "gitlab-release.json", time: "2022-02-01T15:07:00Z",
+			check: func(data interface{}) bool {
+				r, ok := data.(*GitlabReleaseEventPayload)
+				return ok && r.Tag == "0.1.2-1" && r.Action == "create"
+			},
+		},
+		{
+			hook: "Deployment Hook", file: "gitlab-deployment.json", time: "2022-02-01T15:12:00Z",
+			check: func(data interface{}) bool {
+				d, ok := data.(*GitlabDeploymentEventPayload)
+				return ok && d.Status == "success" && d.Environment == "production"
+			},
+		},
+		{
+			hook: "Feature Flag Hook", file: "gitlab-feature-flag.json",
+			check: func(data interface{}) bool {
+				f, ok := data.(*GitlabFeatureFlagEventPayload)
+				return ok && f.ObjectAttributes.Name == "new-renderer" && f.ObjectAttributes.Active
+			},
+		},
+		{
+			hook: "Member Hook", file: "gitlab-member.json", time: "2022-02-01T15:20:22Z",
+			check: func(data interface{}) bool {
+				m, ok := data.(*GitlabMemberEventPayload)
+				return ok && m.EventName == "user_add_to_group" && m.UserUsername == "jdoe"
+			},
+		},
+		{
+			hook: "Emoji Hook", file: "gitlab-emoji.json", time: "2022-02-01T15:25:11Z",
+			check: func(data interface{}) bool {
+				e, ok := data.(*GitlabEmojiEventPayload)
+				return ok && e.ObjectAttributes.Name == "thumbsup" && e.ObjectAttributes.AwardableType == "MergeRequest"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hook, func(t *testing.T) {
+
+			observability := newTestObservability()
+			outputs := common.NewOutputs(observability.Logs())
+			recorder := &testOutput{}
+			outputs.Add(recorder)
+			p := NewGitlabProcessor(GitlabProcessorOptions{Secret: "secret"}, &outputs, observability)
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, gitlabRequest(t, "gitlab", tt.hook, "../test/"+tt.file, "secret"))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			events := recorder.sent()
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+			e := events[0]
+			if e.Type != "GitlabEvent" || !tt.check(e.Data) {
+				t.Errorf("unexpected %s event %+v", e.Type, e.Data)
+			}
+			if tt.time != "" && e.Time.Format(time.RFC3339) != tt.time {
+				t.Errorf("expected time %s, got %s", tt.time, e.Time.Format(time.RFC3339))
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/devopsext/events/common"
 	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
 )
 
 type GoogleProcessor struct {
@@ -37,12 +38,20 @@ type GoogleMetadata struct {
 	UserLabels   map[string]string `json:"user_labels"`
 }
 
+type GoogleAggregation struct {
+	AlignmentPeriod    string   `json:"alignmentPeriod,omitempty"`
+	PerSeriesAligner   string   `json:"perSeriesAligner,omitempty"`
+	CrossSeriesReducer string   `json:"crossSeriesReducer,omitempty"`
+	GroupByFields      []string `json:"groupByFields,omitempty"`
+}
+
 type GoogleConditionThreshold struct {
-	Filter         string      `json:"filter"`
-	Comparison     string      `json:"comparison"`
-	ThresholdValue float32     `json:"thresholdValue"`
-	Duration       string      `json:"duration"`
-	Trigger        interface{} `json:"trigger"`
+	Filter         string               `json:"filter"`
+	Comparison     string               `json:"comparison"`
+	ThresholdValue float32              `json:"thresholdValue"`
+	Duration       string               `json:"duration"`
+	Trigger        interface{}          `json:"trigger"`
+	Aggregations   []*GoogleAggregation `json:"aggregations,omitempty"`
 }
 
 type GoogleCondition struct {
@@ -59,6 +68,7 @@ type GoogleIncident struct {
 	StartedAt               int64             `json:"started_at"`
 	EndedAt                 int64             `json:"ended_at,omitempty"`
 	State                   string            `json:"state"`
+	Severity                string            `json:"severity,omitempty"`
 	Summary                 string            `json:"summary"`
 	ApigeeURL               string            `json:"apigee_url"`
 	ObservedValue           string            `json:"observed_value"`
@@ -94,13 +104,73 @@ func (p *GoogleProcessor) EventType() string {
 	return common.AsEventType(GoogleProcessorType())
 }
 
-func (p *GoogleProcessor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+// setAlert maps state and severity of incident, severity isn't sent by old policies
+func (p *GoogleProcessor) setAlert(e *common.Event, request GoogleRequest) {
+
+	if request.Incident == nil {
+		return
+	}
+
+	status := common.StatusFiring
+	severity := request.Incident.Severity
+	if strings.ToLower(request.Incident.State) == "closed" {
+		status = common.StatusResolved
+		severity = common.SeverityOk
+	}
+	e.SetAlert(request.Incident.IncidentID, status, severity)
+	e.Alert = p.alertData(request.Incident)
+}
+
+// alertData maps incident with labels of resource, metric, metadata and policy
+func (p *GoogleProcessor) alertData(incident *GoogleIncident) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       incident.PolicyName,
+		Description: incident.Summary,
+		Source:      GoogleProcessorType(),
+	}
+	for _, s := range []string{incident.ConditionName, incident.Summary} {
+		if utils.IsEmpty(a.Title) {
+			a.Title = s
+		}
+	}
+
+	var labels []map[string]string
+	if incident.Resource != nil {
+		labels = append(labels, incident.Resource.Labels)
+	}
+	if incident.Metric != nil {
+		labels = append(labels, incident.Metric.Labels)
+	}
+	if incident.Metadata != nil {
+		labels = append(labels, incident.Metadata.SystemLabels, incident.Metadata.UserLabels)
+	}
+	labels = append(labels, incident.PolicyUserLabels)
+	for _, m := range labels {
+		for k, v := range m {
+			a.AddLabel(k, v)
+		}
+	}
+	a.AddLabel("resource", incident.ResourceDisplayName)
+
+	a.AddLink("Incident", incident.URL)
+	if incident.StartedAt > 0 {
+		a.SetStartedAt(time.Unix(incident.StartedAt, 0))
+	}
+	if incident.EndedAt > 0 {
+		a.SetEndedAt(time.Unix(incident.EndedAt, 0))
+	}
+	return a
+}
+
+func (p *GoogleProcessor) send(span sreCommon.TracerSpan, channel string, request GoogleRequest, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
-		Data:    o,
+		Data:    request,
 	}
+	p.setAlert(e, request)
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
 	} else {
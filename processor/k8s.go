@@ -81,6 +81,8 @@ func (p *K8sProcessor) send(span sreCommon.TracerSpan, channel string, ar *admv1
 			User:      user,
 		},
 	}
+	// object is correlated by its kind and location
+	e.Key = fmt.Sprintf("%s/%s", ar.Kind.Kind, location)
 	e.SetTime(time.Now().UTC())
 	if span != nil {
 		e.SetSpanContext(span.GetContext())
@@ -1 +1,222 @@
 package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type NewRelicProcessor struct {
+	outputs  *common.Outputs
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type NewRelicTarget struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Link    string            `json:"link"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Product string            `json:"product"`
+	Type    string            `json:"type"`
+}
+
+// NewRelicRequest is default payload of NewRelic alert webhook channel
+type NewRelicRequest struct {
+	AccountID              int64             `json:"account_id"`
+	AccountName            string            `json:"account_name"`
+	ConditionID            int64             `json:"condition_id"`
+	ConditionName          string            `json:"condition_name"`
+	CurrentState           string            `json:"current_state"`
+	Details                string            `json:"details"`
+	EventType              string            `json:"event_type"`
+	IncidentAcknowledgeURL string            `json:"incident_acknowledge_url"`
+	IncidentID             int64             `json:"incident_id"`
+	IncidentURL            string            `json:"incident_url"`
+	Owner                  string            `json:"owner"`
+	PolicyName             string            `json:"policy_name"`
+	PolicyURL              string            `json:"policy_url"`
+	RunbookURL             string            `json:"runbook_url"`
+	Severity               string            `json:"severity"`
+	Targets                []*NewRelicTarget `json:"targets,omitempty"`
+	Timestamp              int64             `json:"timestamp"`
+	ViolationChartURL      string            `json:"violation_chart_url"`
+}
+
+type NewRelicResponse struct {
+	Message string
+}
+
+func NewRelicProcessorType() string {
+	return "NewRelic"
+}
+
+func (p *NewRelicProcessor) EventType() string {
+	return common.AsEventType(NewRelicProcessorType())
+}
+
+// setAlert maps current state and severity of incident, acknowledged incident is still firing
+func (p *NewRelicProcessor) setAlert(e *common.Event, request NewRelicRequest) {
+
+	status := common.StatusFiring
+	severity := request.Severity
+	if strings.ToLower(request.CurrentState) == "closed" {
+		status = common.StatusResolved
+		severity = common.SeverityOk
+	}
+	key := ""
+	if request.IncidentID > 0 {
+		key = strconv.FormatInt(request.IncidentID, 10)
+	}
+	e.SetAlert(key, status, severity)
+	e.Alert = p.alertData(request, status)
+}
+
+// alertData maps incident with labels of its targets, timestamp is start of incident or its end, if it's closed
+func (p *NewRelicProcessor) alertData(request NewRelicRequest, status string) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       request.ConditionName,
+		Description: request.Details,
+		Source:      NewRelicProcessorType(),
+	}
+	if utils.IsEmpty(a.Title) {
+		a.Title = request.PolicyName
+	}
+
+	a.AddLabel("account", request.AccountName)
+	a.AddLabel("policy", request.PolicyName)
+	for _, t := range request.Targets {
+		if t == nil {
+			continue
+		}
+		for k, v := range t.Labels {
+			a.AddLabel(k, v)
+		}
+		a.AddLabel("target", t.Name)
+		a.AddLink(t.Name, t.Link)
+	}
+
+	a.AddLink("Incident", request.IncidentURL)
+	a.AddLink("Runbook", request.RunbookURL)
+	a.AddLink("Chart", request.ViolationChartURL)
+
+	if request.Timestamp > 0 {
+		if status == common.StatusResolved {
+			a.SetEndedAt(time.UnixMilli(request.Timestamp))
+		} else {
+			a.SetStartedAt(time.UnixMilli(request.Timestamp))
+		}
+	}
+	return a
+}
+
+func (p *NewRelicProcessor) send(span sreCommon.TracerSpan, channel string, request NewRelicRequest, t *time.Time) {
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    request,
+	}
+	p.setAlert(e, request)
+	if t != nil && (*t).UnixNano() > 0 {
+		e.SetTime((*t).UTC())
+	} else {
+		e.SetTime(time.Now().UTC())
+	}
+	if span != nil {
+		e.SetSpanContext(span.GetContext())
+		e.SetLogger(p.logger)
+	}
+	p.outputs.Send(e)
+}
+
+func (p *NewRelicProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+	p.requests.Inc(e.Channel)
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *NewRelicProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	span := p.tracer.StartChildSpan(r.Header)
+	defer span.Finish()
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	p.requests.Inc(channel)
+
+	var body []byte
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+
+	if len(body) == 0 {
+		p.errors.Inc(channel)
+		err := errors.New("empty body")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.SpanDebug(span, "Body => %s", body)
+
+	var request NewRelicRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, "Error unmarshaling message", http.StatusInternalServerError)
+		return err
+	}
+
+	t := time.UnixMilli(request.Timestamp)
+	p.send(span, channel, request, &t)
+
+	response := &NewRelicResponse{
+		Message: "OK",
+	}
+
+	resp, err := json.Marshal(response)
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	if _, err := w.Write(resp); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewNewRelicProcessor(outputs *common.Outputs, observability *common.Observability) *NewRelicProcessor {
+
+	return &NewRelicProcessor{
+		outputs:  outputs,
+		logger:   observability.Logs(),
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all newrelic processor requests", []string{"channel"}, "newrelic", "processor"),
+		errors:   observability.Metrics().Counter("errors", "Count of all newrelic processor errors", []string{"channel"}, "newrelic", "processor"),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,13 +53,56 @@ func (p *Site24x7Processor) EventType() string {
 	return common.AsEventType(Site24x7ProcessorType())
 }
 
-func (p *Site24x7Processor) send(span sreCommon.TracerSpan, channel string, o interface{}, t *time.Time) {
+// setAlert maps status of monitor: DOWN and CRITICAL are critical, TROUBLE is warning, UP is resolved
+func (p *Site24x7Processor) setAlert(e *common.Event, request Site24x7Request) {
+
+	status := common.StatusFiring
+	if common.NormalizeSeverity(request.Status) == common.SeverityOk {
+		status = common.StatusResolved
+	}
+	key := ""
+	if request.MonitorID > 0 {
+		key = strconv.FormatInt(request.MonitorID, 10)
+	}
+	e.SetAlert(key, status, request.Status)
+	e.Alert = p.alertData(request, status)
+}
+
+// alertData maps monitor, incident time is start of incident or its end, if monitor is up
+func (p *Site24x7Processor) alertData(request Site24x7Request, status string) *common.AlertData {
+
+	a := &common.AlertData{
+		Title:       strings.TrimSpace(request.MonitorName),
+		Description: request.IncidentReason,
+		Source:      Site24x7ProcessorType(),
+	}
+	a.AddLabel("monitor_type", request.MonitorType)
+	a.AddLabel("monitor_group", request.MonitorGroupName)
+	a.AddLabel("failed_locations", request.FailedLocations)
+	a.AddLabel("tags", strings.Join(request.Tags, ","))
+	a.AddLabel("group_tags", strings.Join(request.GroupTags, ","))
+
+	a.AddLink("Dashboard", request.MonitorDashboardLink)
+	a.AddLink("Monitor", request.MonitorURL)
+
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", request.IncidentTimeISO); err == nil {
+		if status == common.StatusResolved {
+			a.SetEndedAt(t)
+		} else {
+			a.SetStartedAt(t)
+		}
+	}
+	return a
+}
+
+func (p *Site24x7Processor) send(span sreCommon.TracerSpan, channel string, request Site24x7Request, t *time.Time) {
 
 	e := &common.Event{
 		Channel: channel,
 		Type:    p.EventType(),
-		Data:    o,
+		Data:    request,
 	}
+	p.setAlert(e, request)
 	if t != nil && (*t).UnixNano() > 0 {
 		e.SetTime((*t).UTC())
 	} else {
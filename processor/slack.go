@@ -0,0 +1,278 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type SlackProcessorOptions struct {
+	SigningSecret string
+}
+
+type SlackProcessor struct {
+	options  SlackProcessorOptions
+	outputs  *common.Outputs
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type SlackUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	TeamID   string `json:"team_id,omitempty"`
+}
+
+type SlackChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SlackTeam struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type SlackContainer struct {
+	Type        string `json:"type"`
+	MessageTS   string `json:"message_ts"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+	ChannelID   string `json:"channel_id"`
+	IsEphemeral bool   `json:"is_ephemeral"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type SlackAction struct {
+	ActionID string     `json:"action_id"`
+	BlockID  string     `json:"block_id"`
+	Type     string     `json:"type"`
+	Value    string     `json:"value"`
+	Text     *SlackText `json:"text,omitempty"`
+	ActionTS string     `json:"action_ts"`
+}
+
+type SlackMessage struct {
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+	Text     string `json:"text"`
+}
+
+type SlackInteraction struct {
+	Type        string          `json:"type"`
+	Team        *SlackTeam      `json:"team"`
+	User        *SlackUser      `json:"user"`
+	Channel     *SlackChannel   `json:"channel"`
+	Container   *SlackContainer `json:"container"`
+	Message     *SlackMessage   `json:"message,omitempty"`
+	TriggerID   string          `json:"trigger_id"`
+	ResponseURL string          `json:"response_url"`
+	Actions     []*SlackAction  `json:"actions"`
+}
+
+type SlackActionRequest struct {
+	Action      *SlackAction    `json:"action"`
+	Team        *SlackTeam      `json:"team"`
+	User        *SlackUser      `json:"user"`
+	Channel     *SlackChannel   `json:"channel"`
+	Container   *SlackContainer `json:"container"`
+	Message     *SlackMessage   `json:"message,omitempty"`
+	ResponseURL string          `json:"response_url"`
+}
+
+type SlackResponse struct {
+	Message string
+}
+
+func SlackProcessorType() string {
+	return "SlackAction"
+}
+
+func (p *SlackProcessor) EventType() string {
+	return common.AsEventType(SlackProcessorType())
+}
+
+func (p *SlackProcessor) send(span sreCommon.TracerSpan, channel string, i *SlackInteraction) {
+
+	for _, action := range i.Actions {
+
+		if action == nil {
+			continue
+		}
+
+		e := &common.Event{
+			Channel: channel,
+			Type:    p.EventType(),
+			Data: &SlackActionRequest{
+				Action:      action,
+				Team:        i.Team,
+				User:        i.User,
+				Channel:     i.Channel,
+				Container:   i.Container,
+				Message:     i.Message,
+				ResponseURL: i.ResponseURL,
+			},
+		}
+
+		t := time.Now().UTC()
+		if f, err := strconv.ParseFloat(action.ActionTS, 64); err == nil {
+			sec, dec := math.Modf(f)
+			t = time.Unix(int64(sec), int64(dec*1e9)).UTC()
+		}
+		e.SetTime(t)
+
+		if span != nil {
+			e.SetSpanContext(span.GetContext())
+			e.SetLogger(p.logger)
+		}
+		p.outputs.Send(e)
+	}
+}
+
+// verify checks signature as described in https://api.slack.com/authentication/verifying-requests-from-slack,
+// requests are rejected if signing secret is not defined
+func (p *SlackProcessor) verify(r *http.Request, body []byte) error {
+
+	if utils.IsEmpty(p.options.SigningSecret) {
+		return errors.New("slack signing secret is not defined")
+	}
+
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	signature := r.Header.Get("X-Slack-Signature")
+	if utils.IsEmpty(timestamp) || utils.IsEmpty(signature) {
+		return errors.New("no slack signature")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if math.Abs(float64(time.Now().Unix()-ts)) > 5*60 {
+		return errors.New("slack request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.options.SigningSecret))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:", timestamp)))
+	mac.Write(body)
+	expected := fmt.Sprintf("v0=%s", hex.EncodeToString(mac.Sum(nil)))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("slack signature is invalid")
+	}
+	return nil
+}
+
+func (p *SlackProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+	p.requests.Inc(e.Channel)
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *SlackProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	span := p.tracer.StartChildSpan(r.Header)
+	defer span.Finish()
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	p.requests.Inc(channel)
+
+	var body []byte
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+
+	if len(body) == 0 {
+		p.errors.Inc(channel)
+		err := errors.New("empty body")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.SpanDebug(span, "Body => %s", body)
+
+	if err := p.verify(r, body); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return err
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	var interaction SlackInteraction
+	if err := json.Unmarshal([]byte(values.Get("payload")), &interaction); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't decode payload: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	if interaction.Type != "block_actions" {
+		p.logger.SpanDebug(span, "Slack interaction type %s is not supported", interaction.Type)
+	} else {
+		p.send(span, channel, &interaction)
+	}
+
+	resp, err := json.Marshal(&SlackResponse{Message: "OK"})
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	if _, err := w.Write(resp); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewSlackProcessor(options SlackProcessorOptions, outputs *common.Outputs, observability *common.Observability) *SlackProcessor {
+
+	return &SlackProcessor{
+		options:  options,
+		outputs:  outputs,
+		tracer:   observability.Traces(),
+		logger:   observability.Logs(),
+		requests: observability.Metrics().Counter("requests", "Count of all slack processor requests", []string{"channel"}, "slack", "processor"),
+		errors:   observability.Metrics().Counter("errors", "Count of all slack processor errors", []string{"channel"}, "slack", "processor"),
+	}
+}
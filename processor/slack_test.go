@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+func newTestObservability() *common.Observability {
+	return common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+}
+
+func slackRequest(secret string) *http.Request {
+
+	body := url.Values{"payload": {`{"type":"block_actions","actions":[{"action_id":"ack","value":"1"}]}`}}.Encode()
+	r := httptest.NewRequest("POST", "/slack", strings.NewReader(body))
+
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+		r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return r
+}
+
+func TestSlackProcessorSignature(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		secret string
+		signed string
+		code   int
+	}{
+		{name: "no secret", secret: "", signed: "", code: http.StatusUnauthorized},
+		{name: "no secret signed", secret: "", signed: "secret", code: http.StatusUnauthorized},
+		{name: "unsigned", secret: "secret", signed: "", code: http.StatusUnauthorized},
+		{name: "wrong secret", secret: "secret", signed: "other", code: http.StatusUnauthorized},
+		{name: "signed", secret: "secret", signed: "secret", code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			observability := newTestObservability()
+			outputs := common.NewOutputs(observability.Logs())
+			p := NewSlackProcessor(SlackProcessorOptions{SigningSecret: tt.secret}, &outputs, observability)
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, slackRequest(tt.signed))
+
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package processor
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type TelegramProcessorOptions struct {
+	AllowedChats string
+	AllowedUsers string
+	Commands     string
+	SecretToken  string
+}
+
+type TelegramProcessor struct {
+	options  TelegramProcessorOptions
+	outputs  *common.Outputs
+	tracer   sreCommon.Tracer
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+type TelegramChat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type TelegramMessage struct {
+	MessageID      int64            `json:"message_id"`
+	From           *TelegramUser    `json:"from,omitempty"`
+	Chat           *TelegramChat    `json:"chat"`
+	Date           int64            `json:"date"`
+	Text           string           `json:"text,omitempty"`
+	ReplyToMessage *TelegramMessage `json:"reply_to_message,omitempty"`
+}
+
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message,omitempty"`
+}
+
+type TelegramCommand struct {
+	Command   string        `json:"command"`
+	Args      []string      `json:"args,omitempty"`
+	ID        string        `json:"id,omitempty"`
+	Matchers  string        `json:"matchers,omitempty"`
+	Duration  string        `json:"duration,omitempty"`
+	Text      string        `json:"text"`
+	MessageID int64         `json:"message_id"`
+	ReplyTo   int64         `json:"reply_to,omitempty"`
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      *TelegramChat `json:"chat"`
+}
+
+type TelegramResponse struct {
+	Message string
+}
+
+func TelegramProcessorType() string {
+	return "TelegramCommand"
+}
+
+func (p *TelegramProcessor) EventType() string {
+	return common.AsEventType(TelegramProcessorType())
+}
+
+// allowed checks id in comma separated list, empty list denies all, "*" allows all
+func (p *TelegramProcessor) allowed(list string, id int64) bool {
+
+	s := strconv.FormatInt(id, 10)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parse splits text like "/silence@bot alertname=Foo 2h" into command and its arguments
+func (p *TelegramProcessor) parse(m *TelegramMessage) *TelegramCommand {
+
+	text := strings.TrimSpace(m.Text)
+	if !strings.HasPrefix(text, "/") {
+		return nil
+	}
+
+	fields := strings.Fields(text)
+	command := strings.SplitN(strings.TrimPrefix(fields[0], "/"), "@", 2)[0]
+	command = strings.ToLower(command)
+
+	if !utils.IsEmpty(p.options.Commands) && !utils.Contains(strings.Split(p.options.Commands, ","), command) {
+		return nil
+	}
+
+	c := &TelegramCommand{
+		Command:   command,
+		Args:      fields[1:],
+		Text:      text,
+		MessageID: m.MessageID,
+		From:      m.From,
+		Chat:      m.Chat,
+	}
+
+	if m.ReplyToMessage != nil {
+		c.ReplyTo = m.ReplyToMessage.MessageID
+	}
+
+	switch command {
+	case "ack":
+		if len(c.Args) > 0 {
+			c.ID = c.Args[0]
+		}
+	case "silence":
+		args := c.Args
+		if len(args) > 0 {
+			if _, err := time.ParseDuration(args[len(args)-1]); err == nil {
+				c.Duration = args[len(args)-1]
+				args = args[:len(args)-1]
+			}
+		}
+		c.Matchers = strings.Join(args, " ")
+	}
+	return c
+}
+
+func (p *TelegramProcessor) HandleUpdate(span sreCommon.TracerSpan, channel string, u *TelegramUpdate) error {
+
+	if u == nil || u.Message == nil || u.Message.Chat == nil {
+		return nil
+	}
+
+	m := u.Message
+	if m.From == nil || !p.allowed(p.options.AllowedUsers, m.From.ID) || !p.allowed(p.options.AllowedChats, m.Chat.ID) {
+		err := fmt.Errorf("telegram chat %d or user is not allowed", m.Chat.ID)
+		p.logger.SpanDebug(span, err)
+		return err
+	}
+
+	c := p.parse(m)
+	if c == nil {
+		p.logger.SpanDebug(span, "Telegram message is not a command => %s", m.Text)
+		return nil
+	}
+
+	e := &common.Event{
+		Channel: channel,
+		Type:    p.EventType(),
+		Data:    c,
+	}
+	e.SetTime(time.Unix(m.Date, 0).UTC())
+	if span != nil {
+		e.SetSpanContext(span.GetContext())
+		e.SetLogger(p.logger)
+	}
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *TelegramProcessor) HandleEvent(e *common.Event) error {
+
+	if e == nil {
+		p.logger.Debug("Event is not defined")
+		return nil
+	}
+	p.requests.Inc(e.Channel)
+	p.outputs.Send(e)
+	return nil
+}
+
+func (p *TelegramProcessor) HandleHttpRequest(w http.ResponseWriter, r *http.Request) error {
+
+	span := p.tracer.StartChildSpan(r.Header)
+	defer span.Finish()
+
+	channel := strings.TrimLeft(r.URL.Path, "/")
+	p.requests.Inc(channel)
+
+	// webhook requires secret token, which is set by setWebhook
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if utils.IsEmpty(p.options.SecretToken) || subtle.ConstantTimeCompare([]byte(token), []byte(p.options.SecretToken)) != 1 {
+		p.errors.Inc(channel)
+		err := errors.New("telegram secret token is invalid")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return err
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+
+	if len(body) == 0 {
+		p.errors.Inc(channel)
+		err := errors.New("empty body")
+		p.logger.SpanError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	p.logger.SpanDebug(span, "Body => %s", body)
+
+	var update TelegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't decode body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	// telegram retries webhook on non 2xx responses, so not allowed updates are only logged
+	p.HandleUpdate(span, channel, &update)
+
+	resp, err := json.Marshal(&TelegramResponse{Message: "OK"})
+	if err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+
+	if _, err := w.Write(resp); err != nil {
+		p.errors.Inc(channel)
+		p.logger.SpanError(span, "Can't write response: %v", err)
+		http.Error(w, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+func NewTelegramProcessor(options TelegramProcessorOptions, outputs *common.Outputs, observability *common.Observability) *TelegramProcessor {
+
+	return &TelegramProcessor{
+		options:  options,
+		outputs:  outputs,
+		tracer:   observability.Traces(),
+		logger:   observability.Logs(),
+		requests: observability.Metrics().Counter("requests", "Count of all telegram processor requests", []string{"channel"}, "telegram", "processor"),
+		errors:   observability.Metrics().Counter("errors", "Count of all telegram processor errors", []string{"channel"}, "telegram", "processor"),
+	}
+}
@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devopsext/events/common"
+)
+
+func newTestTelegramProcessor(options TelegramProcessorOptions) *TelegramProcessor {
+
+	observability := newTestObservability()
+	outputs := common.NewOutputs(observability.Logs())
+	return NewTelegramProcessor(options, &outputs, observability)
+}
+
+func TestTelegramProcessorAllowed(t *testing.T) {
+
+	update := func(user, chat int64) *TelegramUpdate {
+		return &TelegramUpdate{Message: &TelegramMessage{
+			Text: "/ack 1",
+			From: &TelegramUser{ID: user},
+			Chat: &TelegramChat{ID: chat},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		users   string
+		chats   string
+		allowed bool
+	}{
+		{name: "empty lists", users: "", chats: "", allowed: false},
+		{name: "empty chats", users: "1", chats: "", allowed: false},
+		{name: "listed", users: "3, 1", chats: "-100", allowed: true},
+		{name: "not listed", users: "2", chats: "-100", allowed: false},
+		{name: "all", users: "*", chats: "*", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p := newTestTelegramProcessor(TelegramProcessorOptions{AllowedUsers: tt.users, AllowedChats: tt.chats})
+			err := p.HandleUpdate(nil, "test", update(1, -100))
+			if (err == nil) != tt.allowed {
+				t.Errorf("expected allowed %v, got error %v", tt.allowed, err)
+			}
+		})
+	}
+}
+
+func TestTelegramProcessorParse(t *testing.T) {
+
+	tests := []struct {
+		text     string
+		matchers string
+		duration string
+	}{
+		{text: "/silence 2h", matchers: "", duration: "2h"},
+		{text: "/silence@bot alertname=Foo 2h", matchers: "alertname=Foo", duration: "2h"},
+		{text: "/silence alertname=Foo job=bar", matchers: "alertname=Foo job=bar", duration: ""},
+		{text: "/silence", matchers: "", duration: ""},
+	}
+
+	p := newTestTelegramProcessor(TelegramProcessorOptions{})
+	for _, tt := range tests {
+		c := p.parse(&TelegramMessage{Text: tt.text})
+		if c == nil || c.Command != "silence" {
+			t.Fatalf("%s is not parsed as silence", tt.text)
+		}
+		if c.Matchers != tt.matchers || c.Duration != tt.duration {
+			t.Errorf("%s => matchers %q, duration %q", tt.text, c.Matchers, c.Duration)
+		}
+	}
+}
+
+func TestTelegramProcessorSecretToken(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		code   int
+	}{
+		{name: "no secret", secret: "", header: "", code: http.StatusUnauthorized},
+		{name: "no header", secret: "secret", header: "", code: http.StatusUnauthorized},
+		{name: "wrong header", secret: "secret", header: "other", code: http.StatusUnauthorized},
+		{name: "valid", secret: "secret", header: "secret", code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p := newTestTelegramProcessor(TelegramProcessorOptions{SecretToken: tt.secret})
+			r := httptest.NewRequest("POST", "/telegram", strings.NewReader(`{"update_id":1}`))
+			if tt.header != "" {
+				r.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			p.HandleHttpRequest(w, r)
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
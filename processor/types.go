@@ -0,0 +1,25 @@
+package processor
+
+import (
+	"github.com/devopsext/events/common"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// EventDataTypes returns values of data which processors send by event type, template linter checks field
+// references against them. Github and Gitlab send different payloads by hook, so they are not checked
+func EventDataTypes() map[string]interface{} {
+
+	return map[string]interface{}{
+		common.AsEventType(AlertmanagerProcessorType()):           template.Alert{},
+		common.AsEventType(AlertmanagerProcessorType() + "Group"): AlertmanagerWebhookMessage{},
+		common.AsEventType(AWSProcessorType()):                    AWSRequest{},
+		common.AsEventType(CloudflareProcessorType()):             CloudflareRequest{},
+		common.AsEventType(DataDogProcessorType()):                DataDogRequest{},
+		common.AsEventType(GoogleProcessorType()):                 GoogleRequest{},
+		common.AsEventType(K8sProcessorType()):                    K8sData{},
+		common.AsEventType(NewRelicProcessorType()):               NewRelicRequest{},
+		common.AsEventType(Site24x7ProcessorType()):               Site24x7Request{},
+		common.AsEventType(SlackProcessorType()):                  SlackActionRequest{},
+		common.AsEventType(TelegramProcessorType()):               TelegramCommand{},
+	}
+}
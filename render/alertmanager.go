@@ -0,0 +1,296 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	utils "github.com/devopsext/utils"
+)
+
+type AlertmanagerApiOptions struct {
+	URL     string
+	Timeout int
+}
+
+type AlertmanagerApi struct {
+	client   *http.Client
+	options  AlertmanagerApiOptions
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type AlertmanagerMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type AlertmanagerSilence struct {
+	ID        string                 `json:"id,omitempty"`
+	Matchers  []*AlertmanagerMatcher `json:"matchers"`
+	StartsAt  time.Time              `json:"startsAt"`
+	EndsAt    time.Time              `json:"endsAt"`
+	CreatedBy string                 `json:"createdBy"`
+	Comment   string                 `json:"comment"`
+}
+
+type AlertmanagerAlertStatus struct {
+	State       string   `json:"state"`
+	SilencedBy  []string `json:"silencedBy"`
+	InhibitedBy []string `json:"inhibitedBy"`
+}
+
+type AlertmanagerReceiver struct {
+	Name string `json:"name"`
+}
+
+type AlertmanagerAlert struct {
+	Labels       map[string]string       `json:"labels"`
+	Annotations  map[string]string       `json:"annotations"`
+	StartsAt     time.Time               `json:"startsAt"`
+	EndsAt       time.Time               `json:"endsAt"`
+	UpdatedAt    time.Time               `json:"updatedAt"`
+	Fingerprint  string                  `json:"fingerprint"`
+	GeneratorURL string                  `json:"generatorURL"`
+	Status       AlertmanagerAlertStatus `json:"status"`
+	Receivers    []*AlertmanagerReceiver `json:"receivers"`
+}
+
+// ParseAlertmanagerMatchers parses matchers like {alertname="Foo",env=~"prod|stage"} or alertname=Foo env!=dev
+func ParseAlertmanagerMatchers(s string) ([]*AlertmanagerMatcher, error) {
+
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+
+	var items []string
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(s):
+			b.WriteByte(c)
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		case c == '"':
+			quoted = !quoted
+		case (c == ',' || c == ' ') && !quoted:
+			items = append(items, b.String())
+			b.Reset()
+			continue
+		}
+		b.WriteByte(c)
+	}
+	items = append(items, b.String())
+
+	var matchers []*AlertmanagerMatcher
+	for _, item := range items {
+
+		item = strings.TrimSpace(item)
+		if utils.IsEmpty(item) {
+			continue
+		}
+
+		m := &AlertmanagerMatcher{IsEqual: true}
+		op := ""
+		for _, o := range []string{"!=", "=~", "!~", "="} {
+			if idx := strings.Index(item, o); idx > 0 && (op == "" || idx < strings.Index(item, op)) {
+				op = o
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("alertmanager matcher %s is invalid", item)
+		}
+
+		pair := strings.SplitN(item, op, 2)
+		m.Name = strings.TrimSpace(pair[0])
+		m.Value = strings.TrimSpace(pair[1])
+		if strings.HasPrefix(m.Value, "\"") {
+			v, err := unquoteMatcherValue(m.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = v
+		}
+		m.IsRegex = op == "=~" || op == "!~"
+		m.IsEqual = op == "=" || op == "=~"
+		matchers = append(matchers, m)
+	}
+
+	if len(matchers) == 0 {
+		return nil, errors.New("alertmanager matchers are empty")
+	}
+	return matchers, nil
+}
+
+func unquoteMatcherValue(s string) (string, error) {
+
+	var v string
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return "", fmt.Errorf("alertmanager matcher value %s is invalid", s)
+	}
+	return v, nil
+}
+
+func (m *AlertmanagerMatcher) String() string {
+
+	op := "="
+	switch {
+	case m.IsRegex && m.IsEqual:
+		op = "=~"
+	case m.IsRegex && !m.IsEqual:
+		op = "!~"
+	case !m.IsEqual:
+		op = "!="
+	}
+	b, _ := json.Marshal(m.Value)
+	return fmt.Sprintf("%s%s%s", m.Name, op, b)
+}
+
+func (a *AlertmanagerApi) call(method, path string, query url.Values, obj interface{}) ([]byte, error) {
+
+	URL := fmt.Sprintf("%s/api/v2/%s", strings.TrimRight(a.options.URL, "/"), path)
+	if len(query) > 0 {
+		URL = fmt.Sprintf("%s?%s", URL, query.Encode())
+	}
+
+	var body *bytes.Reader
+	if obj != nil {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, URL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	a.requests.Inc(path)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		a.errors.Inc(path)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		a.errors.Inc(path)
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		a.errors.Inc(path)
+		return nil, fmt.Errorf("alertmanager response: %s %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// CreateSilence creates or updates silence if ID is set, returns ID of silence
+func (a *AlertmanagerApi) CreateSilence(silence *AlertmanagerSilence) (string, error) {
+
+	b, err := a.call("POST", "silences", nil, silence)
+	if err != nil {
+		return "", err
+	}
+
+	var r struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return "", err
+	}
+	return r.SilenceID, nil
+}
+
+// ExpireSilence expires silence by ID, so alerts are notified again
+func (a *AlertmanagerApi) ExpireSilence(id string) error {
+
+	if utils.IsEmpty(id) {
+		return errors.New("alertmanager silence ID is empty")
+	}
+	_, err := a.call("DELETE", fmt.Sprintf("silence/%s", url.PathEscape(id)), nil, nil)
+	return err
+}
+
+// GetAlerts returns active not silenced and not inhibited alerts matched by matchers
+func (a *AlertmanagerApi) GetAlerts(matchers string) ([]*AlertmanagerAlert, error) {
+
+	query := url.Values{}
+	query.Set("active", "true")
+	query.Set("silenced", "false")
+	query.Set("inhibited", "false")
+
+	if !utils.IsEmpty(matchers) {
+		list, err := ParseAlertmanagerMatchers(matchers)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range list {
+			query.Add("filter", m.String())
+		}
+	}
+
+	b, err := a.call("GET", "alerts", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*AlertmanagerAlert
+	if err := json.Unmarshal(b, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// GetAlert returns alert by fingerprint or nil if it's not active anymore
+func (a *AlertmanagerApi) GetAlert(fingerprint string) (*AlertmanagerAlert, error) {
+
+	alerts, err := a.GetAlerts("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alert := range alerts {
+		if alert.Fingerprint == fingerprint {
+			return alert, nil
+		}
+	}
+	return nil, nil
+}
+
+func NewAlertmanagerApi(options AlertmanagerApiOptions, observability *common.Observability) *AlertmanagerApi {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.URL) {
+		logger.Debug("Alertmanager API URL is not defined. Skipped")
+		return nil
+	}
+
+	return &AlertmanagerApi{
+		client:   utils.NewHttpClient(options.Timeout, false),
+		options:  options,
+		logger:   logger,
+		requests: observability.Metrics().Counter("requests", "Count of all alertmanager api requests", []string{"path"}, "alertmanager", "api"),
+		errors:   observability.Metrics().Counter("errors", "Count of all alertmanager api errors", []string{"path"}, "alertmanager", "api"),
+	}
+}
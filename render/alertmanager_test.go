@@ -0,0 +1,181 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+const alertmanagerTestAlerts = `[
+	{"fingerprint":"f1","labels":{"alertname":"DiskFull","service":"api"},"status":{"state":"active"}},
+	{"fingerprint":"f2","labels":{"alertname":"HighLatency","service":"api"},"status":{"state":"active"}}
+]`
+
+// alertmanagerStandIn is Alertmanager v2 API stand-in, it keeps silences and responds with fixed alerts
+type alertmanagerStandIn struct {
+	server   *httptest.Server
+	mutex    sync.Mutex
+	silences map[string]*AlertmanagerSilence
+	queries  []string
+}
+
+func newAlertmanagerStandIn(t *testing.T) *alertmanagerStandIn {
+
+	s := &alertmanagerStandIn{silences: make(map[string]*AlertmanagerSilence)}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v2/silences":
+			var silence AlertmanagerSilence
+			if err := json.NewDecoder(r.Body).Decode(&silence); err != nil || len(silence.Matchers) == 0 {
+				http.Error(w, "silence is invalid", http.StatusBadRequest)
+				return
+			}
+			if silence.ID == "" {
+				silence.ID = fmt.Sprintf("s%d", len(s.silences)+1)
+			}
+			s.silences[silence.ID] = &silence
+			fmt.Fprintf(w, `{"silenceID":"%s"}`, silence.ID)
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+			id := strings.TrimPrefix(r.URL.Path, "/api/v2/silence/")
+			if _, ok := s.silences[id]; !ok {
+				http.Error(w, "silence not found", http.StatusNotFound)
+				return
+			}
+			delete(s.silences, id)
+		case r.Method == "GET" && r.URL.Path == "/api/v2/alerts":
+			s.queries = append(s.queries, r.URL.RawQuery)
+			fmt.Fprint(w, alertmanagerTestAlerts)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func newTestAlertmanagerApi(URL string) *AlertmanagerApi {
+
+	observability := common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+	return NewAlertmanagerApi(AlertmanagerApiOptions{URL: URL, Timeout: 5}, observability)
+}
+
+func TestAlertmanagerApiSilence(t *testing.T) {
+
+	am := newAlertmanagerStandIn(t)
+	api := newTestAlertmanagerApi(am.server.URL)
+
+	matchers, err := ParseAlertmanagerMatchers(`{alertname="DiskFull",service=~"api|web"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := api.CreateSilence(&AlertmanagerSilence{Matchers: matchers, CreatedBy: "events", Comment: "maintenance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "s1" || am.silences[id] == nil || am.silences[id].Comment != "maintenance" {
+		t.Fatalf("silence isn't created: %s %v", id, am.silences)
+	}
+	if m := am.silences[id].Matchers[1]; m.Name != "service" || m.Value != "api|web" || !m.IsRegex || !m.IsEqual {
+		t.Errorf("unexpected matcher %+v", m)
+	}
+
+	if err := api.ExpireSilence(id); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.silences) != 0 {
+		t.Errorf("silence isn't expired: %v", am.silences)
+	}
+
+	// unknown silence is error of alertmanager
+	err = api.ExpireSilence(id)
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "silence not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if err := api.ExpireSilence(""); err == nil {
+		t.Error("empty ID doesn't fail")
+	}
+
+	if _, err := api.CreateSilence(&AlertmanagerSilence{}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected bad request error, got %v", err)
+	}
+}
+
+func TestAlertmanagerApiAlerts(t *testing.T) {
+
+	am := newAlertmanagerStandIn(t)
+	api := newTestAlertmanagerApi(am.server.URL)
+
+	alerts, err := api.GetAlerts(`service="api"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+	if q := am.queries[0]; !strings.Contains(q, "active=true") || !strings.Contains(q, "silenced=false") ||
+		!strings.Contains(q, "filter=service%3D%22api%22") {
+		t.Errorf("unexpected query %s", q)
+	}
+
+	alert, err := api.GetAlert("f2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert == nil || alert.Labels["alertname"] != "HighLatency" {
+		t.Errorf("alert isn't found by fingerprint: %+v", alert)
+	}
+
+	alert, err = api.GetAlert("f3")
+	if err != nil || alert != nil {
+		t.Errorf("alert which isn't active is found: %+v %v", alert, err)
+	}
+
+	if _, err := api.GetAlerts("service"); err == nil {
+		t.Error("invalid matchers don't fail")
+	}
+
+	am.server.Close()
+	if _, err := api.GetAlerts(""); err == nil {
+		t.Error("unavailable alertmanager doesn't fail")
+	}
+}
+
+func TestParseAlertmanagerMatchers(t *testing.T) {
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: `{alertname="Foo",env=~"prod|stage"}`, want: `[alertname="Foo" env=~"prod|stage"]`},
+		{text: `alertname=Foo env!=dev`, want: `[alertname="Foo" env!="dev"]`},
+		{text: `{job!~"node.*", msg="a, b"}`, want: `[job!~"node.*" msg="a, b"]`},
+		{text: `{msg="say \"hi\""}`, want: `[msg="say \"hi\""]`},
+		{text: `env`, want: "error"},
+		{text: `{}`, want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			matchers, err := ParseAlertmanagerMatchers(tt.text)
+			got := fmt.Sprint(matchers)
+			if err != nil {
+				got = "error"
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+type ChartRenderOptions struct {
+	Period      int
+	Step        int
+	ImageWidth  int
+	ImageHeight int
+}
+
+// ChartRender queries Prometheus compatible datasource and draws time series chart in pure Go,
+// so it doesn't need Grafana with image renderer plugin
+type ChartRender struct {
+	api     *PrometheusApi
+	options ChartRenderOptions
+	logger  sreCommon.Logger
+	tracer  sreCommon.Tracer
+	counter sreCommon.Counter
+}
+
+type chartArea struct {
+	left, top, right, bottom int
+}
+
+const (
+	chartMaxLegend = 5
+	chartLineSize  = 12
+)
+
+var (
+	chartBackground = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	chartGrid       = color.RGBA{0xE6, 0xE6, 0xE6, 0xFF}
+	chartAxis       = color.RGBA{0x80, 0x80, 0x80, 0xFF}
+	chartText       = color.RGBA{0x33, 0x33, 0x33, 0xFF}
+	chartThreshold  = color.RGBA{0xE0, 0x2F, 0x44, 0xFF}
+	chartPalette    = []color.RGBA{
+		{0x73, 0xBF, 0x69, 0xFF},
+		{0xF2, 0xCC, 0x0C, 0xFF},
+		{0x8A, 0xB8, 0xFF, 0xFF},
+		{0xFF, 0x78, 0x0A, 0xFF},
+		{0xB8, 0x77, 0xD9, 0xFF},
+		{0x1F, 0x60, 0xC4, 0xFF},
+		{0x37, 0x87, 0x2D, 0xFF},
+		{0xFA, 0x64, 0x00, 0xFF},
+	}
+	chartFileName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// chartNiceStep returns 1, 2 or 5 multiplied by power of 10 close to raw step
+func chartNiceStep(raw float64) float64 {
+
+	if raw <= 0 || math.IsNaN(raw) || math.IsInf(raw, 0) {
+		return 1
+	}
+
+	exp := math.Floor(math.Log10(raw))
+	base := math.Pow(10, exp)
+	f := raw / base
+
+	switch {
+	case f <= 1:
+		f = 1
+	case f <= 2:
+		f = 2
+	case f <= 5:
+		f = 5
+	default:
+		f = 10
+	}
+	return f * base
+}
+
+func chartFormatValue(v float64, unit string) string {
+
+	s := strconv.FormatFloat(v, 'g', 4, 64)
+	switch unit {
+	case "":
+		return s
+	case "percent":
+		return s + "%"
+	case "percentunit":
+		return strconv.FormatFloat(v*100, 'g', 4, 64) + "%"
+	default:
+		return fmt.Sprintf("%s %s", s, unit)
+	}
+}
+
+func chartSeriesName(metric map[string]string) string {
+
+	name := metric["__name__"]
+	var keys []string
+	for k := range metric {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", k, metric[k]))
+	}
+	if len(pairs) == 0 {
+		if name == "" {
+			return "value"
+		}
+		return name
+	}
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+func chartTruncate(s string, width int) string {
+
+	r := []rune(s)
+	for len(r) > 0 && textWidth(string(r), 1) > width {
+		r = r[:len(r)-1]
+	}
+	if len(r) < len([]rune(s)) && len(r) > 3 {
+		r = append(r[:len(r)-3], []rune("...")...)
+	}
+	return string(r)
+}
+
+func chartHLine(img *image.RGBA, x1, x2, y int, c color.Color, dash int) {
+
+	for x := x1; x <= x2; x++ {
+		if dash > 0 && ((x-x1)/dash)%2 == 1 {
+			continue
+		}
+		img.Set(x, y, c)
+	}
+}
+
+func chartVLine(img *image.RGBA, x, y1, y2 int, c color.Color) {
+
+	for y := y1; y <= y2; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// chartLine draws 2px line with Bresenham's algorithm
+func chartLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+
+	dx := int(math.Abs(float64(x1 - x0)))
+	dy := -int(math.Abs(float64(y1 - y0)))
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		img.Set(x0, y0, c)
+		img.Set(x0, y0+1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *ChartRender) valueRange(series []*PrometheusSeries, threshold *float64) (float64, float64, bool) {
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, v := range s.Values {
+			if v.Value == nil {
+				continue
+			}
+			min = math.Min(min, *v.Value)
+			max = math.Max(max, *v.Value)
+		}
+	}
+
+	if math.IsInf(min, 1) {
+		return 0, 0, false
+	}
+
+	if threshold != nil {
+		min = math.Min(min, *threshold)
+		max = math.Max(max, *threshold)
+	}
+
+	if min == max {
+		d := math.Abs(min) * 0.1
+		if d == 0 {
+			d = 1
+		}
+		return min - d, max + d, true
+	}
+
+	pad := (max - min) * 0.05
+	return min - pad, max + pad, true
+}
+
+func (c *ChartRender) draw(request *ImageRequest, series []*PrometheusSeries, start, end time.Time) (*image.RGBA, error) {
+
+	width, height := c.options.ImageWidth, c.options.ImageHeight
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	min, max, ok := c.valueRange(series, request.Value)
+	if !ok {
+		return nil, fmt.Errorf("no data for %s", request.Metric)
+	}
+
+	step := chartNiceStep((max - min) / 5)
+	first := math.Ceil(min/step) * step
+
+	var ticks []float64
+	labelWidth := 0
+	for v := first; v <= max; v += step {
+		ticks = append(ticks, v)
+		if w := textWidth(chartFormatValue(v, request.Unit), 1); w > labelWidth {
+			labelWidth = w
+		}
+	}
+
+	legend := len(series)
+	if legend > chartMaxLegend {
+		legend = chartMaxLegend + 1
+	}
+
+	area := chartArea{
+		left:   labelWidth + 16,
+		top:    36,
+		right:  width - 20,
+		bottom: height - 28 - legend*chartLineSize,
+	}
+	if area.right-area.left < 50 || area.bottom-area.top < 50 {
+		return nil, errors.New("chart image is too small")
+	}
+
+	x := func(t time.Time) int {
+		return area.left + int(float64(area.right-area.left)*float64(t.Sub(start))/float64(end.Sub(start)))
+	}
+	y := func(v float64) int {
+		return area.bottom - int(float64(area.bottom-area.top)*(v-min)/(max-min))
+	}
+
+	drawText(img, area.left, 10, chartTruncate(request.Title, (width-area.left)/2), chartText, 2)
+
+	// horizontal grid with values
+	for _, v := range ticks {
+		yy := y(v)
+		chartHLine(img, area.left, area.right, yy, chartGrid, 0)
+		label := chartFormatValue(v, request.Unit)
+		drawText(img, area.left-8-textWidth(label, 1), yy-fontHeight/2, label, chartText, 1)
+	}
+
+	// vertical grid with time
+	layout := "15:04"
+	if end.Sub(start) > 24*time.Hour {
+		layout = "02.01 15:04"
+	}
+	for i := 0; i <= 6; i++ {
+		t := start.Add(time.Duration(int64(end.Sub(start)) * int64(i) / 6))
+		xx := x(t)
+		chartVLine(img, xx, area.top, area.bottom, chartGrid)
+		label := t.UTC().Format(layout)
+		drawText(img, xx-textWidth(label, 1)/2, area.bottom+8, label, chartText, 1)
+	}
+
+	chartHLine(img, area.left, area.right, area.bottom, chartAxis, 0)
+	chartVLine(img, area.left, area.top, area.bottom, chartAxis)
+
+	for i, s := range series {
+		col := chartPalette[i%len(chartPalette)]
+		var prev *PrometheusSample
+		for _, v := range s.Values {
+			if v.Value == nil {
+				prev = nil
+				continue
+			}
+			if prev != nil {
+				chartLine(img, x(prev.Time), y(*prev.Value), x(v.Time), y(*v.Value), col)
+			} else {
+				img.Set(x(v.Time), y(*v.Value), col)
+			}
+			prev = v
+		}
+	}
+
+	if request.Value != nil {
+		yy := y(*request.Value)
+		chartHLine(img, area.left, area.right, yy, chartThreshold, 6)
+		chartHLine(img, area.left, area.right, yy+1, chartThreshold, 6)
+		label := strings.TrimSpace(fmt.Sprintf("%s %s", request.Operator, chartFormatValue(*request.Value, request.Unit)))
+		drawText(img, area.right-textWidth(label, 1), yy-fontHeight-4, label, chartThreshold, 1)
+	}
+
+	// legend
+	top := area.bottom + 24
+	for i, s := range series {
+		if i == chartMaxLegend {
+			drawText(img, area.left, top+i*chartLineSize, fmt.Sprintf("+%d more", len(series)-chartMaxLegend), chartText, 1)
+			break
+		}
+		col := chartPalette[i%len(chartPalette)]
+		yy := top + i*chartLineSize
+		draw.Draw(img, image.Rect(area.left, yy+1, area.left+12, yy+fontHeight-1), &image.Uniform{col}, image.Point{}, draw.Src)
+		drawText(img, area.left+18, yy, chartTruncate(chartSeriesName(s.Metric), area.right-area.left-18), chartText, 1)
+	}
+	return img, nil
+}
+
+func (c *ChartRender) Render(spanCtx sreCommon.TracerSpanContext, request *ImageRequest) ([]byte, string, error) {
+
+	span := c.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	if c.api == nil {
+		return nil, "", errors.New("prometheus api is not defined")
+	}
+
+	period := c.options.Period
+	if request.Minutes != nil {
+		period = *request.Minutes
+	}
+
+	end := time.Now().UTC()
+	start := end.Add(-time.Duration(period) * time.Minute)
+
+	step := time.Duration(c.options.Step) * time.Second
+	if step <= 0 {
+		// about one point per two pixels
+		step = end.Sub(start) / time.Duration(c.options.ImageWidth/2+1)
+		if step < 15*time.Second {
+			step = 15 * time.Second
+		}
+	}
+
+	series, err := c.api.QueryRange(request.Metric, start, end, step)
+	if err != nil {
+		c.logger.SpanError(span, err)
+		return nil, "", err
+	}
+
+	b, err := c.png(request, series, start, end)
+	if err != nil {
+		c.logger.SpanError(span, err)
+		return nil, "", err
+	}
+
+	c.counter.Inc(request.Title)
+	return b, chartImageName(request.Title), nil
+}
+
+// png draws series of any datasource, so chart providers use it for non Prometheus alerts
+func (c *ChartRender) png(request *ImageRequest, series []*PrometheusSeries, start, end time.Time) ([]byte, error) {
+
+	img, err := c.draw(request, series, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func chartImageName(title string) string {
+
+	name := strings.Trim(chartFileName.ReplaceAllString(title, "_"), "_")
+	if name == "" {
+		name = "chart"
+	}
+	return fmt.Sprintf("%s.png", name)
+}
+
+func NewChartRender(options ChartRenderOptions, prometheus *PrometheusApi, observability *common.Observability) *ChartRender {
+
+	logger := observability.Logs()
+	if prometheus == nil {
+		logger.Debug("Chart render Prometheus API is not defined. Skipped")
+		return nil
+	}
+
+	return newChartRender(options, prometheus, observability)
+}
+
+func newChartRender(options ChartRenderOptions, prometheus *PrometheusApi, observability *common.Observability) *ChartRender {
+
+	return &ChartRender{
+		api:     prometheus,
+		options: options,
+		logger:  observability.Logs(),
+		tracer:  observability.Traces(),
+		counter: observability.Metrics().Counter("requests", "Count of all chart renders", []string{"title"}, "chart", "render"),
+	}
+}
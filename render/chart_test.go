@@ -0,0 +1,98 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+func newTestChartObservability() *common.Observability {
+	return common.NewObservability(sre.NewLogs(), sre.NewTraces(), sre.NewMetrics(), sre.NewEvents())
+}
+
+func TestNewImageRender(t *testing.T) {
+
+	observability := newTestChartObservability()
+	prometheus := NewPrometheusApi(PrometheusApiOptions{URL: "http://127.0.0.1:9090"}, observability)
+	grafana := GrafanaRenderOptions{URL: "http://127.0.0.1:3000"}
+
+	if _, ok := NewImageRender("chart", grafana, ChartRenderOptions{}, prometheus, observability).(*ChartRender); !ok {
+		t.Fatal("expected chart render")
+	}
+	if r := NewImageRender("chart", grafana, ChartRenderOptions{}, nil, observability); r != nil {
+		t.Fatalf("expected no chart render without prometheus, got %T", r)
+	}
+	if _, ok := NewImageRender("", grafana, ChartRenderOptions{}, prometheus, observability).(*GrafanaRender); !ok {
+		t.Fatal("expected grafana render by default")
+	}
+	if r := NewImageRender("grafana", GrafanaRenderOptions{}, ChartRenderOptions{}, prometheus, observability); r != nil {
+		t.Fatalf("expected no grafana render without URL, got %T", r)
+	}
+}
+
+// chartThresholdRows returns count of image rows which have threshold color
+func chartThresholdRows(t *testing.T, b []byte) int {
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if uint8(r>>8) == chartThreshold.R && uint8(g>>8) == chartThreshold.G && uint8(b>>8) == chartThreshold.B {
+				rows++
+				break
+			}
+		}
+	}
+	return rows
+}
+
+func TestChartRenderThreshold(t *testing.T) {
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		queries = append(queries, r.FormValue("query"))
+		now := time.Now().Unix()
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"up","job":"api"},"values":[[%d,"1"],[%d,"3"],[%d,"2"]]}
+		]}}`, now-600, now-300, now)
+	}))
+	defer server.Close()
+
+	observability := newTestChartObservability()
+	prometheus := NewPrometheusApi(PrometheusApiOptions{URL: server.URL, Timeout: 5}, observability)
+	chart := NewChartRender(ChartRenderOptions{Period: 15, ImageWidth: 400, ImageHeight: 200}, prometheus, observability)
+
+	value := 2.5
+	b, name, err := chart.Render(nil, &ImageRequest{Title: "Up is high", Metric: "up", Operator: ">", Value: &value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Up_is_high.png" || len(queries) != 1 || queries[0] != "up" {
+		t.Fatalf("unexpected image %s of queries %v", name, queries)
+	}
+	if rows := chartThresholdRows(t, b); rows < 2 {
+		t.Fatalf("expected threshold to be drawn, got %d rows", rows)
+	}
+
+	b, _, err = chart.Render(nil, &ImageRequest{Title: "Up", Metric: "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := chartThresholdRows(t, b); rows != 0 {
+		t.Fatalf("expected no threshold without value, got %d rows", rows)
+	}
+}
@@ -0,0 +1,166 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// ChartImage is attached by chat outputs to event message, caption describes what is drawn
+type ChartImage struct {
+	Caption  string
+	FileName string
+	Image    []byte
+}
+
+// ChartProvider makes chart of vendor alert, nil image is returned if event has nothing to draw
+type ChartProvider interface {
+	EventType() string
+	Chart(spanCtx sreCommon.TracerSpanContext, jsonMap map[string]interface{}) (*ChartImage, error)
+}
+
+type ChartProviders struct {
+	list   map[string]ChartProvider
+	logger sreCommon.Logger
+}
+
+func (cs *ChartProviders) Add(p ChartProvider) {
+
+	if reflect.ValueOf(p).IsNil() {
+		return
+	}
+	cs.list[p.EventType()] = p
+}
+
+// Chart returns chart of event by provider of its type, errors are logged and message is sent without chart
+func (cs *ChartProviders) Chart(spanCtx sreCommon.TracerSpanContext, eventType string, jsonMap map[string]interface{}) *ChartImage {
+
+	if cs == nil {
+		return nil
+	}
+
+	p, ok := cs.list[eventType]
+	if !ok {
+		return nil
+	}
+
+	image, err := p.Chart(spanCtx, jsonMap)
+	if err != nil {
+		cs.logger.Error("%s chart failed: %v", eventType, err)
+		return nil
+	}
+	if image == nil || len(image.Image) == 0 {
+		return nil
+	}
+	return image
+}
+
+func NewChartProviders(logger sreCommon.Logger) *ChartProviders {
+
+	return &ChartProviders{
+		list:   make(map[string]ChartProvider),
+		logger: logger,
+	}
+}
+
+// chartMapValue walks json map by dot separated path and returns the first non empty value of paths
+func chartMapValue(m map[string]interface{}, paths ...string) interface{} {
+
+	for _, path := range paths {
+
+		var v interface{} = m
+		for _, k := range strings.Split(path, ".") {
+			mm, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = mm[k]
+		}
+
+		if v == nil {
+			continue
+		}
+		if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+			continue
+		}
+		return v
+	}
+	return nil
+}
+
+func chartMapString(m map[string]interface{}, paths ...string) string {
+
+	v := chartMapValue(m, paths...)
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strings.TrimSpace(fmt.Sprintf("%.0f", t))
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+// chartDownload gets image by URL, vendors return small placeholder until image is ready, so it's retried
+func chartDownload(client *http.Client, URL string, attempts int) ([]byte, error) {
+
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+
+		var resp *http.Response
+		resp, err = client.Get(URL)
+		if err != nil {
+			continue
+		}
+
+		var b []byte
+		b, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("chart image response: %s", resp.Status)
+			continue
+		}
+
+		// 1x1 placeholder is less than 180 bytes
+		if len(b) < 180 {
+			err = fmt.Errorf("chart image %s is not ready", URL)
+			continue
+		}
+		return b, nil
+	}
+	return nil, err
+}
+
+// chartOperators are comparisons of alert conditions which are drawn as threshold
+var chartOperators = []string{">=", "<=", "!=", "==", ">", "<"}
+
+func chartCaption(parts ...string) string {
+
+	var r []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			r = append(r, p)
+		}
+	}
+	return strings.Join(r, " ")
+}
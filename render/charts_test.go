@@ -0,0 +1,92 @@
+package render
+
+import (
+	"errors"
+	"testing"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+type testChartProvider struct {
+	eventType string
+	image     *ChartImage
+	err       error
+	calls     int
+}
+
+func (p *testChartProvider) EventType() string {
+	return p.eventType
+}
+
+func (p *testChartProvider) Chart(spanCtx sre.TracerSpanContext, jsonMap map[string]interface{}) (*ChartImage, error) {
+	p.calls++
+	return p.image, p.err
+}
+
+func TestChartProviders(t *testing.T) {
+
+	datadog := &testChartProvider{eventType: "DataDogEvent", image: &ChartImage{Caption: "datadog", Image: []byte{1}}}
+	google := &testChartProvider{eventType: "GoogleEvent", err: errors.New("failed")}
+	newrelic := &testChartProvider{eventType: "NewRelicEvent", image: &ChartImage{Caption: "empty"}}
+
+	charts := NewChartProviders(newTestChartObservability().Logs())
+	charts.Add(datadog)
+	charts.Add(google)
+	charts.Add(newrelic)
+
+	var disabled *NewRelicChart
+	charts.Add(disabled)
+	if len(charts.list) != 3 {
+		t.Fatalf("expected nil provider to be skipped, got %d providers", len(charts.list))
+	}
+
+	if image := charts.Chart(nil, "DataDogEvent", nil); image == nil || image.Caption != "datadog" {
+		t.Fatalf("expected datadog chart, got %v", image)
+	}
+	if image := charts.Chart(nil, "GoogleEvent", nil); image != nil {
+		t.Fatalf("expected no chart of failed provider, got %v", image)
+	}
+	if image := charts.Chart(nil, "NewRelicEvent", nil); image != nil {
+		t.Fatalf("expected no chart of empty image, got %v", image)
+	}
+	if image := charts.Chart(nil, "AlertmanagerEvent", nil); image != nil {
+		t.Fatalf("expected no chart of event without provider, got %v", image)
+	}
+	if datadog.calls != 1 || google.calls != 1 || newrelic.calls != 1 {
+		t.Fatalf("expected provider of event type to be called once, got %d %d %d", datadog.calls, google.calls, newrelic.calls)
+	}
+
+	var none *ChartProviders
+	if image := none.Chart(nil, "DataDogEvent", nil); image != nil {
+		t.Fatalf("expected no chart without providers, got %v", image)
+	}
+}
+
+func TestParseDataDogMonitorQuery(t *testing.T) {
+
+	tests := []struct {
+		query    string
+		metric   string
+		operator string
+		value    float64
+		minutes  int
+	}{
+		{"avg(last_5m):avg:system.cpu.user{host:a} > 80", "avg:system.cpu.user{host:a}", ">", 80, 5},
+		{"max(last_1h):sum:requests.errors{*} >= 0.5", "sum:requests.errors{*}", ">=", 0.5, 60},
+		{"min(last_30s):avg:disk.free{*} < -1e3", "avg:disk.free{*}", "<", -1000, 1},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseDataDogMonitorQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Query != tt.metric || q.Operator != tt.operator || q.Value == nil || *q.Value != tt.value || q.Minutes != tt.minutes {
+			t.Fatalf("%s: unexpected query %+v", tt.query, q)
+		}
+	}
+
+	if _, err := ParseDataDogMonitorQuery(`"http.check".over("*").by("url").last(2).count_by_status()`); err == nil {
+		t.Fatal("expected service check query to be rejected")
+	}
+}
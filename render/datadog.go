@@ -0,0 +1,270 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type DataDogChartOptions struct {
+	URL      string
+	ApiKey   string
+	AppKey   string
+	Timeout  int
+	Attempts int
+}
+
+// DataDogChart draws monitor query by DataDog metrics API, if keys are not set monitor snapshot is used
+type DataDogChart struct {
+	client   *http.Client
+	options  DataDogChartOptions
+	chart    *ChartRender
+	logger   sreCommon.Logger
+	tracer   sreCommon.Tracer
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+// DataDogMonitorQuery is metric monitor query like avg(last_5m):avg:system.cpu.user{host:a} > 80
+type DataDogMonitorQuery struct {
+	Query    string
+	Operator string
+	Value    *float64
+	Minutes  int
+}
+
+type dataDogSeries struct {
+	Metric      string          `json:"metric"`
+	Scope       string          `json:"scope"`
+	Expression  string          `json:"expression"`
+	DisplayName string          `json:"display_name"`
+	Pointlist   [][]interface{} `json:"pointlist"`
+	Unit        []*struct {
+		ShortName string `json:"short_name"`
+	} `json:"unit"`
+}
+
+type dataDogQueryResponse struct {
+	Status string           `json:"status"`
+	Error  string           `json:"error"`
+	Errors []string         `json:"errors"`
+	Series []*dataDogSeries `json:"series"`
+}
+
+var dataDogMonitorQuery = regexp.MustCompile(`^\s*\w+\(last_(\d+)([smhdw])\)\s*:\s*(.+?)\s*(>=|<=|!=|==|>|<)\s*(-?[0-9][0-9.eE+-]*)\s*$`)
+
+// ParseDataDogMonitorQuery supports metric monitors only, other monitor types return error
+func ParseDataDogMonitorQuery(query string) (*DataDogMonitorQuery, error) {
+
+	m := dataDogMonitorQuery.FindStringSubmatch(query)
+	if len(m) != 6 {
+		return nil, fmt.Errorf("datadog monitor query %s is not supported", query)
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	minutes := n
+	switch m[2] {
+	case "s":
+		minutes = (n + 59) / 60
+	case "h":
+		minutes = n * 60
+	case "d":
+		minutes = n * 60 * 24
+	case "w":
+		minutes = n * 60 * 24 * 7
+	}
+
+	r := &DataDogMonitorQuery{
+		Query:    m[3],
+		Operator: m[4],
+		Minutes:  minutes,
+	}
+	if v, err := strconv.ParseFloat(m[5], 64); err == nil {
+		r.Value = &v
+	}
+	return r, nil
+}
+
+func (d *DataDogChart) EventType() string {
+	return "DataDogEvent"
+}
+
+func (d *DataDogChart) query(query string, from, to time.Time) ([]*PrometheusSeries, string, error) {
+
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("from", strconv.FormatInt(from.Unix(), 10))
+	values.Set("to", strconv.FormatInt(to.Unix(), 10))
+
+	URL := fmt.Sprintf("%s/api/v1/query?%s", strings.TrimRight(d.options.URL, "/"), values.Encode())
+
+	req, err := http.NewRequest("GET", URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("DD-API-KEY", d.options.ApiKey)
+	req.Header.Set("DD-APPLICATION-KEY", d.options.AppKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var r dataDogQueryResponse
+	if err := json.Unmarshal(b, &r); err != nil || resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("datadog response: %s %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	if r.Status == "error" || !utils.IsEmpty(r.Error) {
+		return nil, "", fmt.Errorf("datadog response: %s %s", r.Error, strings.Join(r.Errors, ", "))
+	}
+
+	unit := ""
+	var series []*PrometheusSeries
+	for _, s := range r.Series {
+
+		name := s.Expression
+		if utils.IsEmpty(name) {
+			name = fmt.Sprintf("%s{%s}", s.Metric, s.Scope)
+		}
+		if unit == "" && len(s.Unit) > 0 && s.Unit[0] != nil {
+			unit = s.Unit[0].ShortName
+		}
+
+		item := &PrometheusSeries{Metric: map[string]string{"__name__": name}}
+		for _, p := range s.Pointlist {
+			if len(p) != 2 {
+				continue
+			}
+			ts, ok := p[0].(float64)
+			if !ok {
+				continue
+			}
+			sample := &PrometheusSample{Time: time.UnixMilli(int64(ts)).UTC()}
+			if v, ok := p[1].(float64); ok {
+				sample.Value = &v
+			}
+			item.Values = append(item.Values, sample)
+		}
+		series = append(series, item)
+	}
+	return series, unit, nil
+}
+
+func (d *DataDogChart) snapshot(title, URL string) (*ChartImage, error) {
+
+	b, err := chartDownload(d.client, URL, d.options.Attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChartImage{Caption: title, FileName: chartImageName(title), Image: b}, nil
+}
+
+func (d *DataDogChart) Chart(spanCtx sreCommon.TracerSpanContext, jsonMap map[string]interface{}) (*ChartImage, error) {
+
+	span := d.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	title := chartMapString(jsonMap, "data.alert.title", "data.event.title")
+	query := chartMapString(jsonMap, "data.alert.query")
+	snapshot := chartMapString(jsonMap, "data.snapshot")
+
+	monitor, err := ParseDataDogMonitorQuery(query)
+	if err != nil || utils.IsEmpty(d.options.ApiKey) || utils.IsEmpty(d.options.AppKey) {
+
+		if utils.IsEmpty(snapshot) {
+			if err != nil && !utils.IsEmpty(query) {
+				d.logger.SpanDebug(span, err)
+			}
+			return nil, nil
+		}
+
+		d.requests.Inc("snapshot")
+		image, err := d.snapshot(title, snapshot)
+		if err != nil {
+			d.errors.Inc("snapshot")
+			d.logger.SpanError(span, err)
+			return nil, err
+		}
+		if monitor != nil {
+			image.Caption = query
+		}
+		return image, nil
+	}
+
+	period := d.chart.options.Period
+	if monitor.Minutes*4 > period {
+		period = monitor.Minutes * 4
+	}
+
+	var startsAt time.Time
+	if ms, ok := chartMapValue(jsonMap, "data.date").(float64); ok && ms > 0 {
+		startsAt = time.UnixMilli(int64(ms))
+	}
+	from, to := alertWindow(period, startsAt, time.Time{})
+
+	d.requests.Inc("query")
+	series, unit, err := d.query(monitor.Query, from, to)
+	if err != nil {
+		d.errors.Inc("query")
+		d.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	request := &ImageRequest{
+		Title:    title,
+		Metric:   monitor.Query,
+		Operator: monitor.Operator,
+		Value:    monitor.Value,
+		Unit:     unit,
+	}
+
+	b, err := d.chart.png(request, series, from, to)
+	if err != nil {
+		d.errors.Inc("query")
+		d.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	caption := monitor.Query
+	if monitor.Value != nil {
+		caption = chartCaption(monitor.Query, monitor.Operator, strconv.FormatFloat(*monitor.Value, 'g', -1, 64))
+	}
+	return &ChartImage{Caption: caption, FileName: chartImageName(title), Image: b}, nil
+}
+
+func NewDataDogChart(options DataDogChartOptions, chartOptions ChartRenderOptions, observability *common.Observability) *DataDogChart {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.URL) {
+		logger.Debug("DataDog chart URL is not defined. Skipped")
+		return nil
+	}
+
+	return &DataDogChart{
+		client:   utils.NewHttpClient(options.Timeout, false),
+		options:  options,
+		chart:    newChartRender(chartOptions, nil, observability),
+		logger:   logger,
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all datadog charts", []string{"kind"}, "datadog", "chart"),
+		errors:   observability.Metrics().Counter("errors", "Count of all datadog chart errors", []string{"kind"}, "datadog", "chart"),
+	}
+}
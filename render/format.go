@@ -0,0 +1,374 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// messageLimits are maximum text lengths of chat platforms in characters
+var messageLimits = map[string]int{
+	"telegram":         4096,
+	"telegram-caption": 1024,
+	"slack":            3000,
+	"teams":            28000,
+	"workchat":         2000,
+}
+
+var severityEmojis = map[string]string{
+	"critical": "🔴",
+	"fatal":    "🔴",
+	"page":     "🔴",
+	"error":    "🔴",
+	"high":     "🔴",
+	"p1":       "🔴",
+	"p2":       "🟠",
+	"warning":  "🟠",
+	"warn":     "🟠",
+	"medium":   "🟡",
+	"p3":       "🟡",
+	"low":      "🔵",
+	"info":     "🔵",
+	"p4":       "🔵",
+	"p5":       "🔵",
+	"resolved": "✅",
+	"ok":       "✅",
+	"success":  "✅",
+}
+
+const formatEllipsis = "…"
+
+// formatMarkup matches entities and <...> links or tags, which are never split. Single < or & is text
+var formatMarkup = regexp.MustCompile(`&[a-zA-Z#0-9]+;|<[^\s<>][^<>]*>`)
+
+func formatEscape(s string, chars string, prefix string) string {
+
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			b.WriteString(prefix)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// telegramHTML escapes text for Telegram HTML parse mode, which supports only &lt; &gt; &amp; and &quot; named entities
+func (tpl *TextTemplate) fTelegramHTML(s string) (string, error) {
+
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+	return r.Replace(s), nil
+}
+
+// telegramMarkdownV2 escapes all characters reserved by Telegram MarkdownV2 parse mode
+func (tpl *TextTemplate) fTelegramMarkdownV2(s string) (string, error) {
+	return formatEscape(s, "\\_*[]()~`>#+-=|{}.!", "\\"), nil
+}
+
+// slackMrkdwn escapes control characters of Slack mrkdwn, so text isn't treated as links or mentions
+func (tpl *TextTemplate) fSlackMrkdwn(s string) (string, error) {
+
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return r.Replace(s), nil
+}
+
+// teamsMarkdown escapes markdown characters and html tags supported by Teams messages
+func (tpl *TextTemplate) fTeamsMarkdown(s string) (string, error) {
+
+	s = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+	return formatEscape(s, "\\`*_{}[]()#+-.!|~", "\\"), nil
+}
+
+// messageLimit returns message length limit of platform: telegram, telegram-caption, slack, teams, workchat
+func (tpl *TextTemplate) fMessageLimit(platform string) (int, error) {
+
+	limit, ok := messageLimits[strings.ToLower(platform)]
+	if !ok {
+		return 0, fmt.Errorf("platform %s is not supported", platform)
+	}
+	return limit, nil
+}
+
+// truncateHTML cuts text to limit of visible characters including ellipsis, tags are not counted and all open tags
+// are closed, entity is counted as one character and is never split
+func (tpl *TextTemplate) fTruncateHTML(limit int, s string) (string, error) {
+
+	if limit <= 0 {
+		return "", nil
+	}
+
+	var b bytes.Buffer
+	var open, marked []string
+	mark := 0
+	count := 0
+	i := 0
+
+	for i < len(s) {
+
+		switch s[i] {
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				// broken tag is text
+				break
+			}
+			tag := s[i : i+end+1]
+			name := strings.Fields(strings.Trim(tag, "</>"))
+			if len(name) > 0 && !strings.HasSuffix(tag, "/>") {
+				if strings.HasPrefix(tag, "</") {
+					for j := len(open) - 1; j >= 0; j-- {
+						if open[j] == name[0] {
+							open = append(open[:j], open[j+1:]...)
+							break
+						}
+					}
+				} else {
+					open = append(open, name[0])
+				}
+			}
+			b.WriteString(tag)
+			i += end + 1
+			continue
+		}
+
+		n := 1
+		if s[i] == '&' {
+			if end := strings.IndexByte(s[i:], ';'); end > 0 && end <= 10 && !strings.ContainsAny(s[i+1:i+end], " &<") {
+				n = end + 1
+			}
+		}
+		if n == 1 {
+			_, n = utf8.DecodeRuneInString(s[i:])
+		}
+
+		if count == limit {
+			// ellipsis takes place of the last character
+			b.Truncate(mark)
+			open = marked
+			b.WriteString(formatEllipsis)
+			break
+		}
+		if count == limit-1 {
+			mark = b.Len()
+			marked = append([]string(nil), open...)
+		}
+		b.WriteString(s[i : i+n])
+		count++
+		i += n
+	}
+
+	for j := len(open) - 1; j >= 0; j-- {
+		b.WriteString(fmt.Sprintf("</%s>", open[j]))
+	}
+	return b.String(), nil
+}
+
+// truncateText cuts text to limit of characters including ellipsis, escapes, entities, <...> links of Slack
+// and code blocks are not broken
+func (tpl *TextTemplate) fTruncateText(limit int, s string) (string, error) {
+
+	if utf8.RuneCountInString(s) <= limit {
+		return s, nil
+	}
+	if limit <= 0 {
+		return "", nil
+	}
+
+	// byte offsets of runes within limit, so text is cut without converting it again
+	offsets := make([]int, 0, limit+1)
+	for i := range s {
+		if len(offsets) > limit {
+			break
+		}
+		offsets = append(offsets, i)
+	}
+
+	// markup which is cut ends at the first > or ; after limit, text after it isn't searched
+	window := offsets[limit]
+	for _, c := range ">;" {
+		if n := strings.IndexRune(s[offsets[limit]:], c); n >= 0 && offsets[limit]+n+1 > window {
+			window = offsets[limit] + n + 1
+		}
+	}
+	spans := formatMarkup.FindAllStringIndex(s[:window], -1)
+
+	for size := limit - 1; size >= 0; {
+
+		end := offsets[size]
+		for _, span := range spans {
+			if span[0] >= end {
+				break
+			}
+			if end < span[1] {
+				end = span[0]
+				break
+			}
+		}
+
+		// escaping backslash isn't left without escaped character
+		slashes := len(s[:end]) - len(strings.TrimRight(s[:end], "\\"))
+		if slashes%2 == 1 {
+			end--
+		}
+
+		t := s[:end] + formatEllipsis
+		if strings.Count(t, "```")%2 == 1 {
+			t = t + "```"
+		}
+		// closing of code block is within limit too
+		n := utf8.RuneCountInString(t)
+		if n <= limit {
+			return t, nil
+		}
+		size = utf8.RuneCountInString(s[:end]) - (n - limit)
+	}
+	return "", nil
+}
+
+func formatDuration(d time.Duration) string {
+
+	if d < 0 {
+		d = -d
+	}
+	if d < time.Second {
+		return "0s"
+	}
+
+	units := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	// two most significant units are enough for messages
+	var parts []string
+	for _, u := range units {
+		if d < u.d {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		n := d / u.d
+		d -= n * u.d
+		parts = append(parts, fmt.Sprintf("%d%s", n, u.name))
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// humanizeDuration accepts duration string like 90m, seconds as number or duration
+func (tpl *TextTemplate) fHumanizeDuration(i interface{}) (string, error) {
+
+	switch v := i.(type) {
+	case time.Duration:
+		return formatDuration(v), nil
+	case int:
+		return formatDuration(time.Duration(v) * time.Second), nil
+	case int64:
+		return formatDuration(time.Duration(v) * time.Second), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("duration %v is not valid", v)
+		}
+		return formatDuration(time.Duration(v * float64(time.Second))), nil
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return formatDuration(d), nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", err
+		}
+		return tpl.fHumanizeDuration(f)
+	default:
+		return "", fmt.Errorf("duration type %T is not supported", i)
+	}
+}
+
+func (tpl *TextTemplate) parseTime(i interface{}) (time.Time, error) {
+
+	switch v := i.(type) {
+	case time.Time:
+		return v, nil
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case string:
+		for _, layout := range []string{tpl.options.TimeFormat, time.RFC3339Nano} {
+			if layout == "" {
+				continue
+			}
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("time %s is not valid", v)
+	default:
+		return time.Time{}, fmt.Errorf("time type %T is not supported", i)
+	}
+}
+
+// relativeTime returns time relative to now like 5m ago or in 2h 10m
+func (tpl *TextTemplate) fRelativeTime(i interface{}) (string, error) {
+
+	t, err := tpl.parseTime(i)
+	if err != nil {
+		return "", err
+	}
+
+	d := time.Until(t)
+	if d > -time.Second && d < time.Second {
+		return "now", nil
+	}
+	if d < 0 {
+		return fmt.Sprintf("%s ago", formatDuration(d)), nil
+	}
+	return fmt.Sprintf("in %s", formatDuration(d)), nil
+}
+
+// k8sShortImage removes registry and repository path, digest is shortened to 12 characters
+func (tpl *TextTemplate) fK8sShortImage(image string) (string, error) {
+
+	image = strings.TrimSpace(image)
+
+	digest := ""
+	if n := strings.Index(image, "@"); n >= 0 {
+		digest = image[n+1:]
+		image = image[:n]
+	}
+
+	if n := strings.LastIndex(image, "/"); n >= 0 {
+		image = image[n+1:]
+	}
+
+	if digest == "" || strings.Contains(image, ":") {
+		return image, nil
+	}
+
+	if n := strings.Index(digest, ":"); n >= 0 && len(digest) > n+13 {
+		digest = digest[:n+13]
+	}
+	return fmt.Sprintf("%s@%s", image, digest), nil
+}
+
+// severityEmoji returns emoji of severity, priority or status, white circle is returned for unknown values
+func (tpl *TextTemplate) fSeverityEmoji(severity string) (string, error) {
+
+	if e, ok := severityEmojis[strings.ToLower(strings.TrimSpace(severity))]; ok {
+		return e, nil
+	}
+	return "⚪", nil
+}
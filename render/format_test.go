@@ -0,0 +1,176 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type formatTest struct {
+	name string
+	text string
+	want string
+}
+
+func testFormat(t *testing.T, f func(string) (string, error), tests []formatTest) {
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatTelegramHTML(t *testing.T) {
+
+	tpl := &TextTemplate{}
+	testFormat(t, tpl.fTelegramHTML, []formatTest{
+		{name: "plain", text: "disk is full", want: "disk is full"},
+		{name: "reserved", text: `a < b & c > "d"`, want: "a &lt; b &amp; c &gt; &quot;d&quot;"},
+		{name: "entity", text: "&amp;", want: "&amp;amp;"},
+		{name: "multibyte", text: "диск <90%>", want: "диск &lt;90%&gt;"},
+	})
+}
+
+func TestFormatTelegramMarkdownV2(t *testing.T) {
+
+	tpl := &TextTemplate{}
+	testFormat(t, tpl.fTelegramMarkdownV2, []formatTest{
+		{name: "plain", text: "disk is full", want: "disk is full"},
+		{name: "reserved", text: "a_b*c[d](e)~`>#+-=|{}.!", want: "a\\_b\\*c\\[d\\]\\(e\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!"},
+		{name: "backslash", text: `a\b`, want: `a\\b`},
+		{name: "multibyte", text: "диск 1.5", want: "диск 1\\.5"},
+	})
+}
+
+func TestFormatSlackMrkdwn(t *testing.T) {
+
+	tpl := &TextTemplate{}
+	testFormat(t, tpl.fSlackMrkdwn, []formatTest{
+		{name: "plain", text: "*disk* is full", want: "*disk* is full"},
+		{name: "mention", text: "<!channel> & <@U1>", want: "&lt;!channel&gt; &amp; &lt;@U1&gt;"},
+		{name: "multibyte", text: "диск > 90%", want: "диск &gt; 90%"},
+	})
+}
+
+func TestFormatTeamsMarkdown(t *testing.T) {
+
+	tpl := &TextTemplate{}
+	testFormat(t, tpl.fTeamsMarkdown, []formatTest{
+		{name: "plain", text: "disk is full", want: "disk is full"},
+		{name: "markdown", text: "*a* _b_ [c](d)", want: "\\*a\\* \\_b\\_ \\[c\\]\\(d\\)"},
+		{name: "html", text: "<b>a & b</b>", want: "&lt;b&gt;a &amp; b&lt;/b&gt;"},
+	})
+}
+
+func TestFormatMessageLimit(t *testing.T) {
+
+	tpl := &TextTemplate{}
+	for platform, want := range map[string]int{"telegram": 4096, "Telegram-Caption": 1024, "slack": 3000, "teams": 28000, "workchat": 2000} {
+		got, err := tpl.fMessageLimit(platform)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d %v", platform, want, got, err)
+		}
+	}
+	if _, err := tpl.fMessageLimit("irc"); err == nil {
+		t.Error("unknown platform doesn't fail")
+	}
+}
+
+func TestFormatTruncateHTML(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		limit int
+		text  string
+		want  string
+	}{
+		{name: "short", limit: 10, text: "<b>disk</b>", want: "<b>disk</b>"},
+		{name: "at limit", limit: 5, text: "<b>abcde</b>", want: "<b>abcde</b>"},
+		{name: "over limit", limit: 5, text: "<b>abcdefgh</b>", want: "<b>abcd…</b>"},
+		{name: "nested", limit: 4, text: "<b>ab<i>cdef</i></b>", want: "<b>ab<i>c…</i></b>"},
+		{name: "closed tag", limit: 4, text: "<b>ab</b>cdef", want: "<b>ab</b>c…"},
+		{name: "tag at cut", limit: 3, text: "ab<i>cd</i>", want: "ab<i>…</i>"},
+		{name: "entity", limit: 4, text: "a &amp; b", want: "a &amp;…"},
+		{name: "ampersand", limit: 4, text: "Tom & Jerry", want: "Tom…"},
+		{name: "less than", limit: 6, text: "a < b and c", want: "a < b…"},
+		{name: "link", limit: 3, text: `<a href="https://x">abcdef</a>`, want: `<a href="https://x">ab…</a>`},
+		{name: "self closing", limit: 3, text: "a<br/>bcd", want: "a<br/>b…"},
+		{name: "multibyte", limit: 3, text: "<i>диск</i>", want: "<i>ди…</i>"},
+		{name: "one", limit: 1, text: "<b>ab</b>", want: "<b>…</b>"},
+		{name: "zero", limit: 0, text: "<b>ab</b>", want: ""},
+	}
+
+	tpl := &TextTemplate{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tpl.fTruncateHTML(tt.limit, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatTruncateText(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		limit int
+		text  string
+		want  string
+	}{
+		{name: "short", limit: 10, text: "disk", want: "disk"},
+		{name: "at limit", limit: 4, text: "disk", want: "disk"},
+		{name: "over limit", limit: 4, text: "disks", want: "dis…"},
+		{name: "less than", limit: 9, text: "value < 10 on host", want: "value < …"},
+		{name: "ampersand", limit: 8, text: "Tom & Jerry", want: "Tom & J…"},
+		{name: "ampersand and semicolon", limit: 8, text: "a & b; c & d", want: "a & b; …"},
+		{name: "entity", limit: 6, text: "a &amp; b", want: "a …"},
+		{name: "numeric entity", limit: 6, text: "a &#128308; b", want: "a …"},
+		{name: "entity before cut", limit: 8, text: "a &amp; bc", want: "a &amp;…"},
+		{name: "link", limit: 12, text: "see <https://example.com|dashboard> now", want: "see …"},
+		{name: "mention", limit: 7, text: "hi <!channel>", want: "hi …"},
+		{name: "escape", limit: 4, text: `ab\.c`, want: "ab…"},
+		{name: "escaped backslash", limit: 5, text: `ab\\cd`, want: `ab\\…`},
+		{name: "code block", limit: 12, text: "```\nline\nline\n```", want: "```\nline…```"},
+		{name: "multibyte", limit: 3, text: "диск", want: "ди…"},
+		{name: "emoji", limit: 2, text: "🔴🔴🔴", want: "🔴…"},
+		{name: "one", limit: 1, text: "disk", want: "…"},
+		{name: "zero", limit: 0, text: "disk", want: ""},
+	}
+
+	tpl := &TextTemplate{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tpl.fTruncateText(tt.limit, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("%d characters are over limit %d", n, tt.limit)
+			}
+		})
+	}
+}
+
+func BenchmarkFormatTruncateText(b *testing.B) {
+
+	tpl := &TextTemplate{}
+	s := strings.Repeat("disk &amp; <https://example.com|host> is full ", 1000)
+	for i := 0; i < b.N; i++ {
+		tpl.fTruncateText(4000, s)
+	}
+}
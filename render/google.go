@@ -0,0 +1,241 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+type GoogleChartOptions struct {
+	Credentials string
+	Timeout     int
+}
+
+// GoogleChart draws threshold condition of Google incident by Cloud Monitoring time series API
+type GoogleChart struct {
+	service  *monitoring.Service
+	options  GoogleChartOptions
+	chart    *ChartRender
+	logger   sreCommon.Logger
+	tracer   sreCommon.Tracer
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+var googleComparisons = map[string]string{
+	"COMPARISON_GT": ">",
+	"COMPARISON_GE": ">=",
+	"COMPARISON_LT": "<",
+	"COMPARISON_LE": "<=",
+	"COMPARISON_EQ": "==",
+	"COMPARISON_NE": "!=",
+}
+
+func (g *GoogleChart) EventType() string {
+	return "GoogleEvent"
+}
+
+func (g *GoogleChart) timeSeries(project, filter string, aggregation map[string]interface{}, from, to time.Time) ([]*PrometheusSeries, string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.options.Timeout)*time.Second)
+	defer cancel()
+
+	call := g.service.Projects.TimeSeries.List(fmt.Sprintf("projects/%s", project)).
+		Filter(filter).
+		IntervalStartTime(from.Format(time.RFC3339)).
+		IntervalEndTime(to.Format(time.RFC3339)).
+		Context(ctx)
+
+	// about one point per four pixels, rounded up to minutes
+	step := int(to.Sub(from).Seconds()) / (g.chart.options.ImageWidth/4 + 1)
+	alignmentPeriod := fmt.Sprintf("%ds", (step/60+1)*60)
+	aligner := "ALIGN_MEAN"
+
+	// condition aggregation is used as is, so chart shows the same values which incident is based on
+	if aggregation != nil {
+		if s, ok := aggregation["alignmentPeriod"].(string); ok && !utils.IsEmpty(s) {
+			alignmentPeriod = s
+		}
+		if s, ok := aggregation["perSeriesAligner"].(string); ok && !utils.IsEmpty(s) {
+			aligner = s
+		}
+		if s, ok := aggregation["crossSeriesReducer"].(string); ok && !utils.IsEmpty(s) {
+			call = call.AggregationCrossSeriesReducer(s)
+			if fields, ok := aggregation["groupByFields"].([]interface{}); ok {
+				var groupBy []string
+				for _, f := range fields {
+					groupBy = append(groupBy, fmt.Sprintf("%v", f))
+				}
+				call = call.AggregationGroupByFields(groupBy...)
+			}
+		}
+	}
+	call = call.AggregationAlignmentPeriod(alignmentPeriod).AggregationPerSeriesAligner(aligner)
+
+	var series []*PrometheusSeries
+	unit := ""
+	err := call.Pages(ctx, func(r *monitoring.ListTimeSeriesResponse) error {
+
+		for _, ts := range r.TimeSeries {
+
+			metric := make(map[string]string)
+			if ts.Resource != nil {
+				for k, v := range ts.Resource.Labels {
+					metric[k] = v
+				}
+			}
+			if ts.Metric != nil {
+				for k, v := range ts.Metric.Labels {
+					metric[k] = v
+				}
+				metric["__name__"] = ts.Metric.Type
+			}
+			if unit == "" {
+				unit = ts.Unit
+			}
+
+			item := &PrometheusSeries{Metric: metric}
+			for _, p := range ts.Points {
+				if p == nil || p.Interval == nil {
+					continue
+				}
+				t, err := time.Parse(time.RFC3339Nano, p.Interval.EndTime)
+				if err != nil {
+					continue
+				}
+				sample := &PrometheusSample{Time: t.UTC()}
+				if p.Value != nil {
+					if p.Value.DoubleValue != nil {
+						v := *p.Value.DoubleValue
+						sample.Value = &v
+					} else if p.Value.Int64Value != nil {
+						v := float64(*p.Value.Int64Value)
+						sample.Value = &v
+					}
+				}
+				item.Values = append(item.Values, sample)
+			}
+
+			// points are returned in reverse time order
+			sort.Slice(item.Values, func(i, j int) bool {
+				return item.Values[i].Time.Before(item.Values[j].Time)
+			})
+			series = append(series, item)
+		}
+		return nil
+	})
+	return series, unit, err
+}
+
+func (g *GoogleChart) Chart(spanCtx sreCommon.TracerSpanContext, jsonMap map[string]interface{}) (*ChartImage, error) {
+
+	span := g.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	filter := chartMapString(jsonMap, "data.incident.condition.conditionThreshold.filter")
+	if utils.IsEmpty(filter) {
+		return nil, nil
+	}
+
+	project := chartMapString(jsonMap, "data.incident.scoping_project_id", "data.incident.resource.labels.project_id")
+	if utils.IsEmpty(project) {
+		return nil, errors.New("google incident project is not defined")
+	}
+
+	title := chartMapString(jsonMap, "data.incident.policy_name", "data.incident.condition_name")
+	operator := googleComparisons[chartMapString(jsonMap, "data.incident.condition.conditionThreshold.comparison")]
+
+	var value *float64
+	if v, ok := chartMapValue(jsonMap, "data.incident.condition.conditionThreshold.thresholdValue").(float64); ok {
+		value = &v
+	}
+
+	var aggregation map[string]interface{}
+	if list, ok := chartMapValue(jsonMap, "data.incident.condition.conditionThreshold.aggregations").([]interface{}); ok && len(list) > 0 {
+		aggregation, _ = list[0].(map[string]interface{})
+	}
+
+	var startsAt, endsAt time.Time
+	if sec, ok := chartMapValue(jsonMap, "data.incident.started_at").(float64); ok && sec > 0 {
+		startsAt = time.Unix(int64(sec), 0)
+	}
+	if sec, ok := chartMapValue(jsonMap, "data.incident.ended_at").(float64); ok && sec > 0 {
+		endsAt = time.Unix(int64(sec), 0)
+	}
+	from, to := alertWindow(g.chart.options.Period, startsAt, endsAt)
+
+	g.requests.Inc(project)
+	series, unit, err := g.timeSeries(project, filter, aggregation, from, to)
+	if err != nil {
+		g.errors.Inc(project)
+		g.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	if unit == "1" {
+		unit = ""
+	}
+
+	request := &ImageRequest{
+		Title:    title,
+		Metric:   filter,
+		Operator: operator,
+		Value:    value,
+		Unit:     unit,
+	}
+
+	b, err := g.chart.png(request, series, from, to)
+	if err != nil {
+		g.errors.Inc(project)
+		g.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	caption := filter
+	if value != nil {
+		caption = chartCaption(filter, operator, strconv.FormatFloat(*value, 'g', -1, 64))
+	}
+	return &ChartImage{Caption: caption, FileName: chartImageName(title), Image: b}, nil
+}
+
+func NewGoogleChart(options GoogleChartOptions, chartOptions ChartRenderOptions, observability *common.Observability) *GoogleChart {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.Credentials) {
+		logger.Debug("Google chart credentials are not defined. Skipped")
+		return nil
+	}
+
+	var o option.ClientOption
+	if _, err := os.Stat(options.Credentials); err == nil {
+		o = option.WithCredentialsFile(options.Credentials)
+	} else {
+		o = option.WithCredentialsJSON([]byte(options.Credentials))
+	}
+
+	service, err := monitoring.NewService(context.Background(), o)
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	return &GoogleChart{
+		service:  service,
+		options:  options,
+		chart:    newChartRender(chartOptions, nil, observability),
+		logger:   logger,
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all google charts", []string{"project"}, "google", "chart"),
+		errors:   observability.Metrics().Counter("errors", "Count of all google chart errors", []string{"project"}, "google", "chart"),
+	}
+}
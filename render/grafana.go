@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +30,7 @@ type GrafanaRenderOptions struct {
 	Period      int
 	ImageWidth  int
 	ImageHeight int
+	PanelType   string
 }
 
 type GrafanaAliasColors struct {
@@ -40,27 +44,175 @@ type GrafanaRender struct {
 	counter sreCommon.Counter
 }
 
-func (g *GrafanaRender) findDashboard(c *sdk.Client, ctx context.Context, title string) *sdk.Board {
-	var tags []string
+// grafanaPanelRef points to existing panel, dashboard is found by UID or by title if UID is not known
+type grafanaPanelRef struct {
+	UID     string
+	Title   string
+	PanelID int
+	Vars    url.Values
+}
 
-	boards, err1 := c.SearchDashboards(ctx, title, false, tags...)
-	if err1 != nil {
-		return nil
+type grafanaTarget struct {
+	Expr string `json:"expr"`
+}
+
+type grafanaPanel struct {
+	ID      int              `json:"id"`
+	Type    string           `json:"type"`
+	Title   string           `json:"title"`
+	Targets []*grafanaTarget `json:"targets"`
+	Panels  []*grafanaPanel  `json:"panels"`
+}
+
+// grafanaDashboard keeps only fields needed to find panel, sdk.Board fails on datasource objects of modern dashboards
+type grafanaDashboard struct {
+	Panels []*grafanaPanel `json:"panels"`
+	Rows   []struct {
+		Panels []*grafanaPanel `json:"panels"`
+	} `json:"rows"`
+	Templating struct {
+		List []struct {
+			Name string `json:"name"`
+		} `json:"list"`
+	} `json:"templating"`
+}
+
+var grafanaDashboardURL = regexp.MustCompile(`/d(?:-solo)?/([^/?]+)`)
+
+func (d *grafanaDashboard) allPanels() []*grafanaPanel {
+
+	var r []*grafanaPanel
+	var walk func(panels []*grafanaPanel)
+	walk = func(panels []*grafanaPanel) {
+		for _, p := range panels {
+			if p == nil {
+				continue
+			}
+			r = append(r, p)
+			walk(p.Panels)
+		}
 	}
+	walk(d.Panels)
+	for _, row := range d.Rows {
+		walk(row.Panels)
+	}
+	return r
+}
 
-	if len(boards) > 0 {
-		g.logger.Info(len(boards))
+// findPanel looks for panel by ID, otherwise by panel which queries the same metric
+func (d *grafanaDashboard) findPanel(id int, metric string) *grafanaPanel {
 
-		board, _, err := c.GetDashboardByUID(ctx, boards[0].UID)
-		if err != nil {
-			g.logger.Info(len(board.Panels))
-			return nil
+	panels := d.allPanels()
+	if id > 0 {
+		for _, p := range panels {
+			if p.ID == id {
+				return p
+			}
+		}
+	}
+
+	metric = strings.TrimSpace(metric)
+	if utils.IsEmpty(metric) {
+		return nil
+	}
+	for _, p := range panels {
+		for _, t := range p.Targets {
+			if t != nil && strings.TrimSpace(t.Expr) == metric {
+				return p
+			}
 		}
-		return &board
 	}
 	return nil
 }
 
+func grafanaFirst(m map[string]string, keys ...string) string {
+
+	for _, k := range keys {
+		if v := strings.TrimSpace(m[k]); !utils.IsEmpty(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// grafanaPanelReference takes dashboard and panel from alert annotations or from Grafana URLs, nil is returned if alert doesn't refer to dashboard
+func grafanaPanelReference(request *ImageRequest) *grafanaPanelRef {
+
+	if request == nil {
+		return nil
+	}
+
+	ref := &grafanaPanelRef{
+		UID:   grafanaFirst(request.Annotations, "__dashboardUid__", "dashboardUID", "dashboardUid", "dashboard_uid"),
+		Title: grafanaFirst(request.Annotations, "dashboard", "dashboardTitle"),
+		Vars:  url.Values{},
+	}
+	if id, err := strconv.Atoi(grafanaFirst(request.Annotations, "__panelId__", "panelId", "panelID", "panel_id")); err == nil {
+		ref.PanelID = id
+	}
+
+	links := []string{
+		grafanaFirst(request.Annotations, "__panelUrl__", "panelURL", "panelUrl"),
+		grafanaFirst(request.Annotations, "__dashboardUrl__", "dashboardURL", "dashboardUrl"),
+		request.GeneratorURL,
+	}
+	for _, link := range links {
+
+		u, err := url.Parse(link)
+		if err != nil || utils.IsEmpty(link) {
+			continue
+		}
+		m := grafanaDashboardURL.FindStringSubmatch(u.Path)
+		if len(m) != 2 {
+			continue
+		}
+		if utils.IsEmpty(ref.UID) {
+			ref.UID = m[1]
+		}
+
+		q := u.Query()
+		if ref.PanelID == 0 {
+			for _, k := range []string{"viewPanel", "editPanel", "panelId"} {
+				if id, err := strconv.Atoi(q.Get(k)); err == nil {
+					ref.PanelID = id
+					break
+				}
+			}
+		}
+		for k, v := range q {
+			if strings.HasPrefix(k, "var-") {
+				ref.Vars[k] = v
+			}
+		}
+	}
+
+	if utils.IsEmpty(ref.UID) && utils.IsEmpty(ref.Title) {
+		return nil
+	}
+	return ref
+}
+
+// findDashboard returns UID of the first dashboard with exact title
+func (g *GrafanaRender) findDashboard(c *sdk.Client, ctx context.Context, title string) string {
+
+	if utils.IsEmpty(title) {
+		return ""
+	}
+
+	boards, err := c.SearchDashboards(ctx, title, false)
+	if err != nil {
+		g.logger.Error(err)
+		return ""
+	}
+
+	for _, b := range boards {
+		if b.Title == title {
+			return b.UID
+		}
+	}
+	return ""
+}
+
 func (g *GrafanaRender) apiKeyIsCredentials() bool {
 
 	arr := strings.Split(g.options.ApiKey, ":")
@@ -94,29 +246,23 @@ func (g *GrafanaRender) renderImage(imageURL string, apiKey string) ([]byte, err
 	}
 
 	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
-}
 
-func (g *GrafanaRender) GenerateDashboard(spanCtx sreCommon.TracerSpanContext,
-	title string, metric string, operator string, value *float64, minutes *int, unit string) ([]byte, string, error) {
-
-	span := g.tracer.StartChildSpan(spanCtx)
-	defer span.Finish()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 
-	period := g.options.Period
-	if minutes != nil {
-		period = *minutes
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("grafana render response: %s %s", resp.Status, strings.TrimSpace(string(b)))
 	}
+	return b, nil
+}
 
-	t := strconv.FormatInt(time.Now().UTC().UnixNano(), 10)
-	suffix := fmt.Sprintf("%x", md5.Sum([]byte(t)))
+// graphBoard creates board with deprecated graph panel, it's kept for Grafana versions without timeseries panel
+func (g *GrafanaRender) graphBoard(boardName, title, metric, operator string, value *float64, period int, unit string) *sdk.Board {
 
-	boardName := fmt.Sprintf("%s - %s", title, suffix)
 	board := sdk.NewBoard(boardName)
 	board.Timezone = "utc"
-
-	to := time.Now().UTC().Unix() * 1000
-	from := (to - int64(period*60)*1000)
 	board.Time = sdk.Time{From: fmt.Sprintf("now-%dm", period), To: "now"}
 
 	row1 := board.AddRow("")
@@ -215,53 +361,253 @@ func (g *GrafanaRender) GenerateDashboard(spanCtx sreCommon.TracerSpanContext,
 	graph.AddTarget(&target)
 
 	row1.Add(graph)
+	return board
+}
 
-	c := sdk.NewClient(g.options.URL, g.options.ApiKey, g.client)
+// timeseriesBoard creates board with timeseries panel, threshold is shown as line and soft axis limits
+func (g *GrafanaRender) timeseriesBoard(boardName, title, metric, operator string, value *float64, period int, unit string) ([]byte, error) {
 
+	defaults := map[string]interface{}{
+		"unit": unit,
+		"custom": map[string]interface{}{
+			"lineWidth":   1,
+			"fillOpacity": 10,
+			"thresholdsStyle": map[string]interface{}{
+				"mode": "off",
+			},
+		},
+	}
+
+	if value != nil {
+
+		steps := []interface{}{
+			map[string]interface{}{"color": "green", "value": nil},
+			map[string]interface{}{"color": "red", "value": *value},
+		}
+		if operator == "<" || operator == "<=" {
+			steps = []interface{}{
+				map[string]interface{}{"color": "red", "value": nil},
+				map[string]interface{}{"color": "green", "value": *value},
+			}
+		}
+
+		custom := defaults["custom"].(map[string]interface{})
+		custom["thresholdsStyle"] = map[string]interface{}{"mode": "line"}
+		custom["axisSoftMin"] = *value - (*value * 5 / 100)
+		custom["axisSoftMax"] = *value + (*value * 5 / 100)
+		defaults["thresholds"] = map[string]interface{}{
+			"mode":  "absolute",
+			"steps": steps,
+		}
+	}
+
+	panel := map[string]interface{}{
+		"id":         1,
+		"type":       "timeseries",
+		"title":      title,
+		"datasource": g.options.Datasource,
+		"gridPos":    map[string]interface{}{"h": 8, "w": 24, "x": 0, "y": 0},
+		"targets": []interface{}{
+			map[string]interface{}{"refId": "A", "expr": metric, "legendFormat": ""},
+		},
+		"fieldConfig": map[string]interface{}{
+			"defaults":  defaults,
+			"overrides": []interface{}{},
+		},
+		"options": map[string]interface{}{
+			"legend": map[string]interface{}{
+				"displayMode": "table",
+				"placement":   "bottom",
+				"calcs":       []string{"mean", "min", "max", "lastNotNull"},
+			},
+			"tooltip": map[string]interface{}{"mode": "multi"},
+		},
+	}
+
+	board := map[string]interface{}{
+		"title":         boardName,
+		"timezone":      "utc",
+		"time":          map[string]interface{}{"from": fmt.Sprintf("now-%dm", period), "to": "now"},
+		"panels":        []interface{}{panel},
+		"schemaVersion": 30,
+	}
+	return json.Marshal(board)
+}
+
+func (g *GrafanaRender) GenerateDashboard(spanCtx sreCommon.TracerSpanContext,
+	title string, metric string, operator string, value *float64, minutes *int, unit string) ([]byte, string, error) {
+
+	span := g.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	period := g.options.Period
+	if minutes != nil {
+		period = *minutes
+	}
+
+	t := strconv.FormatInt(time.Now().UTC().UnixNano(), 10)
+	suffix := fmt.Sprintf("%x", md5.Sum([]byte(t)))
+
+	boardName := fmt.Sprintf("%s - %s", title, suffix)
+
+	to := time.Now().UTC().Unix() * 1000
+	from := (to - int64(period*60)*1000)
+
+	c := sdk.NewClient(g.options.URL, g.options.ApiKey, g.client)
 	ctx := context.Background()
 
-	/*b := g.findDashboard(c, ctx, "New dashboard Copy")
-	if b != nil {
-		g.logger.Info(b.ID)
-	}*/
+	var status sdk.StatusMessage
+	var panelID uint
+	var err error
+
+	if g.options.PanelType == "graph" {
+
+		board := g.graphBoard(boardName, title, metric, operator, value, period, unit)
+		if len(board.Rows) != 1 || len(board.Rows[0].Panels) != 1 {
+			return nil, "", errors.New("panel is not found")
+		}
+		panelID = board.Rows[0].Panels[0].ID
+
+		params := sdk.SetDashboardParams{
+			FolderID:  -1,
+			Overwrite: false,
+		}
+		status, err = c.SetDashboard(ctx, *board, params)
+	} else {
 
-	params := sdk.SetDashboardParams{
-		FolderID:  -1,
-		Overwrite: false,
+		raw, e := g.timeseriesBoard(boardName, title, metric, operator, value, period, unit)
+		if e != nil {
+			g.logger.SpanError(span, e)
+			return nil, "", e
+		}
+		panelID = 1
+		status, err = c.SetRawDashboard(ctx, raw)
 	}
 
-	status, err := c.SetDashboard(ctx, *board, params)
 	if err != nil {
 		g.logger.SpanError(span, err)
 		return nil, "", err
 	}
 
+	if status.UID == nil || status.Slug == nil {
+		return nil, "", errors.New("dashboard is not created")
+	}
+
 	g.logger.SpanDebug(span, "%s => %s", *status.UID, *status.Slug)
 
-	if len(board.Rows) == 1 && len(board.Rows[0].Panels) == 1 {
+	URL := fmt.Sprintf("/render/d-solo/%s/%s?orgId=%s&panelId=%d&from=%d&to=%d&width=%d&height=%d&tz=%s",
+		*status.UID, *status.Slug, g.options.Org, panelID, from, to, g.options.ImageWidth, g.options.ImageHeight, "utc")
 
-		URL := fmt.Sprintf("/render/d-solo/%s/%s?orgId=%s&panelId=%d&from=%d&to=%d&width=%d&height=%d&tz=%s",
-			*status.UID, *status.Slug, g.options.Org, board.Rows[0].Panels[0].ID, from, to, g.options.ImageWidth, g.options.ImageHeight, board.Timezone)
+	g.logger.SpanDebug(span, "%s", URL)
 
-		g.logger.SpanDebug(span, "%s", URL)
+	bytes, err := g.renderImage(URL, g.options.ApiKey)
 
-		bytes, err := g.renderImage(URL, g.options.ApiKey)
-		if err != nil {
-			g.logger.SpanError(span, err)
-			return nil, "", err
-		}
+	// temporary board is deleted even if render failed
+	if _, e := c.DeleteDashboardByUID(ctx, *status.UID); e != nil {
+		g.logger.SpanError(span, e)
+	}
+
+	if err != nil {
+		g.logger.SpanError(span, err)
+		return nil, "", err
+	}
 
-		g.counter.Inc(title)
+	g.counter.Inc(title)
+	return bytes, URL, nil
+}
+
+// renderPanel renders existing panel referenced by alert, nil image is returned if there is no such panel
+func (g *GrafanaRender) renderPanel(spanCtx sreCommon.TracerSpanContext, request *ImageRequest) ([]byte, string, error) {
+
+	ref := grafanaPanelReference(request)
+	if ref == nil {
+		return nil, "", nil
+	}
+
+	span := g.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	c := sdk.NewClient(g.options.URL, g.options.ApiKey, g.client)
+	ctx := context.Background()
+
+	uid := ref.UID
+	if utils.IsEmpty(uid) {
+		uid = g.findDashboard(c, ctx, ref.Title)
+	}
+	if utils.IsEmpty(uid) {
+		g.logger.SpanDebug(span, "Grafana dashboard %s is not found", ref.Title)
+		return nil, "", nil
+	}
+
+	raw, props, err := c.GetRawDashboardByUID(ctx, uid)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var board grafanaDashboard
+	if err := json.Unmarshal(raw, &board); err != nil {
+		return nil, "", err
+	}
+
+	panel := board.findPanel(ref.PanelID, request.Metric)
+	if panel == nil {
+		g.logger.SpanDebug(span, "Grafana panel %d is not found in %s", ref.PanelID, uid)
+		return nil, "", nil
+	}
 
-		_, err = c.DeleteDashboard(ctx, *status.Slug)
-		if err != nil {
-			g.logger.SpanError(span, err)
+	// dashboard variables are taken from generator URL or from alert labels with the same name
+	vars := url.Values{}
+	for k, v := range ref.Vars {
+		vars[k] = v
+	}
+	for _, v := range board.Templating.List {
+		key := fmt.Sprintf("var-%s", v.Name)
+		if _, ok := vars[key]; ok {
+			continue
+		}
+		if l, ok := request.Labels[v.Name]; ok {
+			vars.Set(key, l)
 		}
+	}
+
+	period := g.options.Period
+	if request.Minutes != nil {
+		period = *request.Minutes
+	}
+	from, to := alertWindow(period, request.StartsAt, request.EndsAt)
+
+	URL := fmt.Sprintf("/render/d-solo/%s/%s?orgId=%s&panelId=%d&from=%d&to=%d&width=%d&height=%d&tz=%s",
+		uid, props.Slug, g.options.Org, panel.ID, from.UnixNano()/1e6, to.UnixNano()/1e6, g.options.ImageWidth, g.options.ImageHeight, "utc")
+	if len(vars) > 0 {
+		URL = fmt.Sprintf("%s&%s", URL, vars.Encode())
+	}
+
+	g.logger.SpanDebug(span, "%s", URL)
+
+	bytes, err := g.renderImage(URL, g.options.ApiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	g.counter.Inc(request.Title)
+	return bytes, URL, nil
+}
+
+// Render uses panel of existing dashboard if alert refers to it, otherwise temporary dashboard is generated
+func (g *GrafanaRender) Render(spanCtx sreCommon.TracerSpanContext, request *ImageRequest) ([]byte, string, error) {
+
+	bytes, URL, err := g.renderPanel(spanCtx, request)
+	if err != nil {
+		g.logger.Warn("Grafana panel render failed, fall back to generated dashboard: %v", err)
+	}
+	if err == nil && bytes != nil {
 		return bytes, URL, nil
 	}
 
-	err = errors.New("panel is not found")
-	return nil, "", err
+	if utils.IsEmpty(request.Metric) {
+		return nil, "", errors.New("no grafana panel and alert expression")
+	}
+	return g.GenerateDashboard(spanCtx, request.Title, request.Metric, request.Operator, request.Value, request.Minutes, request.Unit)
 }
 
 func NewGrafanaRender(options GrafanaRenderOptions, observability *common.Observability) *GrafanaRender {
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+)
+
+// ImageRequest describes image of metric, threshold is drawn if Value is set.
+// Alert fields let render use existing dashboard panel and alert time window
+type ImageRequest struct {
+	Title        string
+	Metric       string
+	Operator     string
+	Value        *float64
+	Minutes      *int
+	Unit         string
+	Labels       map[string]string
+	Annotations  map[string]string
+	GeneratorURL string
+	StartsAt     time.Time
+	EndsAt       time.Time
+}
+
+// HasPanel checks if request refers to existing Grafana dashboard panel
+func (r *ImageRequest) HasPanel() bool {
+	return grafanaPanelReference(r) != nil
+}
+
+// alertWindow covers period before alert started and ends when alert is resolved
+func alertWindow(period int, startsAt, endsAt time.Time) (time.Time, time.Time) {
+
+	d := time.Duration(period) * time.Minute
+
+	to := time.Now().UTC()
+	if !endsAt.IsZero() && endsAt.Before(to) {
+		to = endsAt.UTC()
+	}
+
+	from := to.Add(-d)
+	if !startsAt.IsZero() && startsAt.Add(-d/2).Before(from) {
+		from = startsAt.Add(-d / 2).UTC()
+	}
+	return from, to
+}
+
+type ImageRender interface {
+	Render(spanCtx sreCommon.TracerSpanContext, request *ImageRequest) ([]byte, string, error)
+}
+
+// NewImageRender returns render by its name: grafana (default) or chart, nil is returned if render is not configured
+func NewImageRender(name string, grafanaOptions GrafanaRenderOptions, chartOptions ChartRenderOptions,
+	prometheus *PrometheusApi, observability *common.Observability) ImageRender {
+
+	switch name {
+	case "chart":
+		if r := NewChartRender(chartOptions, prometheus, observability); r != nil {
+			return r
+		}
+	default:
+		if r := NewGrafanaRender(grafanaOptions, observability); r != nil {
+			return r
+		}
+	}
+	return nil
+}
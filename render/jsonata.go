@@ -0,0 +1,88 @@
+package render
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"sync"
+
+	"github.com/blues/jsonata-go"
+)
+
+type jsonataFile struct {
+	hash [sha256.Size]byte
+	expr *jsonata.Expr
+}
+
+// jsonataQueries is limit of inline queries in cache, queries can be rendered by templates, so cache is cleared once
+// it's reached
+const jsonataQueries = 1024
+
+// jsonataCache keeps one compiled expression per file keyed by hash of its content and compiled inline queries.
+// Files are read on every call, so changed content replaces expression of file whenever it's written
+type jsonataCache struct {
+	mutex   sync.RWMutex
+	files   map[string]*jsonataFile
+	queries map[string]*jsonata.Expr
+}
+
+var jsonataCompiled = &jsonataCache{
+	files:   make(map[string]*jsonataFile),
+	queries: make(map[string]*jsonata.Expr),
+}
+
+func (c *jsonataCache) compileFile(path string, content []byte) (*jsonata.Expr, error) {
+
+	hash := sha256.Sum256(content)
+
+	c.mutex.RLock()
+	f, ok := c.files[path]
+	c.mutex.RUnlock()
+
+	if ok && f.hash == hash {
+		return f.expr, nil
+	}
+
+	e, err := jsonata.Compile(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.files[path] = &jsonataFile{hash: hash, expr: e}
+	c.mutex.Unlock()
+	return e, nil
+}
+
+func (c *jsonataCache) compile(query string) (*jsonata.Expr, error) {
+
+	// query which wasn't a file is known as inline, so it's found without file system
+	c.mutex.RLock()
+	e, ok := c.queries[query]
+	c.mutex.RUnlock()
+	if ok {
+		return e, nil
+	}
+
+	if content, err := ioutil.ReadFile(query); err == nil {
+		return c.compileFile(query, content)
+	}
+
+	e, err := jsonata.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if len(c.queries) >= jsonataQueries {
+		c.queries = make(map[string]*jsonata.Expr)
+	}
+	c.queries[query] = e
+	c.mutex.Unlock()
+	return e, nil
+}
+
+// CompileJsonata returns compiled expression of query or query file, the same query or file is compiled once.
+// Compiled expressions are safe for concurrent evaluation
+func CompileJsonata(query string) (*jsonata.Expr, error) {
+	return jsonataCompiled.compile(query)
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/blues/jsonata-go"
+)
+
+const jsonataTestQuery = "../datadog2slack.jsonata"
+
+func jsonataTestData(b testing.TB) interface{} {
+
+	content, err := ioutil.ReadFile("../test/datadog.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func TestJsonataFileChange(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "test.jsonata")
+	if err := ioutil.WriteFile(path, []byte(`"first"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	eval := func() string {
+		e, err := CompileJsonata(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := e.Eval(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, _ := v.(string)
+		return s
+	}
+
+	if s := eval(); s != "first" {
+		t.Fatalf("unexpected result %s", s)
+	}
+
+	jsonataCompiled.mutex.RLock()
+	first := jsonataCompiled.files[path]
+	jsonataCompiled.mutex.RUnlock()
+
+	if s := eval(); s != "first" {
+		t.Fatalf("unexpected result %s", s)
+	}
+
+	jsonataCompiled.mutex.RLock()
+	same := jsonataCompiled.files[path]
+	jsonataCompiled.mutex.RUnlock()
+	if same != first {
+		t.Error("unchanged file is compiled again")
+	}
+
+	// the same size and modification time, only content is changed
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(`"other"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := eval(); s != "other" {
+		t.Errorf("changed file is not compiled again, result %s", s)
+	}
+
+	jsonataCompiled.mutex.RLock()
+	files := len(jsonataCompiled.files)
+	_, inline := jsonataCompiled.queries[path]
+	jsonataCompiled.mutex.RUnlock()
+	if files != 1 || inline {
+		t.Errorf("file is not kept once by path, files %d", files)
+	}
+}
+
+func TestJsonataQueriesLimit(t *testing.T) {
+
+	for i := 0; i < jsonataQueries+10; i++ {
+		if _, err := CompileJsonata(strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jsonataCompiled.mutex.RLock()
+	queries := len(jsonataCompiled.queries)
+	jsonataCompiled.mutex.RUnlock()
+	if queries > jsonataQueries {
+		t.Errorf("inline queries are not limited, %d", queries)
+	}
+}
+
+// BenchmarkJsonataUncached reads and compiles query on every event, as fJsonata did before cache
+func BenchmarkJsonataUncached(b *testing.B) {
+
+	data := jsonataTestData(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		content, err := ioutil.ReadFile(jsonataTestQuery)
+		if err != nil {
+			b.Fatal(err)
+		}
+		e, err := jsonata.Compile(string(content))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := e.Eval(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJsonataCached(b *testing.B) {
+
+	data := jsonataTestData(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		e, err := CompileJsonata(jsonataTestQuery)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := e.Eval(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
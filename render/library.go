@@ -0,0 +1,98 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	sreCommon "github.com/devopsext/sre/common"
+	utils "github.com/devopsext/utils"
+)
+
+// TemplateLibrary keeps partials of *.tmpl files, which are available in every text template by {{template "name" .}}
+type TemplateLibrary struct {
+	template *template.Template
+	files    map[string]string
+}
+
+// merge adds library partials to parsed template, library is cloned so functions are bound to the template
+func (l *TemplateLibrary) merge(t *template.Template, funcs template.FuncMap) (*template.Template, error) {
+
+	for _, tt := range t.Templates() {
+		if file, ok := l.files[tt.Name()]; ok {
+			return nil, fmt.Errorf("template %s is already defined in %s", tt.Name(), file)
+		}
+	}
+
+	c, err := l.template.Clone()
+	if err != nil {
+		return nil, err
+	}
+	c.Funcs(funcs)
+
+	for _, tt := range t.Templates() {
+		if tt.Tree == nil {
+			continue
+		}
+		if _, err := c.AddParseTree(tt.Name(), tt.Tree); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// NewTemplateLibrary parses all *.tmpl files of dir, partial defined in more than one file is an error
+func NewTemplateLibrary(dir string, logger sreCommon.Logger) (*TemplateLibrary, error) {
+
+	if utils.IsEmpty(dir) {
+		logger.Debug("Template library dir is not defined. Skipped")
+		return nil, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	funcs := (&TextTemplate{}).funcs()
+	lib := &TemplateLibrary{
+		template: template.New("library").Funcs(funcs),
+		files:    make(map[string]string),
+	}
+
+	for _, path := range paths {
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file := filepath.Base(path)
+		t, err := template.New(file).Funcs(funcs).Parse(string(content))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tt := range t.Templates() {
+
+			// file itself keeps only definitions of partials
+			if tt.Name() == file || tt.Tree == nil {
+				continue
+			}
+			if f, ok := lib.files[tt.Name()]; ok {
+				return nil, fmt.Errorf("template %s is defined in %s and %s", tt.Name(), f, file)
+			}
+			lib.files[tt.Name()] = file
+
+			if _, err := lib.template.AddParseTree(tt.Name(), tt.Tree); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	logger.Info("Template library %s has %d partials in %d files", dir, len(lib.files), len(paths))
+	return lib, nil
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+func newTestLibrary(t *testing.T, files map[string]string) (*TemplateLibrary, error) {
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewTemplateLibrary(dir, sre.NewLogs())
+}
+
+func TestTemplateLibraryPartial(t *testing.T) {
+
+	lib, err := newTestLibrary(t, map[string]string{
+		"header.tmpl": `{{define "header"}}[{{.severity | toUpper}}] {{template "owner" .}}{{end}}`,
+		"owner.tmpl":  `{{define "owner"}}{{getVar "Name"}}{{end}}`,
+		"notes.txt":   `{{define "notes"}}not a partial{{end}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.files) != 2 || lib.files["header"] != "header.tmpl" {
+		t.Fatalf("unexpected partials %v", lib.files)
+	}
+
+	logger := sre.NewLogs()
+	vars := struct{ Name string }{Name: "sre"}
+	tpl := NewTextTemplate("message", `{{template "header" .}}: {{.text}}`, TextTemplateOptions{Library: lib}, vars, logger)
+	if tpl == nil {
+		t.Fatal("template with partial is not created")
+	}
+
+	b, err := tpl.Execute(map[string]interface{}{"severity": "critical", "text": "disk is full"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "[CRITICAL] sre: disk is full" {
+		t.Fatalf("unexpected result %s", s)
+	}
+
+	// partial defined by template itself collides with library one
+	if tpl := NewTextTemplate("message", `{{define "owner"}}me{{end}}{{template "owner" .}}`, TextTemplateOptions{Library: lib}, vars, logger); tpl != nil {
+		t.Fatal("expected template redefining partial to be rejected")
+	}
+	if _, err := lib.merge(NewTextTemplate("message", `{{define "owner"}}me{{end}}`, TextTemplateOptions{}, vars, logger).template, nil); err == nil ||
+		!strings.Contains(err.Error(), "template owner is already defined in owner.tmpl") {
+		t.Fatalf("unexpected merge error %v", err)
+	}
+}
+
+func TestTemplateLibraryCollision(t *testing.T) {
+
+	_, err := newTestLibrary(t, map[string]string{
+		"a.tmpl": `{{define "footer"}}a{{end}}`,
+		"b.tmpl": `{{define "footer"}}b{{end}}`,
+	})
+	if err == nil || err.Error() != "template footer is defined in a.tmpl and b.tmpl" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTemplateLibraryEmpty(t *testing.T) {
+
+	lib, err := NewTemplateLibrary("", sre.NewLogs())
+	if lib != nil || err != nil {
+		t.Fatalf("expected no library without dir, got %v %v", lib, err)
+	}
+}
@@ -0,0 +1,376 @@
+package render
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template/parse"
+
+	"github.com/devopsext/events/common"
+)
+
+// TemplateLintIssue is field reference which doesn't exist in data of event
+type TemplateLintIssue struct {
+	Location string
+	Message  string
+}
+
+func (i TemplateLintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Location, i.Message)
+}
+
+// TemplateLinter checks field references of templates against typed data of events, it follows json names of
+// struct fields as templates are executed on json objects of events. Interfaces, maps values and types with own json
+// encoding are not checked
+type TemplateLinter struct {
+	types  map[string]reflect.Type
+	roots  map[reflect.Type]string
+	base   reflect.Type
+	issues []TemplateLintIssue
+	seen   map[string]bool
+	walked map[string]bool
+}
+
+type lintScope struct {
+	tree *parse.Tree
+	dot  reflect.Type
+	vars map[string]reflect.Type
+}
+
+var (
+	lintMarshaler     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	lintTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func lintDeref(t reflect.Type) reflect.Type {
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func lintEncoded(t reflect.Type) bool {
+
+	for _, m := range []reflect.Type{lintMarshaler, lintTextMarshaler} {
+		if t.Implements(m) || reflect.PtrTo(t).Implements(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintField finds field by json name, fields of embedded structs are promoted like encoding/json does
+func lintField(t reflect.Type, name string) (reflect.Type, bool) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && tagName == "" {
+			if ft := lintDeref(f.Type); ft.Kind() == reflect.Struct {
+				if r, ok := lintField(ft, name); ok {
+					return r, true
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+		if tagName == "" {
+			tagName = f.Name
+		}
+		if tagName == name {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// root returns event type with data of typ, so .data.xxx is checked against it
+func (l *TemplateLinter) root(eventType string, typ reflect.Type) reflect.Type {
+
+	var fields []reflect.StructField
+	e := reflect.TypeOf(common.Event{})
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Name == "Data" && typ != nil {
+			f.Type = typ
+		}
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag})
+	}
+	t := reflect.StructOf(fields)
+	l.roots[t] = eventType
+	return t
+}
+
+func (l *TemplateLinter) typeName(t reflect.Type) string {
+
+	if name, ok := l.roots[t]; ok {
+		if name == "" {
+			return "event"
+		}
+		return fmt.Sprintf("%s event", name)
+	}
+	return t.String()
+}
+
+func (l *TemplateLinter) report(scope *lintScope, node parse.Node, format string, args ...interface{}) {
+
+	location, _ := scope.tree.ErrorContext(node)
+	issue := TemplateLintIssue{Location: location, Message: fmt.Sprintf(format, args...)}
+	if !l.seen[issue.String()] {
+		l.seen[issue.String()] = true
+		l.issues = append(l.issues, issue)
+	}
+}
+
+func (l *TemplateLinter) resolve(scope *lintScope, node parse.Node, t reflect.Type, idents []string) reflect.Type {
+
+	for i, ident := range idents {
+
+		t = lintDeref(t)
+		if t == nil || lintEncoded(t) {
+			return nil
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			ft, ok := lintField(t, ident)
+			if !ok {
+				l.report(scope, node, "field %s is not found in %s", strings.Join(idents[:i+1], "."), l.typeName(t))
+				return nil
+			}
+			t = ft
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			return nil
+		default:
+			l.report(scope, node, "field %s can't be evaluated in %s", strings.Join(idents[:i+1], "."), l.typeName(t))
+			return nil
+		}
+	}
+	return t
+}
+
+func (l *TemplateLinter) walkArg(scope *lintScope, node parse.Node) reflect.Type {
+
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return scope.dot
+	case *parse.FieldNode:
+		return l.resolve(scope, n, scope.dot, n.Ident)
+	case *parse.VariableNode:
+		t, ok := scope.vars[n.Ident[0]]
+		if !ok {
+			return nil
+		}
+		return l.resolve(scope, n, t, n.Ident[1:])
+	case *parse.ChainNode:
+		if p, ok := n.Node.(*parse.PipeNode); ok {
+			l.walkPipe(scope, p)
+		}
+	case *parse.PipeNode:
+		return l.walkPipe(scope, n)
+	}
+	return nil
+}
+
+// walkPipe checks all arguments of pipeline and returns its type, if pipeline is a plain field reference
+func (l *TemplateLinter) walkPipe(scope *lintScope, pipe *parse.PipeNode) reflect.Type {
+
+	if pipe == nil {
+		return nil
+	}
+
+	var t reflect.Type
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			t = l.walkArg(scope, arg)
+		}
+	}
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		t = nil
+	}
+
+	for _, v := range pipe.Decl {
+		if pipe.IsAssign {
+			scope.vars[v.Ident[0]] = nil
+		} else {
+			scope.vars[v.Ident[0]] = t
+		}
+	}
+	return t
+}
+
+// narrow returns event with typed data inside of {{if eq .type "K8sEvent"}}
+func (l *TemplateLinter) narrow(scope *lintScope, pipe *parse.PipeNode) reflect.Type {
+
+	if _, ok := l.roots[scope.dot]; !ok || len(pipe.Cmds) != 1 {
+		return scope.dot
+	}
+
+	args := pipe.Cmds[0].Args
+	if len(args) != 3 {
+		return scope.dot
+	}
+	if id, ok := args[0].(*parse.IdentifierNode); !ok || id.Ident != "eq" {
+		return scope.dot
+	}
+
+	isType := func(n parse.Node) bool {
+		f, ok := n.(*parse.FieldNode)
+		return ok && len(f.Ident) == 1 && f.Ident[0] == "type"
+	}
+
+	var s *parse.StringNode
+	if isType(args[1]) {
+		s, _ = args[2].(*parse.StringNode)
+	} else if isType(args[2]) {
+		s, _ = args[1].(*parse.StringNode)
+	}
+	if s == nil {
+		return scope.dot
+	}
+
+	if t, ok := l.types[s.Text]; ok {
+		return l.root(s.Text, t)
+	}
+	return scope.dot
+}
+
+func (l *TemplateLinter) child(scope *lintScope, dot reflect.Type) *lintScope {
+
+	vars := make(map[string]reflect.Type)
+	for k, v := range scope.vars {
+		vars[k] = v
+	}
+	return &lintScope{tree: scope.tree, dot: dot, vars: vars}
+}
+
+func (l *TemplateLinter) walk(scope *lintScope, node parse.Node, templates func(string) *parse.Tree) {
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			l.walk(scope, c, templates)
+		}
+	case *parse.ActionNode:
+		l.walkPipe(scope, n.Pipe)
+	case *parse.IfNode:
+		l.walkPipe(scope, n.Pipe)
+		l.walk(l.child(scope, l.narrow(scope, n.Pipe)), n.List, templates)
+		l.walk(l.child(scope, scope.dot), n.ElseList, templates)
+	case *parse.WithNode:
+		dot := l.walkPipe(scope, n.Pipe)
+		l.walk(l.child(scope, dot), n.List, templates)
+		l.walk(l.child(scope, scope.dot), n.ElseList, templates)
+	case *parse.RangeNode:
+		rs := l.child(scope, scope.dot)
+		pt := lintDeref(l.walkPipe(rs, n.Pipe))
+		rs.dot = nil
+		if pt != nil && !lintEncoded(pt) {
+			switch pt.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				rs.dot = pt.Elem()
+			}
+		}
+		if decl := n.Pipe.Decl; len(decl) > 0 {
+			rs.vars[decl[len(decl)-1].Ident[0]] = rs.dot
+			if len(decl) > 1 {
+				rs.vars[decl[0].Ident[0]] = nil
+			}
+		}
+		l.walk(rs, n.List, templates)
+		l.walk(l.child(scope, scope.dot), n.ElseList, templates)
+	case *parse.TemplateNode:
+		dot := l.walkPipe(scope, n.Pipe)
+		tree := templates(n.Name)
+		if tree == nil {
+			l.report(scope, n, "template %s is not defined", n.Name)
+			return
+		}
+		l.walkTemplate(tree, dot, templates)
+	}
+}
+
+func (l *TemplateLinter) walkTemplate(tree *parse.Tree, dot reflect.Type, templates func(string) *parse.Tree) {
+
+	if dot == nil || tree.Root == nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s/%p", tree.Name, dot)
+	if l.walked[key] {
+		return
+	}
+	l.walked[key] = true
+
+	scope := &lintScope{tree: tree, dot: dot, vars: map[string]reflect.Type{"$": dot}}
+	l.walk(scope, tree.Root, templates)
+}
+
+// Lint checks template executed on event of eventType, if eventType is empty data is checked inside of
+// {{if eq .type "..."}} blocks only
+func (l *TemplateLinter) Lint(tpl *TextTemplate, eventType string) ([]TemplateLintIssue, error) {
+
+	root := l.base
+	if eventType != "" {
+		t, ok := l.types[eventType]
+		if !ok {
+			return nil, fmt.Errorf("event type %s is not known", eventType)
+		}
+		root = l.root(eventType, t)
+	}
+
+	entry := tpl.template.Lookup(tpl.layout)
+	if entry == nil || entry.Tree == nil {
+		entry = tpl.template
+	}
+	if entry.Tree == nil {
+		return nil, fmt.Errorf("template %s is not defined", tpl.layout)
+	}
+
+	templates := func(name string) *parse.Tree {
+		if t := tpl.template.Lookup(name); t != nil {
+			return t.Tree
+		}
+		return nil
+	}
+
+	l.issues = nil
+	l.seen = make(map[string]bool)
+	l.walked = make(map[string]bool)
+	l.walkTemplate(entry.Tree, root, templates)
+	return l.issues, nil
+}
+
+// NewTemplateLinter creates linter of event types and values of their data
+func NewTemplateLinter(types map[string]interface{}) *TemplateLinter {
+
+	l := &TemplateLinter{
+		types: make(map[string]reflect.Type),
+		roots: make(map[reflect.Type]string),
+	}
+	for name, v := range types {
+		l.types[name] = reflect.TypeOf(v)
+	}
+	l.base = l.root("", nil)
+	return l
+}
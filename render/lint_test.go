@@ -0,0 +1,121 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devopsext/events/common"
+	sre "github.com/devopsext/sre/common"
+)
+
+type lintTestItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type lintTestData struct {
+	Location string            `json:"location"`
+	Labels   map[string]string `json:"labels"`
+	Items    []*lintTestItem   `json:"items"`
+	Owner    *lintTestItem     `json:"owner,omitempty"`
+	Raw      interface{}       `json:"raw"`
+	Secret   string            `json:"-"`
+}
+
+func newTestLinter() *TemplateLinter {
+	return NewTemplateLinter(map[string]interface{}{"TestEvent": &lintTestData{}})
+}
+
+func TestTemplateLinter(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		template  string
+		eventType string
+		issues    []string
+	}{
+		{"known fields", `{{.type}} {{.data.location}} {{.data.labels.team}} {{.data.owner.name}} {{.data.raw.any.field}}`, "TestEvent", nil},
+		{"unknown field", `{{.data.locaton}}`, "TestEvent", []string{"field data.locaton is not found in render.lintTestData"}},
+		{"unknown event field", `{{.chanel}}`, "TestEvent", []string{"field chanel is not found in TestEvent event"}},
+		{"ignored field", `{{.data.Secret}}`, "TestEvent", []string{"field data.Secret is not found in render.lintTestData"}},
+		{"scalar field", `{{.data.location.city}}`, "TestEvent", []string{"field data.location.city can't be evaluated in string"}},
+		{"range", `{{range .data.items}}{{.name}} {{.count}}{{end}}`, "TestEvent", nil},
+		{"range unknown", `{{range .data.items}}{{.nmae}}{{end}}`, "TestEvent", []string{"field nmae is not found in render.lintTestItem"}},
+		{"range variables", `{{range $i, $item := .data.items}}{{$i}} {{$item.name}} {{$item.size}} {{$.data.location}}{{end}}`, "TestEvent",
+			[]string{"field size is not found in render.lintTestItem"}},
+		{"with", `{{with .data.owner}}{{.name}}{{else}}{{.data.location}}{{end}}`, "TestEvent", nil},
+		{"with unknown", `{{with .data.owner}}{{.location}}{{end}}`, "TestEvent", []string{"field location is not found in render.lintTestItem"}},
+		{"variable", `{{$d := .data}}{{$d.location}} {{$d.city}}`, "TestEvent", []string{"field city is not found in render.lintTestData"}},
+		{"type narrowing", `{{.data.anything}}{{if eq .type "TestEvent"}}{{.data.locaton}}{{end}}`, "", []string{"field data.locaton is not found in render.lintTestData"}},
+		{"template", `{{define "owner"}}{{.nam}}{{end}}{{template "owner" .data.owner}}`, "TestEvent", []string{"field nam is not found in render.lintTestItem"}},
+		{"undefined template", `{{template "footer" .}}`, "TestEvent", []string{"template footer is not defined"}},
+	}
+
+	linter := newTestLinter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			tpl := NewTextTemplate("test", tt.template, TextTemplateOptions{}, nil, sre.NewLogs())
+			if tpl == nil {
+				t.Fatal("template is not created")
+			}
+
+			issues, err := linter.Lint(tpl, tt.eventType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != len(tt.issues) {
+				t.Fatalf("expected issues %v, got %v", tt.issues, issues)
+			}
+			for i, issue := range issues {
+				if !strings.Contains(issue.Message, tt.issues[i]) {
+					t.Errorf("expected issue %s, got %s", tt.issues[i], issue)
+				}
+			}
+		})
+	}
+
+	tpl := NewTextTemplate("test", `{{.data}}`, TextTemplateOptions{}, nil, sre.NewLogs())
+	if _, err := linter.Lint(tpl, "UnknownEvent"); err == nil {
+		t.Fatal("expected unknown event type to fail")
+	}
+}
+
+func TestTemplateStrict(t *testing.T) {
+
+	event := &common.Event{Type: "TestEvent", Data: &lintTestData{Location: "eu"}}
+	object, err := event.JsonObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := `{{.data.locaton}}`
+
+	tpl := NewTextTemplate("message", text, TextTemplateOptions{}, nil, sre.NewLogs())
+	b, err := tpl.Execute(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "<no value>" {
+		t.Fatalf("unexpected result %s", s)
+	}
+
+	tpl = NewTextTemplate("message", text, TextTemplateOptions{Strict: []string{"message"}}, nil, sre.NewLogs())
+	if _, err := tpl.Execute(object); err == nil || !strings.Contains(err.Error(), `map has no entry for key "locaton"`) {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+
+	tpl = NewTextTemplate("message", `{{.data.location}} {{index .data "locaton"}}`, TextTemplateOptions{Strict: []string{"*"}}, nil, sre.NewLogs())
+	b, err = tpl.Execute(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "eu <no value>" {
+		t.Fatalf("unexpected result %s", s)
+	}
+
+	issues, err := newTestLinter().Lint(tpl, "TestEvent")
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("expected no issues of optional key, got %v %v", issues, err)
+	}
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	"github.com/devopsext/utils"
+)
+
+type NewRelicChartOptions struct {
+	Timeout  int
+	Attempts int
+}
+
+// NewRelicChart downloads violation chart which NewRelic renders for incident
+type NewRelicChart struct {
+	client   *http.Client
+	options  NewRelicChartOptions
+	logger   sreCommon.Logger
+	tracer   sreCommon.Tracer
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+func (n *NewRelicChart) EventType() string {
+	return "NewRelicEvent"
+}
+
+func (n *NewRelicChart) Chart(spanCtx sreCommon.TracerSpanContext, jsonMap map[string]interface{}) (*ChartImage, error) {
+
+	URL := chartMapString(jsonMap, "data.violation_chart_url")
+	if utils.IsEmpty(URL) {
+		return nil, nil
+	}
+
+	span := n.tracer.StartChildSpan(spanCtx)
+	defer span.Finish()
+
+	policy := chartMapString(jsonMap, "data.policy_name")
+
+	n.requests.Inc(policy)
+	b, err := chartDownload(n.client, URL, n.options.Attempts)
+	if err != nil {
+		n.errors.Inc(policy)
+		n.logger.SpanError(span, err)
+		return nil, err
+	}
+
+	caption := chartMapString(jsonMap, "data.condition_name", "data.details")
+	return &ChartImage{Caption: caption, FileName: chartImageName(policy), Image: b}, nil
+}
+
+func NewNewRelicChart(options NewRelicChartOptions, observability *common.Observability) *NewRelicChart {
+
+	logger := observability.Logs()
+	if options.Attempts <= 0 {
+		logger.Debug("NewRelic chart attempts are not defined. Skipped")
+		return nil
+	}
+
+	return &NewRelicChart{
+		client:   utils.NewHttpClient(options.Timeout, false),
+		options:  options,
+		logger:   logger,
+		tracer:   observability.Traces(),
+		requests: observability.Metrics().Counter("requests", "Count of all newrelic charts", []string{"policy"}, "newrelic", "chart"),
+		errors:   observability.Metrics().Counter("errors", "Count of all newrelic chart errors", []string{"policy"}, "newrelic", "chart"),
+	}
+}
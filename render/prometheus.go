@@ -0,0 +1,217 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devopsext/events/common"
+	sreCommon "github.com/devopsext/sre/common"
+	utils "github.com/devopsext/utils"
+)
+
+type PrometheusApiOptions struct {
+	URL      string
+	Timeout  int
+	User     string
+	Password string
+}
+
+type PrometheusApi struct {
+	client   *http.Client
+	options  PrometheusApiOptions
+	logger   sreCommon.Logger
+	requests sreCommon.Counter
+	errors   sreCommon.Counter
+}
+
+type PrometheusSample struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+type PrometheusSeries struct {
+	Metric map[string]string   `json:"metric"`
+	Value  *PrometheusSample   `json:"value,omitempty"`
+	Values []*PrometheusSample `json:"values,omitempty"`
+}
+
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string            `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+	Values [][]interface{}   `json:"values"`
+}
+
+// prometheusSample converts [<unix time>, "<value>"] pair, NaN and Inf values are returned as nil
+// because they can't be encoded to json
+func prometheusSample(pair []interface{}) *PrometheusSample {
+
+	if len(pair) != 2 {
+		return nil
+	}
+
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return nil
+	}
+	sec, frac := math.Modf(ts)
+	sample := &PrometheusSample{
+		Time: time.Unix(int64(sec), int64(frac*1e9)).UTC(),
+	}
+
+	s, ok := pair[1].(string)
+	if !ok {
+		return sample
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return sample
+	}
+	sample.Value = &v
+	return sample
+}
+
+func (p *PrometheusApi) call(path string, query url.Values) ([]*PrometheusSeries, error) {
+
+	URL := fmt.Sprintf("%s/api/v1/%s", strings.TrimRight(p.options.URL, "/"), path)
+
+	req, err := http.NewRequest("POST", URL, strings.NewReader(query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !utils.IsEmpty(p.options.User) {
+		req.SetBasicAuth(p.options.User, p.options.Password)
+	}
+
+	p.requests.Inc(path)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.errors.Inc(path)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		p.errors.Inc(path)
+		return nil, err
+	}
+
+	var r prometheusResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		p.errors.Inc(path)
+		return nil, fmt.Errorf("prometheus response: %s %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	if r.Status != "success" {
+		p.errors.Inc(path)
+		return nil, fmt.Errorf("prometheus response: %s %s", r.ErrorType, r.Error)
+	}
+
+	var series []*PrometheusSeries
+	switch r.Data.ResultType {
+	case "vector", "matrix":
+		for _, raw := range r.Data.Result {
+			var s prometheusSeries
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			item := &PrometheusSeries{Metric: s.Metric}
+			if s.Value != nil {
+				item.Value = prometheusSample(s.Value)
+			}
+			for _, pair := range s.Values {
+				if sample := prometheusSample(pair); sample != nil {
+					item.Values = append(item.Values, sample)
+				}
+			}
+			series = append(series, item)
+		}
+	case "scalar":
+		var pair []interface{}
+		if len(r.Data.Result) == 0 {
+			break
+		}
+		// scalar result is a single pair instead of list of series
+		raw, _ := json.Marshal(r.Data.Result)
+		if err := json.Unmarshal(raw, &pair); err != nil {
+			return nil, err
+		}
+		series = append(series, &PrometheusSeries{Metric: map[string]string{}, Value: prometheusSample(pair)})
+	default:
+		return nil, fmt.Errorf("prometheus result type %s is not supported", r.Data.ResultType)
+	}
+	return series, nil
+}
+
+func prometheusTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+// Query executes instant query at time t
+func (p *PrometheusApi) Query(query string, t time.Time) ([]*PrometheusSeries, error) {
+
+	if utils.IsEmpty(query) {
+		return nil, errors.New("prometheus query is empty")
+	}
+
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("time", prometheusTime(t))
+	return p.call("query", values)
+}
+
+// QueryRange executes range query between start and end with step
+func (p *PrometheusApi) QueryRange(query string, start, end time.Time, step time.Duration) ([]*PrometheusSeries, error) {
+
+	if utils.IsEmpty(query) {
+		return nil, errors.New("prometheus query is empty")
+	}
+
+	if step <= 0 {
+		step = time.Minute
+	}
+
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("start", prometheusTime(start))
+	values.Set("end", prometheusTime(end))
+	values.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	return p.call("query_range", values)
+}
+
+func NewPrometheusApi(options PrometheusApiOptions, observability *common.Observability) *PrometheusApi {
+
+	logger := observability.Logs()
+	if utils.IsEmpty(options.URL) {
+		logger.Debug("Prometheus API URL is not defined. Skipped")
+		return nil
+	}
+
+	return &PrometheusApi{
+		client:   utils.NewHttpClient(options.Timeout, false),
+		options:  options,
+		logger:   logger,
+		requests: observability.Metrics().Counter("requests", "Count of all prometheus api requests", []string{"path"}, "prometheus", "api"),
+		errors:   observability.Metrics().Counter("errors", "Count of all prometheus api errors", []string{"path"}, "prometheus", "api"),
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/blues/jsonata-go"
 	"github.com/devopsext/events/common"
 
 	"github.com/Masterminds/sprig/v3"
@@ -24,7 +23,11 @@ import (
 )
 
 type TextTemplateOptions struct {
-	TimeFormat string
+	TimeFormat   string
+	Alertmanager *AlertmanagerApi
+	Prometheus   *PrometheusApi
+	Library      *TemplateLibrary
+	Strict       []string
 }
 
 type TextTemplate struct {
@@ -183,18 +186,7 @@ func (tpl *TextTemplate) fJsonata(data interface{}, query string) (string, error
 		return "", errors.New("query is empty")
 	}
 
-	if _, err := os.Stat(query); err == nil {
-
-		content, err := ioutil.ReadFile(query)
-		if err != nil {
-			tpl.logger.Error(err)
-			return "", err
-		}
-
-		query = string(content)
-	}
-
-	e, err := jsonata.Compile(query)
+	e, err := CompileJsonata(query)
 	if err != nil {
 		tpl.logger.Error("fail to compile jsonata query", err)
 		return "", err
@@ -215,6 +207,49 @@ func (tpl *TextTemplate) fJsonata(data interface{}, query string) (string, error
 	return string(b), nil
 }
 
+// toObject converts typed value into maps and slices, so it can be used in templates as event data
+func (tpl *TextTemplate) toObject(i interface{}) (interface{}, error) {
+
+	b, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// alertmanagerAlerts returns active alerts matched by matchers like {service="api"}
+func (tpl *TextTemplate) fAlertmanagerAlerts(matchers string) (interface{}, error) {
+
+	if tpl.options.Alertmanager == nil {
+		return nil, errors.New("alertmanager api is not defined")
+	}
+
+	alerts, err := tpl.options.Alertmanager.GetAlerts(matchers)
+	if err != nil {
+		return nil, err
+	}
+	return tpl.toObject(alerts)
+}
+
+// alertmanagerAlert returns active alert by fingerprint
+func (tpl *TextTemplate) fAlertmanagerAlert(fingerprint string) (interface{}, error) {
+
+	if tpl.options.Alertmanager == nil {
+		return nil, errors.New("alertmanager api is not defined")
+	}
+
+	alert, err := tpl.options.Alertmanager.GetAlert(fingerprint)
+	if err != nil || alert == nil {
+		return nil, err
+	}
+	return tpl.toObject(alert)
+}
+
 func (tpl *TextTemplate) Execute(object interface{}) (*bytes.Buffer, error) {
 
 	var b bytes.Buffer
@@ -235,17 +270,7 @@ func (tpl *TextTemplate) Execute(object interface{}) (*bytes.Buffer, error) {
 	return &b, nil
 }
 
-func NewTextTemplate(name string, fileOrVar string, options TextTemplateOptions, vars interface{}, logger sreCommon.Logger) *TextTemplate {
-
-	var tpl = TextTemplate{}
-
-	var t *template.Template
-	var err1 error
-
-	if utils.IsEmpty(fileOrVar) {
-		logger.Warn("Template %s is empty.", name)
-		return nil
-	}
+func (tpl *TextTemplate) funcs() template.FuncMap {
 
 	funcs := sprig.TxtFuncMap()
 	funcs["regexReplaceAll"] = tpl.fRegexReplaceAll
@@ -268,6 +293,35 @@ func NewTextTemplate(name string, fileOrVar string, options TextTemplateOptions,
 	funcs["unescapeString"] = tpl.fUnescapeString
 	funcs["jsonata"] = tpl.fJsonata
 	funcs["ifDef"] = tpl.fIfDef
+	funcs["alertmanagerAlerts"] = tpl.fAlertmanagerAlerts
+	funcs["alertmanagerAlert"] = tpl.fAlertmanagerAlert
+	funcs["telegramHTML"] = tpl.fTelegramHTML
+	funcs["telegramMarkdownV2"] = tpl.fTelegramMarkdownV2
+	funcs["slackMrkdwn"] = tpl.fSlackMrkdwn
+	funcs["teamsMarkdown"] = tpl.fTeamsMarkdown
+	funcs["messageLimit"] = tpl.fMessageLimit
+	funcs["truncateHTML"] = tpl.fTruncateHTML
+	funcs["truncateText"] = tpl.fTruncateText
+	funcs["humanizeDuration"] = tpl.fHumanizeDuration
+	funcs["relativeTime"] = tpl.fRelativeTime
+	funcs["k8sShortImage"] = tpl.fK8sShortImage
+	funcs["severityEmoji"] = tpl.fSeverityEmoji
+	return funcs
+}
+
+func NewTextTemplate(name string, fileOrVar string, options TextTemplateOptions, vars interface{}, logger sreCommon.Logger) *TextTemplate {
+
+	var tpl = TextTemplate{}
+
+	var t *template.Template
+	var err1 error
+
+	if utils.IsEmpty(fileOrVar) {
+		logger.Warn("Template %s is empty.", name)
+		return nil
+	}
+
+	funcs := tpl.funcs()
 
 	if _, err := os.Stat(fileOrVar); err == nil {
 
@@ -288,6 +342,19 @@ func NewTextTemplate(name string, fileOrVar string, options TextTemplateOptions,
 		return nil
 	}
 
+	if options.Library != nil {
+		t, err1 = options.Library.merge(t, funcs)
+		if err1 != nil {
+			logger.Error("Template %s: %v", name, err1)
+			return nil
+		}
+	}
+
+	// missing keys are errors instead of <no value>, {{index .data "key"}} still can be used for optional keys
+	if utils.Contains(options.Strict, name) || utils.Contains(options.Strict, "*") {
+		t.Option("missingkey=error")
+	}
+
 	tpl.template = t
 	tpl.options = options
 	tpl.layout = name
@@ -1,7 +1,22 @@
 #!/bin/bash
 
-#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Job Hook" -d @gitlab-job.json "http://localhost:8081/gitlab"
-#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Pipeline Hook" -d @gitlab-pipeline.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Job Hook" -H "X-Gitlab-Token: secret" -d @gitlab-job.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Pipeline Hook" -H "X-Gitlab-Token: secret" -d @gitlab-pipeline.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Release Hook" -H "X-Gitlab-Token: secret" -d @gitlab-release.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Deployment Hook" -H "X-Gitlab-Token: secret" -d @gitlab-deployment.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Feature Flag Hook" -H "X-Gitlab-Token: secret" -d @gitlab-feature-flag.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Member Hook" -H "X-Gitlab-Token: secret" -d @gitlab-member.json "http://localhost:8081/gitlab"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-Gitlab-Event: Emoji Hook" -H "X-Gitlab-Token: secret" -d @gitlab-emoji.json "http://localhost:8081/gitlab"
+
+#GITHUB_SECRET="secret"
+#github_signature() { echo "sha256=$(openssl dgst -sha256 -hmac "$GITHUB_SECRET" < "$1" | sed 's/^.* //')"; }
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: push" -H "X-Hub-Signature-256: $(github_signature github-push.json)" --data-binary @github-push.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: pull_request" -H "X-Hub-Signature-256: $(github_signature github-pull_request.json)" --data-binary @github-pull_request.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: workflow_run" -H "X-Hub-Signature-256: $(github_signature github-workflow_run.json)" --data-binary @github-workflow_run.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: workflow_job" -H "X-Hub-Signature-256: $(github_signature github-workflow_job.json)" --data-binary @github-workflow_job.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: release" -H "X-Hub-Signature-256: $(github_signature github-release.json)" --data-binary @github-release.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: deployment_status" -H "X-Hub-Signature-256: $(github_signature github-deployment_status.json)" --data-binary @github-deployment_status.json "http://localhost:8081/github"
+#curl -sk -X POST -H "Content-type: application/json" -H "X-GitHub-Event: check_suite" -H "X-Hub-Signature-256: $(github_signature github-check_suite.json)" --data-binary @github-check_suite.json "http://localhost:8081/github"
 
 #curl -sk -X POST -H "Content-type: application/json" -d @k8s.json "http://localhost:8081/k8s"
 